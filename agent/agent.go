@@ -287,15 +287,17 @@ func (a *Agent) initPersister() error {
 	}
 
 	for _, aggregator := range a.Config.Aggregators {
-		plugin, ok := aggregator.Aggregator.(telegraf.StatefulPlugin)
-		if !ok {
-			continue
-		}
+		// Each resolution of the aggregator has its own plugin instance
+		for id, instance := range aggregator.Instances() {
+			plugin, ok := instance.(telegraf.StatefulPlugin)
+			if !ok {
+				continue
+			}
 
-		name := aggregator.LogName()
-		id := aggregator.ID()
-		if err := a.Config.Persister.Register(id, plugin); err != nil {
-			return fmt.Errorf("could not register aggregator %s: %w", name, err)
+			name := aggregator.LogName()
+			if err := a.Config.Persister.Register(id, plugin); err != nil {
+				return fmt.Errorf("could not register aggregator %s: %w", name, err)
+			}
 		}
 	}
 
@@ -700,6 +702,10 @@ func (a *Agent) runAggregators(
 	for _, agg := range a.Config.Aggregators {
 		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
 		agg.UpdateWindow(since, until)
+		for i, period := range agg.ResolutionPeriods() {
+			since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, period)
+			agg.UpdateResolutionWindow(i, since, until)
+		}
 	}
 
 	var wg sync.WaitGroup
@@ -877,7 +877,17 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 		return err
 	}
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	// Each additional resolution requires its own plugin instance
+	resolutions := make([]telegraf.Aggregator, 0, len(conf.Resolutions))
+	for range conf.Resolutions {
+		res := creator()
+		if err := c.toml.UnmarshalTable(table, res); err != nil {
+			return err
+		}
+		resolutions = append(resolutions, res)
+	}
+
+	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf, resolutions...))
 	return nil
 }
 
@@ -985,6 +995,8 @@ func (c *Config) addParser(parentcategory, parentname string, table *ast.Table)
 		}
 	}
 	conf.LogLevel = c.getFieldString(table, "log_level")
+	conf.SchemaTracking = c.getFieldBool(table, "schema_tracking")
+	conf.SchemaTrackingExpiry, _ = c.getFieldDuration(table, "schema_tracking_expiry")
 
 	creator, ok := parsers.Parsers[conf.DataFormat]
 	if !ok {
@@ -1373,6 +1385,18 @@ func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.Aggregato
 	if grace, found := c.getFieldDuration(tbl, "grace"); found {
 		conf.Grace = grace
 	}
+	if window, found := c.getFieldDuration(tbl, "window"); found {
+		conf.Window = window
+	}
+	if conf.Window != 0 && (conf.Window < conf.Period || conf.Window%conf.Period != 0) {
+		return nil, fmt.Errorf("window of aggregator %s must be a multiple of the period", name)
+	}
+
+	resolutions, err := c.buildAggregatorResolutions(name, tbl, conf.Period)
+	if err != nil {
+		return nil, err
+	}
+	conf.Resolutions = resolutions
 
 	conf.DropOriginal = c.getFieldBool(tbl, "drop_original")
 	conf.MeasurementPrefix = c.getFieldString(tbl, "name_prefix")
@@ -1394,7 +1418,6 @@ func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.Aggregato
 		return nil, c.firstErr()
 	}
 
-	var err error
 	conf.Filter, err = c.buildFilter("aggregators."+name, tbl)
 	if err != nil {
 		return conf, err
@@ -1405,6 +1428,52 @@ func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.Aggregato
 	return conf, err
 }
 
+// buildAggregatorResolutions parses the additional resolutions of an
+// aggregator given as "resolution" sub-tables
+func (c *Config) buildAggregatorResolutions(name string, tbl *ast.Table, period time.Duration) ([]models.AggregatorResolution, error) {
+	node, found := tbl.Fields["resolution"]
+	if !found {
+		return nil, nil
+	}
+	subtbls, ok := node.([]*ast.Table)
+	if !ok {
+		return nil, fmt.Errorf("resolution of aggregator %s must be an array of tables", name)
+	}
+
+	suffixes := make(map[string]bool, len(subtbls))
+	resolutions := make([]models.AggregatorResolution, 0, len(subtbls))
+	for _, subtbl := range subtbls {
+		var cfg struct {
+			Period Duration `toml:"period"`
+			Window Duration `toml:"window"`
+			Suffix string   `toml:"suffix"`
+		}
+		if err := c.toml.UnmarshalTable(subtbl, &cfg); err != nil {
+			return nil, fmt.Errorf("parsing resolution of aggregator %s failed: %w", name, err)
+		}
+		res := models.AggregatorResolution{
+			Period: time.Duration(cfg.Period),
+			Window: time.Duration(cfg.Window),
+			Suffix: cfg.Suffix,
+		}
+
+		switch {
+		case res.Suffix == "":
+			return nil, fmt.Errorf("resolution of aggregator %s requires a suffix", name)
+		case suffixes[res.Suffix]:
+			return nil, fmt.Errorf("duplicate resolution suffix %q for aggregator %s", res.Suffix, name)
+		case res.Period <= 0 || res.Period%period != 0:
+			return nil, fmt.Errorf("period of resolution %q of aggregator %s must be a multiple of the period", res.Suffix, name)
+		case res.Window != 0 && (res.Window < res.Period || res.Window%res.Period != 0):
+			return nil, fmt.Errorf("window of resolution %q of aggregator %s must be a multiple of its period", res.Suffix, name)
+		}
+		suffixes[res.Suffix] = true
+		resolutions = append(resolutions, res)
+	}
+
+	return resolutions, nil
+}
+
 // buildProcessor parses Processor specific items from the ast.Table,
 // builds the filter and returns a
 // models.ProcessorConfig to be inserted into models.RunningProcessor
@@ -1614,13 +1683,15 @@ func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 		"name_override", "name_prefix", "name_suffix", "namedrop", "namedrop_separator", "namepass", "namepass_separator",
 		"order",
 		"pass", "period", "precision",
-		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "startup_error_behavior":
+		"resolution",
+		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "startup_error_behavior",
+		"window":
 
 	// Secret-store options to ignore
 	case "id":
 
 	// Parser and serializer options to ignore
-	case "data_type", "influx_parser_type":
+	case "data_type", "influx_parser_type", "schema_tracking", "schema_tracking_expiry":
 
 	default:
 		c.unusedFieldsMutex.Lock()
@@ -26,6 +26,7 @@ import (
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/models"
 	"github.com/influxdata/telegraf/persister"
+	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -915,6 +916,49 @@ func TestConfig_ParserInterface(t *testing.T) {
 	}
 }
 
+func TestConfig_ParserSchemaTracking(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/parser_schema_tracking.toml"))
+	require.Len(t, c.Inputs, 1)
+	require.Empty(t, c.UnusedFields)
+
+	input, ok := c.Inputs[0].Input.(*MockupInputPluginParserNew)
+	require.True(t, ok)
+	parser, ok := input.Parser.(*models.RunningParser)
+	require.True(t, ok)
+	require.True(t, parser.Config.SchemaTracking)
+	require.Equal(t, time.Hour, parser.Config.SchemaTrackingExpiry)
+}
+
+func TestConfig_AggregatorResolutions(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/aggregator_resolutions.toml"))
+	require.Len(t, c.Aggregators, 1)
+	require.Empty(t, c.UnusedFields)
+
+	agg := c.Aggregators[0]
+	require.Equal(t, time.Minute, agg.Config.Period)
+	require.Equal(t, 5*time.Minute, agg.Config.Window)
+	expected := []models.AggregatorResolution{
+		{Period: 5 * time.Minute, Suffix: "_5m"},
+		{Period: time.Hour, Window: 2 * time.Hour, Suffix: "_1h"},
+	}
+	require.Equal(t, expected, agg.Config.Resolutions)
+	require.Equal(t, []time.Duration{5 * time.Minute, time.Hour}, agg.ResolutionPeriods())
+	require.NoError(t, agg.Init())
+
+	// Each resolution must use a separate, identically configured instance
+	plugin, ok := agg.Aggregator.(*MockupAggregatorPlugin)
+	require.True(t, ok)
+	require.Equal(t, []string{"min", "max"}, plugin.Stats)
+}
+
+func TestConfig_AggregatorResolutionsInvalid(t *testing.T) {
+	c := config.NewConfig()
+	err := c.LoadConfig("./testdata/aggregator_resolutions_invalid.toml")
+	require.ErrorContains(t, err, `period of resolution "_90s" of aggregator minmax must be a multiple of the period`)
+}
+
 func TestConfig_MultipleProcessorsOrder(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -1543,6 +1587,21 @@ func (*MockupOutputPluginSerializerNew) Write(_ []telegraf.Metric) error {
 	return nil
 }
 
+// Mockup AGGREGATOR plugin for testing to avoid cyclic dependencies
+type MockupAggregatorPlugin struct {
+	Stats []string `toml:"stats"`
+}
+
+func (*MockupAggregatorPlugin) SampleConfig() string {
+	return "Mockup test aggregator plugin"
+}
+
+func (*MockupAggregatorPlugin) Add(telegraf.Metric) {}
+
+func (*MockupAggregatorPlugin) Push(telegraf.Accumulator) {}
+
+func (*MockupAggregatorPlugin) Reset() {}
+
 // Mockup INPUT plugin with state for testing to avoid cyclic dependencies
 type MockupState struct {
 	Name     string
@@ -1652,6 +1711,11 @@ func init() {
 		return &MockupProcessorPlugin{}
 	})
 
+	// Register the mockup aggregator plugin for the required names
+	aggregators.Add("minmax", func() telegraf.Aggregator {
+		return &MockupAggregatorPlugin{}
+	})
+
 	// Register the mockup output plugin for the required names
 	outputs.Add("azure_monitor", func() telegraf.Output {
 		return &MockupOutputPlugin{NamespacePrefix: "Telegraf/"}
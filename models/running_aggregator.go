@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -12,11 +14,14 @@ import (
 
 type RunningAggregator struct {
 	sync.Mutex
-	Aggregator  telegraf.Aggregator
-	Config      *AggregatorConfig
-	periodStart time.Time
-	periodEnd   time.Time
-	log         telegraf.Logger
+	Aggregator telegraf.Aggregator
+	Config     *AggregatorConfig
+	log        telegraf.Logger
+
+	// windows contains the aggregation window of the base period followed
+	// by the windows of the additional resolutions
+	windows []*aggregationWindow
+	pushing *aggregationWindow
 
 	MetricsPushed   selfstat.Stat
 	MetricsFiltered selfstat.Stat
@@ -24,7 +29,27 @@ type RunningAggregator struct {
 	PushTime        selfstat.Stat
 }
 
-func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConfig) *RunningAggregator {
+// aggregationWindow is a resolution of the aggregator with its own plugin
+// instance. For sliding windows, i.e. windows longer than the period, the
+// metrics are buffered and replayed to the plugin on each push.
+type aggregationWindow struct {
+	aggregator  telegraf.Aggregator
+	period      time.Duration
+	window      time.Duration
+	suffix      string
+	periodStart time.Time
+	periodEnd   time.Time
+	buffer      []telegraf.Metric
+}
+
+func (w *aggregationWindow) sliding() bool {
+	return w.window > w.period
+}
+
+// NewRunningAggregator creates a running aggregator for the given plugin. For
+// each additional resolution in the config, a separate plugin instance must
+// be passed in the order of the resolutions.
+func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConfig, resolutions ...telegraf.Aggregator) *RunningAggregator {
 	tags := map[string]string{"aggregator": config.Name}
 	if config.Alias != "" {
 		tags["alias"] = config.Alias
@@ -39,6 +64,28 @@ func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConf
 		logger.Error(err)
 	}
 	SetLoggerOnPlugin(aggregator, logger)
+	for _, res := range resolutions {
+		SetLoggerOnPlugin(res, logger)
+	}
+
+	windows := []*aggregationWindow{
+		{
+			aggregator: aggregator,
+			period:     config.Period,
+			window:     config.Window,
+		},
+	}
+	for i, res := range config.Resolutions {
+		if i >= len(resolutions) {
+			break
+		}
+		windows = append(windows, &aggregationWindow{
+			aggregator: resolutions[i],
+			period:     res.Period,
+			window:     res.Window,
+			suffix:     res.Suffix,
+		})
+	}
 
 	return &RunningAggregator{
 		Aggregator: aggregator,
@@ -63,7 +110,8 @@ func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConf
 			"push_time_ns",
 			tags,
 		),
-		log: logger,
+		log:     logger,
+		windows: windows,
 	}
 }
 
@@ -74,9 +122,11 @@ type AggregatorConfig struct {
 	ID           string
 	DropOriginal bool
 	Period       time.Duration
+	Window       time.Duration
 	Delay        time.Duration
 	Grace        time.Duration
 	LogLevel     string
+	Resolutions  []AggregatorResolution
 
 	NameOverride      string
 	MeasurementPrefix string
@@ -85,15 +135,37 @@ type AggregatorConfig struct {
 	Filter            Filter
 }
 
+// AggregatorResolution is an additional output resolution of an aggregator
+// emitted with the given suffix appended to the metric name. A window longer
+// than the period results in a sliding window.
+type AggregatorResolution struct {
+	Period time.Duration
+	Window time.Duration
+	Suffix string
+}
+
 func (r *RunningAggregator) LogName() string {
 	return logName("aggregators", r.Config.Name, r.Config.Alias)
 }
 
 func (r *RunningAggregator) Init() error {
-	if p, ok := r.Aggregator.(telegraf.Initializer); ok {
-		err := p.Init()
-		if err != nil {
-			return err
+	if len(r.windows) != len(r.Config.Resolutions)+1 {
+		return errors.New("number of plugin instances does not match the number of resolutions")
+	}
+	for _, w := range r.windows {
+		if w.window > 0 && w.period > 0 && w.window%w.period != 0 {
+			return fmt.Errorf("window %s is not a multiple of period %s", w.window, w.period)
+		}
+		if w.period > 0 && r.Config.Period > 0 && w.period%r.Config.Period != 0 {
+			return fmt.Errorf("period %s is not a multiple of the base period %s", w.period, r.Config.Period)
+		}
+	}
+
+	for _, w := range r.windows {
+		if p, ok := w.aggregator.(telegraf.Initializer); ok {
+			if err := p.Init(); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -106,26 +178,61 @@ func (r *RunningAggregator) ID() string {
 	return r.Config.ID
 }
 
+// Instances returns the plugin instances of the base period and of all
+// additional resolutions keyed by a unique ID. The base instance uses the
+// plugin ID while the resolutions append their suffix.
+func (r *RunningAggregator) Instances() map[string]telegraf.Aggregator {
+	id := r.ID()
+	instances := make(map[string]telegraf.Aggregator, len(r.windows))
+	for _, w := range r.windows {
+		instances[id+w.suffix] = w.aggregator
+	}
+	return instances
+}
+
 func (r *RunningAggregator) Period() time.Duration {
 	return r.Config.Period
 }
 
+// ResolutionPeriods returns the periods of the additional resolutions
+func (r *RunningAggregator) ResolutionPeriods() []time.Duration {
+	periods := make([]time.Duration, 0, len(r.windows)-1)
+	for _, w := range r.windows[1:] {
+		periods = append(periods, w.period)
+	}
+	return periods
+}
+
 func (r *RunningAggregator) EndPeriod() time.Time {
-	return r.periodEnd
+	return r.windows[0].periodEnd
 }
 
 func (r *RunningAggregator) UpdateWindow(start, until time.Time) {
-	r.periodStart = start
-	r.periodEnd = until
+	r.windows[0].periodStart = start
+	r.windows[0].periodEnd = until
 	r.log.Debugf("Updated aggregation range [%s, %s]", start, until)
 }
 
+// UpdateResolutionWindow sets the aggregation range of the additional
+// resolution with the given index
+func (r *RunningAggregator) UpdateResolutionWindow(idx int, start, until time.Time) {
+	w := r.windows[idx+1]
+	w.periodStart = start
+	w.periodEnd = until
+	r.log.Debugf("Updated aggregation range of resolution %q [%s, %s]", w.suffix, start, until)
+}
+
 func (r *RunningAggregator) MakeMetric(telegrafMetric telegraf.Metric) telegraf.Metric {
+	suffix := r.Config.MeasurementSuffix
+	if r.pushing != nil {
+		suffix += r.pushing.suffix
+	}
+
 	m := makemetric(
 		telegrafMetric,
 		r.Config.NameOverride,
 		r.Config.MeasurementPrefix,
-		r.Config.MeasurementSuffix,
+		suffix,
 		r.Config.Tags,
 		nil)
 
@@ -159,30 +266,83 @@ func (r *RunningAggregator) Add(m telegraf.Metric) bool {
 	r.Lock()
 	defer r.Unlock()
 
-	if m.Time().Before(r.periodStart.Add(-r.Config.Grace)) || m.Time().After(r.periodEnd.Add(r.Config.Delay)) {
-		r.log.Debugf("Metric is outside aggregation window; discarding. %s: m: %s e: %s g: %s",
-			m.Time(), r.periodStart, r.periodEnd, r.Config.Grace)
-		r.MetricsDropped.Incr(1)
-		return r.Config.DropOriginal
+	var added bool
+	for _, w := range r.windows {
+		if m.Time().Before(w.periodStart.Add(-r.Config.Grace)) || m.Time().After(w.periodEnd.Add(r.Config.Delay)) {
+			r.log.Debugf("Metric is outside aggregation window; discarding. %s: m: %s e: %s g: %s",
+				m.Time(), w.periodStart, w.periodEnd, r.Config.Grace)
+			continue
+		}
+		added = true
+
+		// The metric must neither be shared between plugin instances nor
+		// with the metric passed on to the outputs as they might modify it
+		wm := m.Copy()
+		if w.sliding() {
+			w.buffer = append(w.buffer, wm)
+		} else {
+			w.aggregator.Add(wm)
+		}
 	}
 
-	r.Aggregator.Add(m)
+	// Only count metrics not falling into any of the windows as dropped
+	if !added {
+		r.MetricsDropped.Incr(1)
+	}
 	return r.Config.DropOriginal
 }
 
+// Push emits the aggregation of the base period and of all resolutions
+// ending with the base period and advances their aggregation windows.
 func (r *RunningAggregator) Push(acc telegraf.Accumulator) {
 	r.Lock()
 	defer r.Unlock()
 
-	since := r.periodEnd
-	until := r.periodEnd.Add(r.Config.Period)
-	r.UpdateWindow(since, until)
+	base := r.windows[0]
+	end := base.periodEnd
+	for _, w := range r.windows {
+		if w != base && w.periodEnd.After(end) {
+			continue
+		}
+		r.push(w, acc)
+	}
+	r.pushing = nil
+}
+
+func (r *RunningAggregator) push(w *aggregationWindow, acc telegraf.Accumulator) {
+	since := w.periodEnd
+	until := w.periodEnd.Add(w.period)
 
 	start := time.Now()
-	r.Aggregator.Push(acc)
+	if w.sliding() {
+		// Replay the metrics of the whole window and keep the ones still
+		// required for the next window
+		w.aggregator.Reset()
+		oldest := w.periodEnd.Add(-w.window - r.Config.Grace)
+		next := until.Add(-w.window - r.Config.Grace)
+		keep := w.buffer[:0]
+		for _, m := range w.buffer {
+			if m.Time().Before(oldest) {
+				continue
+			}
+			w.aggregator.Add(m.Copy())
+			if !m.Time().Before(next) {
+				keep = append(keep, m)
+			}
+		}
+		clear(w.buffer[len(keep):])
+		w.buffer = keep
+	}
+
+	r.pushing = w
+	w.aggregator.Push(acc)
 	elapsed := time.Since(start)
 	r.PushTime.Incr(elapsed.Nanoseconds())
-	r.Aggregator.Reset()
+	w.aggregator.Reset()
+
+	w.periodStart = since
+	w.periodEnd = until
+	r.log.Debugf("Updated aggregation range [%s, %s]", since, until)
 }
 
 func (r *RunningAggregator) Log() telegraf.Logger {
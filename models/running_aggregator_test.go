@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -239,6 +240,174 @@ func TestRunningAggregatorAddDoesNotModifyMetric(t *testing.T) {
 	testutil.RequireMetricEqual(t, expected, m)
 }
 
+func TestRunningAggregatorSlidingWindow(t *testing.T) {
+	ra := NewRunningAggregator(&mockAggregator{}, &AggregatorConfig{
+		Name:   "TestRunningAggregator",
+		Period: time.Minute,
+		Window: 3 * time.Minute,
+	})
+	require.NoError(t, ra.Config.Filter.Compile())
+	require.NoError(t, ra.Init())
+
+	start := time.Unix(0, 0)
+	ra.UpdateWindow(start, start.Add(time.Minute))
+
+	// Each window covers the last three periods
+	expected := []int64{1, 3, 7, 14, 28}
+	for i, sum := range expected {
+		m := testutil.MustMetric("RITest",
+			map[string]string{},
+			map[string]interface{}{"value": int64(1) << i},
+			start.Add(time.Duration(i)*time.Minute+time.Second),
+		)
+		require.False(t, ra.Add(m))
+
+		var acc testutil.Accumulator
+		ra.Push(&acc)
+		require.Len(t, acc.Metrics, 1)
+		require.Equal(t, sum, acc.Metrics[0].Fields["sum"], "period %d", i)
+	}
+
+	// Metrics outside of the window are removed from the buffer
+	require.Len(t, ra.windows[0].buffer, 2)
+}
+
+func TestRunningAggregatorSlidingWindowMetricModified(t *testing.T) {
+	ra := NewRunningAggregator(&mockAggregator{}, &AggregatorConfig{
+		Name:   "TestRunningAggregator",
+		Period: time.Minute,
+		Window: 2 * time.Minute,
+	})
+	require.NoError(t, ra.Config.Filter.Compile())
+	require.NoError(t, ra.Init())
+
+	start := time.Unix(0, 0)
+	ra.UpdateWindow(start, start.Add(time.Minute))
+
+	expected := []int64{1, 3}
+	for i, sum := range expected {
+		m := testutil.MustMetric("RITest",
+			map[string]string{},
+			map[string]interface{}{"value": int64(1) << i},
+			start.Add(time.Duration(i)*time.Minute+time.Second),
+		)
+		require.False(t, ra.Add(m))
+
+		// Modify the metric as done by processors or outputs after the
+		// aggregator saw it
+		m.AddField("value", int64(100))
+		m.RemoveField("value")
+
+		var acc testutil.Accumulator
+		ra.Push(&acc)
+		require.Len(t, acc.Metrics, 1)
+		require.Equal(t, sum, acc.Metrics[0].Fields["sum"], "period %d", i)
+	}
+}
+
+func TestRunningAggregatorResolutions(t *testing.T) {
+	ra := NewRunningAggregator(
+		&mockAggregator{},
+		&AggregatorConfig{
+			Name:   "TestRunningAggregator",
+			Period: time.Minute,
+			Resolutions: []AggregatorResolution{
+				{Period: 2 * time.Minute, Suffix: "_2m"},
+				{Period: 2 * time.Minute, Window: 4 * time.Minute, Suffix: "_4m"},
+			},
+		},
+		&mockAggregator{},
+		&mockAggregator{},
+	)
+	require.NoError(t, ra.Config.Filter.Compile())
+	require.NoError(t, ra.Init())
+
+	start := time.Unix(0, 0)
+	ra.UpdateWindow(start, start.Add(time.Minute))
+	for i := range ra.ResolutionPeriods() {
+		ra.UpdateResolutionWindow(i, start, start.Add(2*time.Minute))
+	}
+
+	var acc testutil.Accumulator
+	for i := 0; i < 4; i++ {
+		m := testutil.MustMetric("RITest",
+			map[string]string{},
+			map[string]interface{}{"value": int64(1) << i},
+			start.Add(time.Duration(i)*time.Minute+time.Second),
+		)
+		require.False(t, ra.Add(m))
+		ra.Push(&makeMetricAccumulator{Accumulator: &acc, maker: ra})
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("TestMetric", map[string]string{}, map[string]interface{}{"sum": int64(1)}, time.Unix(0, 0)),
+		testutil.MustMetric("TestMetric", map[string]string{}, map[string]interface{}{"sum": int64(2)}, time.Unix(0, 0)),
+		testutil.MustMetric("TestMetric_2m", map[string]string{}, map[string]interface{}{"sum": int64(3)}, time.Unix(0, 0)),
+		testutil.MustMetric("TestMetric_4m", map[string]string{}, map[string]interface{}{"sum": int64(3)}, time.Unix(0, 0)),
+		testutil.MustMetric("TestMetric", map[string]string{}, map[string]interface{}{"sum": int64(4)}, time.Unix(0, 0)),
+		testutil.MustMetric("TestMetric", map[string]string{}, map[string]interface{}{"sum": int64(8)}, time.Unix(0, 0)),
+		testutil.MustMetric("TestMetric_2m", map[string]string{}, map[string]interface{}{"sum": int64(12)}, time.Unix(0, 0)),
+		testutil.MustMetric("TestMetric_4m", map[string]string{}, map[string]interface{}{"sum": int64(15)}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestRunningAggregatorResolutionsMetricsDropped(t *testing.T) {
+	ra := NewRunningAggregator(
+		&mockAggregator{},
+		&AggregatorConfig{
+			Name:        "TestRunningAggregatorResolutionsDropped",
+			ID:          "base",
+			Period:      time.Minute,
+			Resolutions: []AggregatorResolution{{Period: 2 * time.Minute, Suffix: "_2m"}},
+		},
+		&mockAggregator{},
+	)
+	require.NoError(t, ra.Config.Filter.Compile())
+	require.NoError(t, ra.Init())
+
+	// Each resolution instance is available under its own ID
+	instances := ra.Instances()
+	require.Len(t, instances, 2)
+	require.Contains(t, instances, "base")
+	require.Contains(t, instances, "base_2m")
+	require.NotSame(t, instances["base"], instances["base_2m"])
+
+	start := time.Unix(0, 0)
+	ra.UpdateWindow(start, start.Add(time.Minute))
+	ra.UpdateResolutionWindow(0, start, start.Add(2*time.Minute))
+
+	// Outside of the base period but inside the resolution's window
+	m := testutil.MustMetric("RITest", map[string]string{}, map[string]interface{}{"value": int64(1)}, start.Add(90*time.Second))
+	require.False(t, ra.Add(m))
+	require.Zero(t, ra.MetricsDropped.Get())
+
+	// Outside of all windows
+	m = testutil.MustMetric("RITest", map[string]string{}, map[string]interface{}{"value": int64(1)}, start.Add(time.Hour))
+	require.False(t, ra.Add(m))
+	require.Equal(t, int64(1), ra.MetricsDropped.Get())
+}
+
+func TestRunningAggregatorInitInvalidWindow(t *testing.T) {
+	ra := NewRunningAggregator(&mockAggregator{}, &AggregatorConfig{
+		Name:   "TestRunningAggregator",
+		Period: time.Minute,
+		Window: 90 * time.Second,
+	})
+	require.ErrorContains(t, ra.Init(), "window 1m30s is not a multiple of period 1m0s")
+}
+
+// makeMetricAccumulator applies the running aggregator's modifications to the
+// pushed metrics like the agent's accumulator
+type makeMetricAccumulator struct {
+	*testutil.Accumulator
+	maker *RunningAggregator
+}
+
+func (a *makeMetricAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, _ ...time.Time) {
+	a.AddMetric(a.maker.MakeMetric(metric.New(measurement, tags, fields, time.Unix(0, 0))))
+}
+
 type mockAggregator struct {
 	sum int64
 }
@@ -160,6 +160,10 @@ func (r *RunningOutput) Init() error {
 		return fmt.Errorf("invalid 'startup_error_behavior' setting %q", r.Config.StartupErrorBehavior)
 	}
 
+	if p, ok := r.Output.(telegraf.PluginWithIDSetter); ok {
+		p.SetPluginID(r.Config.ID)
+	}
+
 	if p, ok := r.Output.(telegraf.Initializer); ok {
 		err := p.Init()
 		if err != nil {
@@ -737,6 +737,29 @@ func BenchmarkRunningOutputAddFailWrites(b *testing.B) {
 	}
 }
 
+type idOutput struct {
+	mockOutput
+	id string
+}
+
+func (m *idOutput) SetPluginID(id string) {
+	m.id = id
+}
+
+func (m *idOutput) Init() error {
+	if m.id == "" {
+		return errors.New("plugin ID not set")
+	}
+	return nil
+}
+
+func TestRunningOutputSetPluginID(t *testing.T) {
+	m := &idOutput{}
+	ro := NewRunningOutput(m, &OutputConfig{ID: "abc"}, 1000, 10000)
+	require.NoError(t, ro.Init())
+	require.Equal(t, "abc", m.id)
+}
+
 type mockOutput struct {
 	sync.Mutex
 
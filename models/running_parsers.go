@@ -15,6 +15,8 @@ type RunningParser struct {
 
 	MetricsParsed selfstat.Stat
 	ParseTime     selfstat.Stat
+
+	schema *schemaTracker
 }
 
 func NewRunningParser(parser telegraf.Parser, config *ParserConfig) *RunningParser {
@@ -33,6 +35,15 @@ func NewRunningParser(parser telegraf.Parser, config *ParserConfig) *RunningPars
 	}
 	SetLoggerOnPlugin(parser, logger)
 
+	var schema *schemaTracker
+	if config.SchemaTracking {
+		eventTags := map[string]string{"data_format": config.DataFormat, "plugin": config.Parent}
+		if config.Alias != "" {
+			eventTags["alias"] = config.Alias
+		}
+		schema = newSchemaTracker(config.SchemaTrackingExpiry, eventTags, tags, logger)
+	}
+
 	return &RunningParser{
 		Parser: parser,
 		Config: config,
@@ -46,7 +57,8 @@ func NewRunningParser(parser telegraf.Parser, config *ParserConfig) *RunningPars
 			"parse_time_ns",
 			tags,
 		),
-		log: logger,
+		log:    logger,
+		schema: schema,
 	}
 }
 
@@ -57,6 +69,10 @@ type ParserConfig struct {
 	DataFormat  string
 	DefaultTags map[string]string
 	LogLevel    string
+
+	// Schema tracking settings
+	SchemaTracking       bool
+	SchemaTrackingExpiry time.Duration
 }
 
 func (r *RunningParser) LogName() string {
@@ -80,6 +96,13 @@ func (r *RunningParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 	r.ParseTime.Incr(elapsed.Nanoseconds())
 	r.MetricsParsed.Incr(int64(len(m)))
 
+	if r.schema != nil && len(m) > 0 {
+		now := time.Now()
+		if changes := r.schema.track(m, now); len(changes) > 0 {
+			m = append(m, r.schema.events(changes, now)...)
+		}
+	}
+
 	return m, err
 }
 
@@ -90,6 +113,11 @@ func (r *RunningParser) ParseLine(line string) (telegraf.Metric, error) {
 	r.ParseTime.Incr(elapsed.Nanoseconds())
 	r.MetricsParsed.Incr(1)
 
+	// Changes are only logged and counted as we cannot return the events here
+	if r.schema != nil && m != nil {
+		r.schema.track([]telegraf.Metric{m}, time.Now())
+	}
+
 	return m, err
 }
 
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestRunningParserSchemaTrackingDisabled(t *testing.T) {
+	parser := &mockParser{}
+	rp := NewRunningParser(parser, &ParserConfig{DataFormat: "mock", Parent: "test"})
+
+	parser.metrics = []telegraf.Metric{
+		metric.New("test", nil, map[string]interface{}{"value": int64(1)}, time.Unix(0, 0)),
+	}
+	_, err := rp.Parse(nil)
+	require.NoError(t, err)
+
+	parser.metrics = []telegraf.Metric{
+		metric.New("test", nil, map[string]interface{}{"value": "one"}, time.Unix(0, 0)),
+	}
+	actual, err := rp.Parse(nil)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+}
+
+func TestRunningParserSchemaTracking(t *testing.T) {
+	parser := &mockParser{}
+	rp := NewRunningParser(parser, &ParserConfig{
+		DataFormat:     "mock",
+		Parent:         "TestRunningParserSchemaTracking",
+		SchemaTracking: true,
+	})
+
+	// The first occurrence of a measurement is learned without events
+	parser.metrics = []telegraf.Metric{
+		metric.New(
+			"test",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(0, 0),
+		),
+	}
+	actual, err := rp.Parse(nil)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, parser.metrics, actual)
+
+	// Changing the type, adding a tag and a field must be reported
+	parser.metrics = []telegraf.Metric{
+		metric.New(
+			"test",
+			map[string]string{"host": "a", "region": "eu"},
+			map[string]interface{}{"value": "1", "status": true},
+			time.Unix(0, 0),
+		),
+	}
+	expected := []telegraf.Metric{
+		parser.metrics[0],
+		metric.New(
+			"telegraf_schema_change",
+			map[string]string{
+				"data_format": "mock",
+				"plugin":      "TestRunningParserSchemaTracking",
+				"measurement": "test",
+				"change":      "tag_added",
+				"key":         "region",
+			},
+			map[string]interface{}{"new_type": "string"},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"telegraf_schema_change",
+			map[string]string{
+				"data_format": "mock",
+				"plugin":      "TestRunningParserSchemaTracking",
+				"measurement": "test",
+				"change":      "field_added",
+				"key":         "status",
+			},
+			map[string]interface{}{"new_type": "bool"},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"telegraf_schema_change",
+			map[string]string{
+				"data_format": "mock",
+				"plugin":      "TestRunningParserSchemaTracking",
+				"measurement": "test",
+				"change":      "field_type_changed",
+				"key":         "value",
+			},
+			map[string]interface{}{"old_type": "int", "new_type": "string"},
+			time.Unix(0, 0),
+		),
+	}
+	actual, err = rp.Parse(nil)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime(), testutil.SortMetrics())
+
+	// Check the internal statistics
+	stats := make(map[string]interface{})
+	for _, m := range selfstat.Metrics() {
+		if m.Name() != "internal_parser" {
+			continue
+		}
+		if tag, ok := m.GetTag("type"); !ok || tag != "mock" {
+			continue
+		}
+		for _, f := range m.FieldList() {
+			stats[f.Key] = f.Value
+		}
+	}
+	require.Equal(t, int64(1), stats["schema_fields_added"])
+	require.Equal(t, int64(1), stats["schema_type_changes"])
+	require.Equal(t, int64(1), stats["schema_tags_added"])
+}
+
+func TestRunningParserSchemaTrackingExpiry(t *testing.T) {
+	parser := &mockParser{}
+	rp := NewRunningParser(parser, &ParserConfig{
+		DataFormat:           "mock_expiry",
+		Parent:               "test",
+		SchemaTracking:       true,
+		SchemaTrackingExpiry: 50 * time.Millisecond,
+	})
+
+	parser.metrics = []telegraf.Metric{
+		metric.New(
+			"test",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": int64(1), "old": int64(2)},
+			time.Unix(0, 0),
+		),
+	}
+	_, err := rp.Parse(nil)
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	parser.metrics = []telegraf.Metric{
+		metric.New(
+			"test",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(0, 0),
+		),
+	}
+	expected := []telegraf.Metric{
+		parser.metrics[0],
+		metric.New(
+			"telegraf_schema_change",
+			map[string]string{
+				"data_format": "mock_expiry",
+				"plugin":      "test",
+				"measurement": "test",
+				"change":      "field_removed",
+				"key":         "old",
+			},
+			map[string]interface{}{"old_type": "int"},
+			time.Unix(0, 0),
+		),
+	}
+	actual, err := rp.Parse(nil)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
+}
+
+type mockParser struct {
+	metrics []telegraf.Metric
+}
+
+func (p *mockParser) Parse([]byte) ([]telegraf.Metric, error) {
+	return p.metrics, nil
+}
+
+func (p *mockParser) ParseLine(string) (telegraf.Metric, error) {
+	return p.metrics[0], nil
+}
+
+func (*mockParser) SetDefaultTags(map[string]string) {}
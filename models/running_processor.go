@@ -59,6 +59,16 @@ func (rp *RunningProcessor) metricFiltered(metric telegraf.Metric) {
 }
 
 func (rp *RunningProcessor) Init() error {
+	// Processors are wrapped to be streaming processors, so pass the alias
+	// to the underlying plugin
+	var plugin interface{} = rp.Processor
+	if p, ok := rp.Processor.(interface{ Unwrap() telegraf.Processor }); ok {
+		plugin = p.Unwrap()
+	}
+	if p, ok := plugin.(telegraf.PluginWithAliasSetter); ok && rp.Config != nil {
+		p.SetPluginAlias(rp.Config.Alias)
+	}
+
 	if p, ok := rp.Processor.(telegraf.Initializer); ok {
 		err := p.Init()
 		if err != nil {
@@ -22,6 +22,16 @@ func TestRunningProcessorInit(t *testing.T) {
 	require.True(t, mock.hasBeenInit)
 }
 
+func TestRunningProcessorInitAlias(t *testing.T) {
+	mock := mockProcessor{}
+	rp := &models.RunningProcessor{
+		Processor: processors.NewStreamingProcessorFromProcessor(&mock),
+		Config:    &models.ProcessorConfig{Name: "mock", Alias: "custom"},
+	}
+	require.NoError(t, rp.Init())
+	require.Equal(t, "custom", mock.alias)
+}
+
 func TestRunningProcessorApply(t *testing.T) {
 	type args struct {
 		Processor telegraf.StreamingProcessor
@@ -210,12 +220,17 @@ func TestRunningProcessorOrder(t *testing.T) {
 type mockProcessor struct {
 	applyF      func(in ...telegraf.Metric) []telegraf.Metric
 	hasBeenInit bool
+	alias       string
 }
 
 func (p *mockProcessor) SampleConfig() string {
 	return ""
 }
 
+func (p *mockProcessor) SetPluginAlias(alias string) {
+	p.alias = alias
+}
+
 func (p *mockProcessor) Init() error {
 	p.hasBeenInit = true
 	return nil
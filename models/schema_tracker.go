@@ -0,0 +1,231 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// SchemaChangeMeasurement is the name of the event metric emitted on schema
+// changes detected by the schema tracker.
+const SchemaChangeMeasurement = "telegraf_schema_change"
+
+// Kinds of schema changes reported in the "change" tag of the event metric
+const (
+	schemaFieldAdded       = "field_added"
+	schemaFieldRemoved     = "field_removed"
+	schemaFieldTypeChanged = "field_type_changed"
+	schemaTagAdded         = "tag_added"
+	schemaTagRemoved       = "tag_removed"
+)
+
+type schemaEntry struct {
+	dtype    string
+	lastSeen time.Time
+}
+
+type measurementSchema struct {
+	fields map[string]*schemaEntry
+	tags   map[string]*schemaEntry
+}
+
+type schemaChange struct {
+	measurement string
+	kind        string
+	key         string
+	oldType     string
+	newType     string
+}
+
+// schemaTracker learns the tag and field names and field types per
+// measurement and reports new and disappearing keys as well as type changes.
+type schemaTracker struct {
+	expiry    time.Duration
+	eventTags map[string]string
+	log       telegraf.Logger
+
+	schemas map[string]*measurementSchema
+	sync.Mutex
+
+	fieldsAdded   selfstat.Stat
+	fieldsRemoved selfstat.Stat
+	typeChanges   selfstat.Stat
+	tagsAdded     selfstat.Stat
+	tagsRemoved   selfstat.Stat
+}
+
+func newSchemaTracker(expiry time.Duration, eventTags, statTags map[string]string, log telegraf.Logger) *schemaTracker {
+	return &schemaTracker{
+		expiry:        expiry,
+		eventTags:     eventTags,
+		log:           log,
+		schemas:       make(map[string]*measurementSchema),
+		fieldsAdded:   selfstat.Register("parser", "schema_fields_added", statTags),
+		fieldsRemoved: selfstat.Register("parser", "schema_fields_removed", statTags),
+		typeChanges:   selfstat.Register("parser", "schema_type_changes", statTags),
+		tagsAdded:     selfstat.Register("parser", "schema_tags_added", statTags),
+		tagsRemoved:   selfstat.Register("parser", "schema_tags_removed", statTags),
+	}
+}
+
+// track updates the learned schemas with the given metrics and returns the
+// detected changes. Measurements seen for the first time are learned without
+// reporting changes.
+func (t *schemaTracker) track(metrics []telegraf.Metric, now time.Time) []schemaChange {
+	t.Lock()
+	defer t.Unlock()
+
+	var changes []schemaChange
+	seen := make(map[string]bool)
+	for _, m := range metrics {
+		name := m.Name()
+		seen[name] = true
+
+		schema, known := t.schemas[name]
+		if !known {
+			schema = &measurementSchema{
+				fields: make(map[string]*schemaEntry),
+				tags:   make(map[string]*schemaEntry),
+			}
+			t.schemas[name] = schema
+		}
+
+		for _, tag := range m.TagList() {
+			if entry, found := schema.tags[tag.Key]; found {
+				entry.lastSeen = now
+				continue
+			}
+			schema.tags[tag.Key] = &schemaEntry{dtype: "string", lastSeen: now}
+			if known {
+				changes = append(changes, schemaChange{
+					measurement: name,
+					kind:        schemaTagAdded,
+					key:         tag.Key,
+					newType:     "string",
+				})
+			}
+		}
+
+		for _, field := range m.FieldList() {
+			dtype := fieldType(field.Value)
+			entry, found := schema.fields[field.Key]
+			if !found {
+				schema.fields[field.Key] = &schemaEntry{dtype: dtype, lastSeen: now}
+				if known {
+					changes = append(changes, schemaChange{
+						measurement: name,
+						kind:        schemaFieldAdded,
+						key:         field.Key,
+						newType:     dtype,
+					})
+				}
+				continue
+			}
+			entry.lastSeen = now
+			if entry.dtype != dtype {
+				changes = append(changes, schemaChange{
+					measurement: name,
+					kind:        schemaFieldTypeChanged,
+					key:         field.Key,
+					oldType:     entry.dtype,
+					newType:     dtype,
+				})
+				entry.dtype = dtype
+			}
+		}
+	}
+
+	// Check for expired keys in the measurements seen in this batch
+	if t.expiry > 0 {
+		for name := range seen {
+			schema := t.schemas[name]
+			for key, entry := range schema.tags {
+				if now.Sub(entry.lastSeen) > t.expiry {
+					delete(schema.tags, key)
+					changes = append(changes, schemaChange{
+						measurement: name,
+						kind:        schemaTagRemoved,
+						key:         key,
+						oldType:     entry.dtype,
+					})
+				}
+			}
+			for key, entry := range schema.fields {
+				if now.Sub(entry.lastSeen) > t.expiry {
+					delete(schema.fields, key)
+					changes = append(changes, schemaChange{
+						measurement: name,
+						kind:        schemaFieldRemoved,
+						key:         key,
+						oldType:     entry.dtype,
+					})
+				}
+			}
+		}
+	}
+
+	for _, c := range changes {
+		switch c.kind {
+		case schemaFieldAdded:
+			t.fieldsAdded.Incr(1)
+			t.log.Infof("New field %q of type %s in measurement %q", c.key, c.newType, c.measurement)
+		case schemaFieldRemoved:
+			t.fieldsRemoved.Incr(1)
+			t.log.Infof("Field %q disappeared from measurement %q", c.key, c.measurement)
+		case schemaFieldTypeChanged:
+			t.typeChanges.Incr(1)
+			t.log.Warnf("Type of field %q in measurement %q changed from %s to %s", c.key, c.measurement, c.oldType, c.newType)
+		case schemaTagAdded:
+			t.tagsAdded.Incr(1)
+			t.log.Infof("New tag %q in measurement %q", c.key, c.measurement)
+		case schemaTagRemoved:
+			t.tagsRemoved.Incr(1)
+			t.log.Infof("Tag %q disappeared from measurement %q", c.key, c.measurement)
+		}
+	}
+
+	return changes
+}
+
+// events converts the given changes to event metrics
+func (t *schemaTracker) events(changes []schemaChange, now time.Time) []telegraf.Metric {
+	events := make([]telegraf.Metric, 0, len(changes))
+	for _, c := range changes {
+		tags := make(map[string]string, len(t.eventTags)+3)
+		for k, v := range t.eventTags {
+			tags[k] = v
+		}
+		tags["measurement"] = c.measurement
+		tags["change"] = c.kind
+		tags["key"] = c.key
+
+		fields := make(map[string]interface{}, 2)
+		if c.oldType != "" {
+			fields["old_type"] = c.oldType
+		}
+		if c.newType != "" {
+			fields["new_type"] = c.newType
+		}
+		events = append(events, metric.New(SchemaChangeMeasurement, tags, fields, now))
+	}
+	return events
+}
+
+func fieldType(v interface{}) string {
+	switch v.(type) {
+	case int64:
+		return "int"
+	case uint64:
+		return "uint"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	}
+	return "unknown"
+}
@@ -37,6 +37,24 @@ type PluginWithID interface {
 	ID() string
 }
 
+// PluginWithIDSetter allows a plugin to receive the identifier of the plugin
+// instance, e.g. to derive stable names for resources on external systems.
+// Currently only supported for output plugins.
+type PluginWithIDSetter interface {
+	// SetPluginID is called with the ID of the plugin instance before the
+	// plugin's Init() function if there is any.
+	SetPluginID(id string)
+}
+
+// PluginWithAliasSetter allows a plugin to receive the alias of the plugin
+// instance, e.g. to tag its internal statistics. Currently only supported for
+// processor plugins.
+type PluginWithAliasSetter interface {
+	// SetPluginAlias is called with the alias of the plugin instance before
+	// the plugin's Init() function if there is any.
+	SetPluginAlias(alias string)
+}
+
 // StatefulPlugin contains the functions that plugins must implement to
 // persist an internal state across Telegraf runs.
 // Note that plugins may define a persister that is not part of the
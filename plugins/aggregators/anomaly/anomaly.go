@@ -0,0 +1,358 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package anomaly
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Anomaly struct {
+	Fields           []string        `toml:"fields"`
+	Model            string          `toml:"model"`
+	Alpha            float64         `toml:"alpha"`
+	Beta             float64         `toml:"beta"`
+	Gamma            float64         `toml:"gamma"`
+	SeasonLength     int             `toml:"season_length"`
+	Warmup           int64           `toml:"warmup"`
+	Threshold        float64         `toml:"threshold"`
+	EmitEvents       bool            `toml:"emit_events"`
+	EventMeasurement string          `toml:"event_measurement"`
+	TTL              config.Duration `toml:"ttl"`
+	Log              telegraf.Logger `toml:"-"`
+
+	filter filter.Filter
+	window map[uint64]*aggregate
+	series map[uint64]*series
+}
+
+// aggregate collects the values of a series within the current period
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	fields map[string]*mean
+}
+
+type mean struct {
+	sum   float64
+	count int64
+}
+
+// series is the persisted baseline of a series
+type series struct {
+	Name     string            `json:"name"`
+	Tags     map[string]string `json:"tags"`
+	Fields   map[string]*model `json:"fields"`
+	LastSeen int64             `json:"last_seen"`
+}
+
+// model is the baseline of a single field. For the EWMA model only level
+// and variance are used, the Holt-Winters model additionally uses trend and
+// seasonal components. The variance always refers to the forecast errors.
+type model struct {
+	Count     int64     `json:"count"`
+	Level     float64   `json:"level"`
+	Trend     float64   `json:"trend,omitempty"`
+	Variance  float64   `json:"variance"`
+	Seasonals []float64 `json:"seasonals,omitempty"`
+	Anomalous bool      `json:"anomalous,omitempty"`
+}
+
+func (*Anomaly) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Anomaly) Init() error {
+	switch a.Model {
+	case "ewma":
+	case "holt_winters":
+		if a.SeasonLength < 2 {
+			return errors.New("'season_length' must be at least two for the holt_winters model")
+		}
+	default:
+		return fmt.Errorf("invalid model %q", a.Model)
+	}
+
+	for name, v := range map[string]float64{"alpha": a.Alpha, "beta": a.Beta, "gamma": a.Gamma} {
+		if v <= 0 || v >= 1 {
+			return fmt.Errorf("%q must be between zero and one (exclusive)", name)
+		}
+	}
+	if a.Threshold <= 0 {
+		return errors.New("'threshold' must be greater than zero")
+	}
+	if a.Warmup < 0 {
+		return errors.New("'warmup' must not be negative")
+	}
+	if a.EmitEvents && a.EventMeasurement == "" {
+		return errors.New("'event_measurement' must not be empty")
+	}
+
+	f, err := filter.Compile(a.Fields)
+	if err != nil {
+		return fmt.Errorf("creating field filter failed: %w", err)
+	}
+	a.filter = f
+
+	a.window = make(map[uint64]*aggregate)
+	a.series = make(map[uint64]*series)
+
+	return nil
+}
+
+func (a *Anomaly) Add(in telegraf.Metric) {
+	id := in.HashID()
+	agg, found := a.window[id]
+	if !found {
+		agg = &aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*mean),
+		}
+		a.window[id] = agg
+	}
+
+	for _, field := range in.FieldList() {
+		if a.filter != nil && !a.filter.Match(field.Key) {
+			continue
+		}
+		v, ok := convert(field.Value)
+		if !ok {
+			continue
+		}
+		m, found := agg.fields[field.Key]
+		if !found {
+			m = &mean{}
+			agg.fields[field.Key] = m
+		}
+		m.sum += v
+		m.count++
+	}
+}
+
+func (a *Anomaly) Push(acc telegraf.Accumulator) {
+	now := time.Now()
+
+	for id, agg := range a.window {
+		s, found := a.series[id]
+		if !found {
+			s = &series{
+				Name:   agg.name,
+				Tags:   agg.tags,
+				Fields: make(map[string]*model),
+			}
+			a.series[id] = s
+		}
+		s.LastSeen = now.UnixNano()
+
+		fields := make(map[string]interface{})
+		for key, m := range agg.fields {
+			value := m.sum / float64(m.count)
+
+			mdl, found := s.Fields[key]
+			if !found || (a.Model == "holt_winters" && len(mdl.Seasonals) != a.SeasonLength) {
+				mdl = &model{}
+				s.Fields[key] = mdl
+			}
+
+			forecast, ready := a.forecast(mdl)
+			variance := mdl.Variance
+			a.update(mdl, value)
+			if !ready || mdl.Count <= a.Warmup+a.initCount() {
+				continue
+			}
+
+			deviation := value - forecast
+			fields[key+"_baseline"] = forecast
+			fields[key+"_deviation"] = deviation
+
+			// Score the value against the variance before the update as the
+			// value itself would dampen its score otherwise. Series that were
+			// constant so far have no meaningful score.
+			stddev := math.Sqrt(variance)
+			if stddev == 0 {
+				continue
+			}
+			zscore := deviation / stddev
+			fields[key+"_zscore"] = zscore
+
+			anomalous := math.Abs(zscore) >= a.Threshold
+			if a.EmitEvents && anomalous != mdl.Anomalous {
+				a.emitEvent(acc, s, key, anomalous, value, forecast, zscore)
+			}
+			mdl.Anomalous = anomalous
+		}
+
+		if len(fields) > 0 {
+			acc.AddFields(agg.name, fields, agg.tags)
+		}
+	}
+
+	a.cleanup(now)
+}
+
+func (a *Anomaly) Reset() {
+	a.window = make(map[uint64]*aggregate)
+}
+
+func (a *Anomaly) GetState() interface{} {
+	return a.series
+}
+
+func (a *Anomaly) SetState(state interface{}) error {
+	s, ok := state.(map[uint64]*series)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+	for id, entry := range s {
+		if entry == nil || entry.Fields == nil {
+			continue
+		}
+		a.series[id] = entry
+	}
+	// Remove the state that went stale while we were not running
+	a.cleanup(time.Now())
+
+	return nil
+}
+
+// initCount returns the number of values required to initialize the model
+func (a *Anomaly) initCount() int64 {
+	if a.Model == "holt_winters" {
+		return int64(a.SeasonLength)
+	}
+	return 1
+}
+
+// forecast returns the expected value of the model for the next period and
+// whether the model is initialized
+func (a *Anomaly) forecast(mdl *model) (float64, bool) {
+	if mdl.Count < a.initCount() {
+		return 0, false
+	}
+	if a.Model == "holt_winters" {
+		return mdl.Level + mdl.Trend + mdl.Seasonals[mdl.Count%int64(a.SeasonLength)], true
+	}
+	return mdl.Level, true
+}
+
+// update adds the value to the model
+func (a *Anomaly) update(mdl *model, value float64) {
+	defer func() { mdl.Count++ }()
+
+	if a.Model == "ewma" {
+		if mdl.Count == 0 {
+			mdl.Level = value
+			return
+		}
+		// Incremental computation of the exponentially weighted mean and
+		// variance, see "Incremental calculation of weighted mean and
+		// variance" by Tony Finch
+		diff := value - mdl.Level
+		incr := a.Alpha * diff
+		mdl.Level += incr
+		mdl.Variance = (1 - a.Alpha) * (mdl.Variance + diff*incr)
+		return
+	}
+
+	// Additive Holt-Winters model. The first season is used to initialize
+	// the level with the mean and the seasonal components with the offsets
+	// from the mean.
+	length := int64(a.SeasonLength)
+	idx := mdl.Count % length
+	if mdl.Count < length {
+		if mdl.Seasonals == nil {
+			mdl.Seasonals = make([]float64, a.SeasonLength)
+		}
+		mdl.Seasonals[idx] = value
+		if mdl.Count == length-1 {
+			var sum float64
+			for _, v := range mdl.Seasonals {
+				sum += v
+			}
+			mdl.Level = sum / float64(length)
+			for i := range mdl.Seasonals {
+				mdl.Seasonals[i] -= mdl.Level
+			}
+		}
+		return
+	}
+
+	deviation := value - (mdl.Level + mdl.Trend + mdl.Seasonals[idx])
+	level := a.Alpha*(value-mdl.Seasonals[idx]) + (1-a.Alpha)*(mdl.Level+mdl.Trend)
+	mdl.Trend = a.Beta*(level-mdl.Level) + (1-a.Beta)*mdl.Trend
+	mdl.Seasonals[idx] = a.Gamma*(value-level) + (1-a.Gamma)*mdl.Seasonals[idx]
+	mdl.Level = level
+	mdl.Variance = (1-a.Alpha)*mdl.Variance + a.Alpha*deviation*deviation
+}
+
+func (a *Anomaly) emitEvent(acc telegraf.Accumulator, s *series, field string, anomalous bool, value, baseline, zscore float64) {
+	tags := make(map[string]string, len(s.Tags)+3)
+	for k, v := range s.Tags {
+		tags[k] = v
+	}
+	tags["measurement"] = s.Name
+	tags["field"] = field
+	tags["state"] = "normal"
+	if anomalous {
+		tags["state"] = "anomaly"
+	}
+
+	fields := map[string]interface{}{
+		"value":    value,
+		"baseline": baseline,
+		"zscore":   zscore,
+	}
+	acc.AddFields(a.EventMeasurement, fields, tags)
+}
+
+// cleanup removes the series not seen within the TTL
+func (a *Anomaly) cleanup(now time.Time) {
+	if a.TTL <= 0 {
+		return
+	}
+	threshold := now.Add(-time.Duration(a.TTL)).UnixNano()
+	for id, s := range a.series {
+		if s.LastSeen < threshold {
+			delete(a.series, id)
+		}
+	}
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("anomaly", func() telegraf.Aggregator {
+		return &Anomaly{
+			Model:            "ewma",
+			Alpha:            0.3,
+			Beta:             0.1,
+			Gamma:            0.1,
+			Warmup:           10,
+			Threshold:        3.0,
+			EventMeasurement: "anomaly",
+			TTL:              config.Duration(24 * time.Hour),
+		}
+	})
+}
@@ -0,0 +1,237 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newAnomaly() *Anomaly {
+	return &Anomaly{
+		Model:            "ewma",
+		Alpha:            0.3,
+		Beta:             0.1,
+		Gamma:            0.1,
+		Warmup:           10,
+		Threshold:        3.0,
+		EventMeasurement: "anomaly",
+		TTL:              config.Duration(24 * time.Hour),
+		Log:              testutil.Logger{},
+	}
+}
+
+func cpu(value float64) telegraf.Metric {
+	return metric.New(
+		"cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"usage": value, "state": "ok"},
+		time.Unix(0, 0),
+	)
+}
+
+// period adds the values as one aggregation period and returns the pushed
+// metrics
+func period(a *Anomaly, values ...float64) []telegraf.Metric {
+	var acc testutil.Accumulator
+	for _, v := range values {
+		a.Add(cpu(v))
+	}
+	a.Push(&acc)
+	a.Reset()
+	return acc.GetTelegrafMetrics()
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(a *Anomaly)
+		expected string
+	}{
+		{
+			name:     "invalid model",
+			modify:   func(a *Anomaly) { a.Model = "foo" },
+			expected: `invalid model "foo"`,
+		},
+		{
+			name:     "missing season length",
+			modify:   func(a *Anomaly) { a.Model = "holt_winters" },
+			expected: "'season_length' must be at least two",
+		},
+		{
+			name:     "invalid alpha",
+			modify:   func(a *Anomaly) { a.Alpha = 1.0 },
+			expected: `"alpha" must be between zero and one`,
+		},
+		{
+			name:     "invalid threshold",
+			modify:   func(a *Anomaly) { a.Threshold = 0 },
+			expected: "'threshold' must be greater than zero",
+		},
+		{
+			name: "empty event measurement",
+			modify: func(a *Anomaly) {
+				a.EmitEvents = true
+				a.EventMeasurement = ""
+			},
+			expected: "'event_measurement' must not be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newAnomaly()
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestEWMA(t *testing.T) {
+	plugin := newAnomaly()
+	plugin.Warmup = 5
+	plugin.EmitEvents = true
+	require.NoError(t, plugin.Init())
+
+	noise := []float64{1, -1, 0.5, -0.5, 0}
+
+	// No output during initialization and warmup
+	for i := 0; i < 6; i++ {
+		require.Empty(t, period(plugin, 12+noise[i%len(noise)]))
+	}
+
+	// Normal values are scored without events
+	for i := 0; i < 10; i++ {
+		actual := period(plugin, 12+noise[i%len(noise)])
+		require.Len(t, actual, 1)
+		require.Equal(t, "cpu", actual[0].Name())
+		zscore, found := actual[0].GetField("usage_zscore")
+		require.True(t, found)
+		require.Less(t, zscore.(float64), 3.0)
+		require.Contains(t, actual[0].Fields(), "usage_baseline")
+		require.Contains(t, actual[0].Fields(), "usage_deviation")
+		require.NotContains(t, actual[0].Fields(), "state")
+	}
+
+	// A spike is reported as anomaly
+	actual := period(plugin, 100)
+	require.Len(t, actual, 2)
+	var event telegraf.Metric
+	for _, m := range actual {
+		if m.Name() == "anomaly" {
+			event = m
+		}
+	}
+	require.NotNil(t, event)
+	require.Equal(t, map[string]string{
+		"host":        "server01",
+		"measurement": "cpu",
+		"field":       "usage",
+		"state":       "anomaly",
+	}, event.Tags())
+	require.InDelta(t, 100.0, event.Fields()["value"], 1e-9)
+	require.Greater(t, event.Fields()["zscore"], 3.0)
+
+	// Returning to normal values emits the recovery event once the spike
+	// decayed from the baseline
+	var recovered bool
+	for i := 0; i < 30 && !recovered; i++ {
+		for _, m := range period(plugin, 12) {
+			if m.Name() == "anomaly" {
+				require.Equal(t, "normal", m.Tags()["state"])
+				recovered = true
+			}
+		}
+	}
+	require.True(t, recovered)
+}
+
+func TestHoltWinters(t *testing.T) {
+	plugin := newAnomaly()
+	plugin.Model = "holt_winters"
+	plugin.SeasonLength = 4
+	plugin.Warmup = 0
+	require.NoError(t, plugin.Init())
+
+	season := []float64{0, 10, 20, 10}
+	noise := []float64{0.5, -0.3, 0.2, -0.4, 0.1}
+
+	// No output during the initial season
+	for i := 0; i < 4; i++ {
+		require.Empty(t, period(plugin, season[i]))
+	}
+
+	// The seasonal pattern is forecast
+	var actual []telegraf.Metric
+	for i := 4; i < 40; i++ {
+		actual = period(plugin, season[i%4]+noise[i%len(noise)])
+		require.Len(t, actual, 1)
+	}
+	require.InDelta(t, 10.0, actual[0].Fields()["usage_baseline"], 2.0)
+	require.Less(t, actual[0].Fields()["usage_zscore"], 3.0)
+
+	// A value normal for another phase of the season is an anomaly
+	actual = period(plugin, 20)
+	require.Len(t, actual, 1)
+	require.InDelta(t, 0.0, actual[0].Fields()["usage_baseline"], 2.0)
+	require.Greater(t, actual[0].Fields()["usage_zscore"], 3.0)
+}
+
+func TestFieldFilter(t *testing.T) {
+	plugin := newAnomaly()
+	plugin.Fields = []string{"idle"}
+	plugin.Warmup = 0
+	require.NoError(t, plugin.Init())
+
+	for i := 0; i < 5; i++ {
+		require.Empty(t, period(plugin, float64(i)))
+	}
+}
+
+func TestState(t *testing.T) {
+	plugin := newAnomaly()
+	plugin.Warmup = 3
+	require.NoError(t, plugin.Init())
+
+	for i := 0; i < 10; i++ {
+		period(plugin, 10+float64(i%2))
+	}
+	expected := period(plugin, 10)
+	require.Len(t, expected, 1)
+
+	// Persist the state and restore it in a new instance like the persister
+	buf, err := json.Marshal(plugin.GetState())
+	require.NoError(t, err)
+
+	restored := newAnomaly()
+	restored.Warmup = 3
+	require.NoError(t, restored.Init())
+	state := reflect.New(reflect.TypeOf(restored.GetState())).Interface()
+	require.NoError(t, json.Unmarshal(buf, state))
+	require.NoError(t, restored.SetState(reflect.ValueOf(state).Elem().Interface()))
+
+	// Both instances must produce the same results without a new warmup
+	testutil.RequireMetricsEqual(t, period(plugin, 11), period(restored, 11), testutil.IgnoreTime())
+}
+
+func TestTTL(t *testing.T) {
+	plugin := newAnomaly()
+	plugin.TTL = config.Duration(time.Minute)
+	require.NoError(t, plugin.Init())
+
+	period(plugin, 10)
+	require.Len(t, plugin.series, 1)
+
+	for _, s := range plugin.series {
+		s.LastSeen = time.Now().Add(-2 * time.Minute).UnixNano()
+	}
+	period(plugin)
+	require.Empty(t, plugin.series)
+}
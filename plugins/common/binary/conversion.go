@@ -0,0 +1,184 @@
+package binary
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// bitReader reads values of arbitrary bit-length from a buffer. Bits are
+// consumed most-significant bit first. Values spanning multiple complete
+// bytes are interpreted according to the given byte-order.
+type bitReader struct {
+	buf       []byte
+	pos       uint64
+	maxLength uint64
+}
+
+func (r *bitReader) read(n uint64, order binary.ByteOrder) (uint64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	if r.pos+n > uint64(len(r.buf))*8 {
+		return 0, fmt.Errorf("out-of-bounds @%d with %d bits", r.pos, n)
+	}
+
+	var v uint64
+	for range n {
+		bit := (r.buf[r.pos/8] >> (7 - r.pos%8)) & 0x01
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+
+	if n%8 == 0 && n > 8 && order == binary.LittleEndian {
+		v = swapBytes(v, n)
+	}
+	return v, nil
+}
+
+func (r *bitReader) readBytes(n uint64) ([]byte, error) {
+	// Check the length before allocating as it might stem from the input
+	if n > (uint64(len(r.buf))*8-r.pos)/8 {
+		return nil, fmt.Errorf("out-of-bounds @%d with %d bytes", r.pos, n)
+	}
+	out := make([]byte, 0, n)
+	for range n {
+		v, err := r.read(8, binary.BigEndian)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, byte(v))
+	}
+	return out, nil
+}
+
+// bitWriter is the counterpart of bitReader
+type bitWriter struct {
+	buf []byte
+	pos uint64
+}
+
+func (w *bitWriter) write(v, n uint64, order binary.ByteOrder) {
+	if n%8 == 0 && n > 8 && order == binary.LittleEndian {
+		v = swapBytes(v, n)
+	}
+
+	for i := n; i > 0; i-- {
+		if w.pos/8 >= uint64(len(w.buf)) {
+			w.buf = append(w.buf, 0)
+		}
+		bit := byte((v >> (i - 1)) & 0x01)
+		w.buf[w.pos/8] |= bit << (7 - w.pos%8)
+		w.pos++
+	}
+}
+
+func (w *bitWriter) writeBytes(data []byte) {
+	for _, b := range data {
+		w.write(uint64(b), 8, binary.BigEndian)
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	return w.buf
+}
+
+// swapBytes reverses the byte-order of the lowest n bits of v
+func swapBytes(v, n uint64) uint64 {
+	return bits.ReverseBytes64(v) >> (64 - n)
+}
+
+// fromRaw converts the raw bits read to a value of the given type
+func fromRaw(raw uint64, t string, n uint64) (interface{}, error) {
+	switch t {
+	case "uint8":
+		return uint8(raw), nil
+	case "uint16":
+		return uint16(raw), nil
+	case "uint32":
+		return uint32(raw), nil
+	case "uint64":
+		return raw, nil
+	case "int8":
+		return int8(signExtend(raw, n)), nil
+	case "int16":
+		return int16(signExtend(raw, n)), nil
+	case "int32":
+		return int32(signExtend(raw, n)), nil
+	case "int64", "unix", "unix_ms", "unix_us", "unix_ns":
+		return signExtend(raw, n), nil
+	case "float32":
+		return math.Float32frombits(uint32(raw)), nil
+	case "float64":
+		return math.Float64frombits(raw), nil
+	case "bool":
+		return raw != 0, nil
+	}
+	return nil, fmt.Errorf("cannot convert to type %q", t)
+}
+
+// toRaw converts the given value to raw bits of the given type and length
+func toRaw(v interface{}, t string, n uint64) (uint64, error) {
+	var raw uint64
+	switch t {
+	case "uint8", "uint16", "uint32", "uint64":
+		x, err := internal.ToUint64(v)
+		if err != nil {
+			return 0, err
+		}
+		raw = x
+		if n < 64 && raw >= 1<<n {
+			return 0, fmt.Errorf("value %d exceeds %d bits", x, n)
+		}
+	case "int8", "int16", "int32", "int64", "unix", "unix_ms", "unix_us", "unix_ns":
+		x, err := internal.ToInt64(v)
+		if err != nil {
+			return 0, err
+		}
+		if n < 64 && (x < -(1<<(n-1)) || x >= 1<<(n-1)) {
+			return 0, fmt.Errorf("value %d exceeds %d bits", x, n)
+		}
+		raw = uint64(x)
+	case "float32":
+		x, err := internal.ToFloat32(v)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(math.Float32bits(x)), nil
+	case "float64":
+		x, err := internal.ToFloat64(v)
+		if err != nil {
+			return 0, err
+		}
+		return math.Float64bits(x), nil
+	case "bool":
+		x, err := internal.ToBool(v)
+		if err != nil {
+			return 0, err
+		}
+		if x {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, errors.New("unsupported type " + t)
+	}
+
+	if n < 64 {
+		raw &= (1 << n) - 1
+	}
+	return raw, nil
+}
+
+// signExtend interprets the lowest n bits of v as a two's complement number
+func signExtend(v, n uint64) int64 {
+	if n >= 64 {
+		return int64(v)
+	}
+	shift := 64 - n
+	return int64(v<<shift) >> shift
+}
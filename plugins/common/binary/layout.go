@@ -0,0 +1,545 @@
+package binary
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// defaultMaxLength limits the length of prefixed strings and arrays if not
+// configured otherwise
+const defaultMaxLength = 65536
+
+// Entry describes one element of a binary layout
+type Entry struct {
+	Name         string `toml:"name"`
+	Type         string `toml:"type"`
+	Bits         uint64 `toml:"bits"`
+	Assignment   string `toml:"assignment"`
+	Omit         bool   `toml:"omit"`
+	Terminator   string `toml:"terminator"`
+	LengthPrefix string `toml:"length_prefix"`
+	Count        uint64 `toml:"count"`
+
+	termination []byte
+	prefixBits  uint64
+}
+
+// Section is a list of entries only present if the value of the
+// discriminator entry matches one of the given values
+type Section struct {
+	Discriminator string   `toml:"discriminator"`
+	Values        []string `toml:"values"`
+	Entries       []Entry  `toml:"entries"`
+}
+
+// Layout is a binary message definition usable for both, decoding and
+// encoding of metrics
+type Layout struct {
+	Entries   []Entry   `toml:"entries"`
+	Sections  []Section `toml:"sections"`
+	MaxLength uint64    `toml:"max_length"`
+
+	discriminators map[string]*Entry
+}
+
+// Init checks the layout and fills in the defaults
+func (l *Layout) Init() error {
+	if len(l.Entries) == 0 {
+		return errors.New("no entries defined")
+	}
+	if l.MaxLength == 0 {
+		l.MaxLength = defaultMaxLength
+	}
+
+	known := make(map[string]*Entry)
+	for i := range l.Entries {
+		e := &l.Entries[i]
+		if err := e.init(); err != nil {
+			return fmt.Errorf("entry %q (%d): %w", e.Name, i, err)
+		}
+		if !e.Omit {
+			known[e.Name] = e
+		}
+	}
+
+	l.discriminators = make(map[string]*Entry)
+	for i := range l.Sections {
+		s := &l.Sections[i]
+		d, found := known[s.Discriminator]
+		if !found {
+			return fmt.Errorf("section %d: unknown discriminator %q", i, s.Discriminator)
+		}
+		if d.isArray() {
+			return fmt.Errorf("section %d: discriminator %q cannot be an array", i, s.Discriminator)
+		}
+		l.discriminators[s.Discriminator] = d
+		if len(s.Values) == 0 {
+			return fmt.Errorf("section %d: no values given", i)
+		}
+		if len(s.Entries) == 0 {
+			return fmt.Errorf("section %d: no entries given", i)
+		}
+		for j := range s.Entries {
+			e := &s.Entries[j]
+			if err := e.init(); err != nil {
+				return fmt.Errorf("section %d entry %q (%d): %w", i, e.Name, j, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// HasAssignment returns true if any entry of the layout, including the ones
+// in sections, has the given assignment
+func (l *Layout) HasAssignment(assignment string) bool {
+	for _, e := range l.Entries {
+		if !e.Omit && e.Assignment == assignment {
+			return true
+		}
+	}
+	for _, s := range l.Sections {
+		for _, e := range s.Entries {
+			if !e.Omit && e.Assignment == assignment {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decode extracts the data from the given buffer according to the layout
+// and adds it to the given metric.
+func (l *Layout) Decode(in []byte, order binary.ByteOrder, m telegraf.Metric) error {
+	r := &bitReader{buf: in, maxLength: l.MaxLength}
+	values := make(map[string]string)
+
+	for i := range l.Entries {
+		e := &l.Entries[i]
+		if err := e.decode(r, order, m); err != nil {
+			return err
+		}
+		if _, found := l.discriminators[e.Name]; found {
+			values[e.Name] = discriminatorValue(e, m)
+		}
+	}
+
+	for i := range l.Sections {
+		s := &l.Sections[i]
+		if !slices.Contains(s.Values, values[s.Discriminator]) {
+			continue
+		}
+		for j := range s.Entries {
+			if err := s.Entries[j].decode(r, order, m); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Encode serializes the given metric according to the layout
+func (l *Layout) Encode(m telegraf.Metric, order binary.ByteOrder) ([]byte, error) {
+	w := &bitWriter{}
+
+	for i := range l.Entries {
+		if err := l.Entries[i].encode(w, order, m); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range l.Sections {
+		s := &l.Sections[i]
+		if !slices.Contains(s.Values, discriminatorValue(l.discriminators[s.Discriminator], m)) {
+			continue
+		}
+		for j := range s.Entries {
+			if err := s.Entries[j].encode(w, order, m); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return w.bytes(), nil
+}
+
+func discriminatorValue(e *Entry, m telegraf.Metric) string {
+	var v interface{}
+	var found bool
+	switch e.Assignment {
+	case "measurement":
+		return m.Name()
+	case "time":
+		return strconv.FormatInt(m.Time().UnixNano(), 10)
+	case "tag":
+		v, found = m.GetTag(e.Name)
+	default:
+		v, found = m.GetField(e.Name)
+	}
+	if !found {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func (e *Entry) init() error {
+	// Normalize cases
+	e.Assignment = strings.ToLower(e.Assignment)
+	e.Type = strings.ToLower(e.Type)
+	e.Terminator = strings.ToLower(e.Terminator)
+
+	switch e.Assignment {
+	case "measurement":
+		e.Name = e.Assignment
+		if e.Type == "" {
+			e.Type = "string"
+		}
+		if e.Type != "string" {
+			return errors.New("'measurement' type has to be 'string'")
+		}
+	case "time":
+		e.Name = e.Assignment
+		switch e.Type {
+		case "":
+			e.Type = "unix"
+		case "unix", "unix_ms", "unix_us", "unix_ns":
+		default:
+			return fmt.Errorf("invalid time type %q", e.Type)
+		}
+		if e.Bits == 0 {
+			e.Bits = 64
+		}
+		if e.Bits > 64 {
+			return errors.New("time exceeds 64 bits")
+		}
+	case "tag":
+		if e.Type == "" {
+			e.Type = "string"
+		}
+	case "", "field":
+		e.Assignment = "field"
+	default:
+		return fmt.Errorf("invalid assignment %q", e.Assignment)
+	}
+
+	if e.Name == "" && !e.Omit {
+		return errors.New("missing name")
+	}
+
+	// Check the prefix for variable length strings and arrays
+	switch e.LengthPrefix {
+	case "":
+	case "uint8", "uint16", "uint32":
+		e.prefixBits, _ = bitsForType(e.LengthPrefix)
+		if e.Count != 0 {
+			return errors.New("cannot use 'count' and 'length_prefix' together")
+		}
+	default:
+		return fmt.Errorf("invalid length prefix %q", e.LengthPrefix)
+	}
+
+	if e.isArray() && (e.Assignment == "measurement" || e.Assignment == "time") {
+		return errors.New("arrays are only supported for fields and tags")
+	}
+
+	switch e.Type {
+	case "int8", "int16", "int32", "int64", "uint8", "uint16", "uint32", "uint64":
+		width, _ := bitsForType(e.Type)
+		if e.Bits == 0 {
+			e.Bits = width
+		}
+		if e.Bits > width {
+			return fmt.Errorf("%d bits exceed type %q", e.Bits, e.Type)
+		}
+	case "float32", "float64":
+		width, _ := bitsForType(e.Type)
+		if e.Bits == 0 {
+			e.Bits = width
+		}
+		if e.Bits != width {
+			return fmt.Errorf("floating-point types require %d bits", width)
+		}
+	case "bool":
+		if e.Bits == 0 {
+			e.Bits = 1
+		}
+		if e.Bits > 64 {
+			return errors.New("bool exceeds 64 bits")
+		}
+	case "string":
+		switch {
+		case e.LengthPrefix != "":
+			if e.Bits != 0 || (e.Terminator != "" && e.Terminator != "fixed") {
+				return errors.New("cannot use 'length_prefix' with 'bits' or 'terminator'")
+			}
+			e.Terminator = ""
+		case e.Terminator == "" || e.Terminator == "fixed":
+			e.Terminator = "fixed"
+			if e.Bits == 0 {
+				return errors.New("require 'bits' for fixed-length string")
+			}
+		case e.Terminator == "null":
+			e.termination = []byte{0}
+		default:
+			var err error
+			e.termination, err = hex.DecodeString(strings.TrimPrefix(e.Terminator, "0x"))
+			if err != nil {
+				return fmt.Errorf("decoding terminator failed: %w", err)
+			}
+			if len(e.termination) == 0 {
+				return errors.New("empty terminator")
+			}
+		}
+		if e.Terminator != "fixed" && e.Bits != 0 {
+			return errors.New("cannot use 'bits' and terminator together")
+		}
+		if e.Bits%8 != 0 {
+			return errors.New("non-byte length for string")
+		}
+		if e.Count != 0 {
+			return errors.New("arrays of strings are not supported")
+		}
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+		if e.Assignment != "time" {
+			return fmt.Errorf("type %q requires 'time' assignment", e.Type)
+		}
+	case "":
+		if !e.Omit || e.Bits == 0 {
+			return errors.New("missing type")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", e.Type)
+	}
+
+	return nil
+}
+
+func (e *Entry) isArray() bool {
+	return e.Count > 0 || (e.LengthPrefix != "" && e.Type != "string")
+}
+
+func (e *Entry) decode(r *bitReader, order binary.ByteOrder, m telegraf.Metric) error {
+	// Handle variable-length strings and arrays
+	count := e.Count
+	if e.LengthPrefix != "" {
+		n, err := r.read(e.prefixBits, order)
+		if err != nil {
+			return fmt.Errorf("reading length of %q failed: %w", e.Name, err)
+		}
+		if n > r.maxLength {
+			return fmt.Errorf("length %d of %q exceeds maximum of %d", n, e.Name, r.maxLength)
+		}
+		if e.Type == "string" {
+			data, err := r.readBytes(n)
+			if err != nil {
+				return fmt.Errorf("reading %q failed: %w", e.Name, err)
+			}
+			return e.assign(m, e.Name, string(data))
+		}
+		count = n
+	}
+
+	if count > 0 {
+		for i := range count {
+			v, err := e.decodeValue(r, order)
+			if err != nil {
+				return fmt.Errorf("reading %q element %d failed: %w", e.Name, i, err)
+			}
+			if err := e.assign(m, e.Name+"_"+strconv.FormatUint(i, 10), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	v, err := e.decodeValue(r, order)
+	if err != nil {
+		return fmt.Errorf("reading %q failed: %w", e.Name, err)
+	}
+	return e.assign(m, e.Name, v)
+}
+
+func (e *Entry) decodeValue(r *bitReader, order binary.ByteOrder) (interface{}, error) {
+	if e.Type == "string" {
+		if e.Terminator == "fixed" {
+			data, err := r.readBytes(e.Bits / 8)
+			if err != nil {
+				return nil, err
+			}
+			return strings.TrimRight(string(data), "\x00"), nil
+		}
+		var data []byte
+		for !bytes.HasSuffix(data, e.termination) {
+			b, err := r.readBytes(1)
+			if err != nil {
+				return nil, errors.New("terminator not found")
+			}
+			data = append(data, b...)
+		}
+		return string(data[:len(data)-len(e.termination)]), nil
+	}
+
+	raw, err := r.read(e.Bits, order)
+	if err != nil {
+		return nil, err
+	}
+	if e.Omit {
+		return nil, nil
+	}
+	return fromRaw(raw, e.Type, e.Bits)
+}
+
+func (e *Entry) assign(m telegraf.Metric, name string, v interface{}) error {
+	if e.Omit {
+		return nil
+	}
+
+	switch e.Assignment {
+	case "measurement":
+		m.SetName(v.(string))
+	case "time":
+		var t time.Time
+		ts := v.(int64)
+		switch e.Type {
+		case "unix":
+			t = time.Unix(ts, 0)
+		case "unix_ms":
+			t = time.UnixMilli(ts)
+		case "unix_us":
+			t = time.UnixMicro(ts)
+		case "unix_ns":
+			t = time.Unix(0, ts)
+		}
+		m.SetTime(t.UTC())
+	case "tag":
+		m.AddTag(name, fmt.Sprint(v))
+	default:
+		m.AddField(name, v)
+	}
+	return nil
+}
+
+func (e *Entry) encode(w *bitWriter, order binary.ByteOrder, m telegraf.Metric) error {
+	if e.Omit {
+		if e.Type == "string" {
+			w.writeBytes(make([]byte, e.Bits/8))
+			return nil
+		}
+		w.write(0, e.Bits, order)
+		return nil
+	}
+
+	if e.isArray() {
+		var values []interface{}
+		for i := uint64(0); e.Count == 0 || i < e.Count; i++ {
+			v, found := e.lookup(m, e.Name+"_"+strconv.FormatUint(i, 10))
+			if !found {
+				if e.Count > 0 {
+					return fmt.Errorf("element %d of %q not found", i, e.Name)
+				}
+				break
+			}
+			values = append(values, v)
+		}
+		if e.LengthPrefix != "" {
+			if err := e.writePrefix(w, order, uint64(len(values))); err != nil {
+				return err
+			}
+		}
+		for i, v := range values {
+			if err := e.encodeValue(w, order, v); err != nil {
+				return fmt.Errorf("element %d of %q: %w", i, e.Name, err)
+			}
+		}
+		return nil
+	}
+
+	v, found := e.lookup(m, e.Name)
+	if !found {
+		return fmt.Errorf("%s %q not found", e.Assignment, e.Name)
+	}
+	if err := e.encodeValue(w, order, v); err != nil {
+		return fmt.Errorf("%s %q: %w", e.Assignment, e.Name, err)
+	}
+	return nil
+}
+
+func (e *Entry) lookup(m telegraf.Metric, name string) (interface{}, bool) {
+	switch e.Assignment {
+	case "measurement":
+		return m.Name(), true
+	case "time":
+		switch e.Type {
+		case "unix":
+			return m.Time().Unix(), true
+		case "unix_ms":
+			return m.Time().UnixMilli(), true
+		case "unix_us":
+			return m.Time().UnixMicro(), true
+		}
+		return m.Time().UnixNano(), true
+	case "tag":
+		return m.GetTag(name)
+	}
+	return m.GetField(name)
+}
+
+func (e *Entry) writePrefix(w *bitWriter, order binary.ByteOrder, n uint64) error {
+	if n >= 1<<e.prefixBits {
+		return fmt.Errorf("length %d of %q exceeds prefix %q", n, e.Name, e.LengthPrefix)
+	}
+	w.write(n, e.prefixBits, order)
+	return nil
+}
+
+func (e *Entry) encodeValue(w *bitWriter, order binary.ByteOrder, v interface{}) error {
+	if e.Type == "string" {
+		s := fmt.Sprint(v)
+		switch {
+		case e.LengthPrefix != "":
+			if err := e.writePrefix(w, order, uint64(len(s))); err != nil {
+				return err
+			}
+			w.writeBytes([]byte(s))
+		case e.Terminator == "fixed":
+			buf := make([]byte, e.Bits/8)
+			copy(buf, s)
+			w.writeBytes(buf)
+		default:
+			w.writeBytes([]byte(s))
+			w.writeBytes(e.termination)
+		}
+		return nil
+	}
+
+	raw, err := toRaw(v, e.Type, e.Bits)
+	if err != nil {
+		return err
+	}
+	w.write(raw, e.Bits, order)
+	return nil
+}
+
+func bitsForType(t string) (uint64, error) {
+	switch t {
+	case "uint8", "int8":
+		return 8, nil
+	case "uint16", "int16":
+		return 16, nil
+	case "uint32", "int32", "float32":
+		return 32, nil
+	case "uint64", "int64", "float64":
+		return 64, nil
+	}
+	return 0, fmt.Errorf("cannot determine length for type %q", t)
+}
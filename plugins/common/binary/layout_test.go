@@ -0,0 +1,297 @@
+package binary
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   Layout
+		order    binary.ByteOrder
+		data     []byte
+		expected telegraf.Metric
+	}{
+		{
+			name: "bitfields",
+			layout: Layout{
+				Entries: []Entry{
+					{Name: "version", Type: "uint8", Bits: 3},
+					{Name: "alarm", Type: "bool"},
+					{Name: "offset", Type: "int8", Bits: 4},
+					{Name: "value", Type: "uint16", Bits: 12},
+					{Omit: true, Bits: 4},
+				},
+			},
+			order: binary.BigEndian,
+			data:  []byte{0b101_1_1110, 0xAB, 0xC0},
+			expected: metric.New(
+				"test",
+				map[string]string{},
+				map[string]interface{}{
+					"version": uint8(5),
+					"alarm":   true,
+					"offset":  int8(-2),
+					"value":   uint16(0xABC),
+				},
+				time.Unix(0, 0),
+			),
+		},
+		{
+			name: "endianness",
+			layout: Layout{
+				Entries: []Entry{
+					{Name: "a", Type: "uint16"},
+					{Name: "b", Type: "int32"},
+					{Name: "c", Type: "float32"},
+				},
+			},
+			order: binary.LittleEndian,
+			data:  []byte{0x34, 0x12, 0xFE, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0xC0, 0x3F},
+			expected: metric.New(
+				"test",
+				map[string]string{},
+				map[string]interface{}{
+					"a": uint16(0x1234),
+					"b": int32(-2),
+					"c": float32(1.5),
+				},
+				time.Unix(0, 0),
+			),
+		},
+		{
+			name: "strings",
+			layout: Layout{
+				Entries: []Entry{
+					{Assignment: "measurement", Terminator: "null"},
+					{Name: "site", Assignment: "tag", LengthPrefix: "uint8"},
+					{Name: "fixed", Type: "string", Bits: 48},
+					{Name: "line", Type: "string", Terminator: "0x0D0A"},
+					{Name: "value", Type: "uint8"},
+				},
+			},
+			order: binary.BigEndian,
+			data: []byte{
+				'm', 'e', 't', 'e', 'r', 0x00,
+				0x05, 'n', 'o', 'r', 't', 'h',
+				'a', 'b', 'c', 0x00, 0x00, 0x00,
+				'h', 'i', 0x0D, 0x0A,
+				0x2A,
+			},
+			expected: metric.New(
+				"meter",
+				map[string]string{"site": "north"},
+				map[string]interface{}{
+					"fixed": "abc",
+					"line":  "hi",
+					"value": uint8(42),
+				},
+				time.Unix(0, 0),
+			),
+		},
+		{
+			name: "arrays",
+			layout: Layout{
+				Entries: []Entry{
+					{Name: "fixed", Type: "int16", Count: 2},
+					{Name: "dynamic", Type: "uint8", LengthPrefix: "uint16"},
+					{Name: "flag", Type: "bool", Count: 4},
+					{Omit: true, Bits: 4},
+				},
+			},
+			order: binary.BigEndian,
+			data:  []byte{0x00, 0x01, 0xFF, 0xFF, 0x00, 0x03, 0x0A, 0x0B, 0x0C, 0b1010_0000},
+			expected: metric.New(
+				"test",
+				map[string]string{},
+				map[string]interface{}{
+					"fixed_0":   int16(1),
+					"fixed_1":   int16(-1),
+					"dynamic_0": uint8(10),
+					"dynamic_1": uint8(11),
+					"dynamic_2": uint8(12),
+					"flag_0":    true,
+					"flag_1":    false,
+					"flag_2":    true,
+					"flag_3":    false,
+				},
+				time.Unix(0, 0),
+			),
+		},
+		{
+			name: "time",
+			layout: Layout{
+				Entries: []Entry{
+					{Assignment: "time", Type: "unix_ms"},
+					{Name: "value", Type: "uint8"},
+				},
+			},
+			order: binary.BigEndian,
+			data:  []byte{0x00, 0x00, 0x01, 0x8B, 0xCF, 0xE5, 0x68, 0x00, 0x01},
+			expected: metric.New(
+				"test",
+				map[string]string{},
+				map[string]interface{}{"value": uint8(1)},
+				time.UnixMilli(1700000000000),
+			),
+		},
+		{
+			name: "sections",
+			layout: Layout{
+				Entries: []Entry{
+					{Name: "type", Type: "uint8", Assignment: "tag"},
+				},
+				Sections: []Section{
+					{
+						Discriminator: "type",
+						Values:        []string{"1"},
+						Entries:       []Entry{{Name: "temperature", Type: "float32"}},
+					},
+					{
+						Discriminator: "type",
+						Values:        []string{"2", "3"},
+						Entries:       []Entry{{Name: "humidity", Type: "uint8"}},
+					},
+					{
+						Discriminator: "type",
+						Values:        []string{"2"},
+						Entries:       []Entry{{Name: "pressure", Type: "uint16"}},
+					},
+				},
+			},
+			order: binary.BigEndian,
+			data:  []byte{0x02, 0x37, 0x03, 0xF5},
+			expected: metric.New(
+				"test",
+				map[string]string{"type": "2"},
+				map[string]interface{}{
+					"humidity": uint8(55),
+					"pressure": uint16(1013),
+				},
+				time.Unix(0, 0),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.layout.Init())
+
+			// Decode
+			actual := metric.New("test", map[string]string{}, map[string]interface{}{}, time.Unix(0, 0))
+			require.NoError(t, tt.layout.Decode(tt.data, tt.order, actual))
+			testutil.RequireMetricEqual(t, tt.expected, actual)
+
+			// Encode
+			encoded, err := tt.layout.Encode(tt.expected, tt.order)
+			require.NoError(t, err)
+			require.Equal(t, tt.data, encoded)
+		})
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	layout := Layout{
+		Entries: []Entry{
+			{Name: "small", Type: "uint8", Bits: 3},
+			{Name: "array", Type: "uint8", Count: 2},
+		},
+	}
+	require.NoError(t, layout.Init())
+
+	m := metric.New("test", nil, map[string]interface{}{"small": 8, "array_0": 1, "array_1": 2}, time.Unix(0, 0))
+	_, err := layout.Encode(m, binary.BigEndian)
+	require.ErrorContains(t, err, "value 8 exceeds 3 bits")
+
+	m = metric.New("test", nil, map[string]interface{}{"small": 7, "array_0": 1}, time.Unix(0, 0))
+	_, err = layout.Encode(m, binary.BigEndian)
+	require.ErrorContains(t, err, `element 1 of "array" not found`)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	layout := Layout{
+		Entries: []Entry{
+			{Name: "name", Type: "string", Terminator: "null"},
+			{Name: "value", Type: "uint16"},
+		},
+	}
+	require.NoError(t, layout.Init())
+
+	m := metric.New("test", nil, nil, time.Unix(0, 0))
+	require.ErrorContains(t, layout.Decode([]byte{'a', 'b'}, binary.BigEndian, m), "terminator not found")
+	require.ErrorContains(t, layout.Decode([]byte{'a', 0x00, 0x01}, binary.BigEndian, m), "out-of-bounds")
+}
+
+func TestDecodeOversizedLengthPrefix(t *testing.T) {
+	layout := Layout{
+		Entries: []Entry{
+			{Name: "name", Type: "string", LengthPrefix: "uint32"},
+			{Name: "samples", Type: "uint8", LengthPrefix: "uint32"},
+		},
+	}
+	require.NoError(t, layout.Init())
+
+	// The prefix exceeds the maximum length
+	m := metric.New("test", nil, nil, time.Unix(0, 0))
+	err := layout.Decode([]byte{0xff, 0xff, 0xff, 0xff, 'a'}, binary.BigEndian, m)
+	require.ErrorContains(t, err, `length 4294967295 of "name" exceeds maximum of 65536`)
+
+	// The prefix exceeds the remaining data
+	err = layout.Decode([]byte{0x00, 0x00, 0x01, 0x00, 'a'}, binary.BigEndian, m)
+	require.ErrorContains(t, err, "out-of-bounds @32 with 256 bytes")
+
+	// The prefix of an array exceeds the configured maximum
+	layout.MaxLength = 2
+	err = layout.Decode([]byte{0x00, 0x00, 0x00, 0x01, 'a', 0x00, 0x00, 0x00, 0x03, 0x01, 0x02, 0x03}, binary.BigEndian, m)
+	require.ErrorContains(t, err, `length 3 of "samples" exceeds maximum of 2`)
+}
+
+func TestInvalidLayouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   Layout
+		expected string
+	}{
+		{
+			name:     "empty",
+			expected: "no entries defined",
+		},
+		{
+			name:     "bits exceed type",
+			layout:   Layout{Entries: []Entry{{Name: "a", Type: "uint8", Bits: 9}}},
+			expected: `9 bits exceed type "uint8"`,
+		},
+		{
+			name:     "count and prefix",
+			layout:   Layout{Entries: []Entry{{Name: "a", Type: "uint8", Count: 2, LengthPrefix: "uint8"}}},
+			expected: "cannot use 'count' and 'length_prefix' together",
+		},
+		{
+			name:     "invalid prefix",
+			layout:   Layout{Entries: []Entry{{Name: "a", Type: "string", LengthPrefix: "int8"}}},
+			expected: `invalid length prefix "int8"`,
+		},
+		{
+			name: "unknown discriminator",
+			layout: Layout{
+				Entries:  []Entry{{Name: "a", Type: "uint8"}},
+				Sections: []Section{{Discriminator: "b", Values: []string{"1"}, Entries: []Entry{{Name: "c", Type: "uint8"}}}},
+			},
+			expected: `section 0: unknown discriminator "b"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.layout.Init(), tt.expected)
+		})
+	}
+}
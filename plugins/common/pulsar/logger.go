@@ -0,0 +1,98 @@
+package pulsar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/pulsar-client-go/pulsar/log"
+
+	"github.com/influxdata/telegraf"
+)
+
+// logger forwards the messages of the Pulsar client to the plugin's logger.
+// Informational messages of the client are logged at debug level as they
+// are very verbose.
+type logger struct {
+	log    telegraf.Logger
+	fields log.Fields
+}
+
+func (l *logger) SubLogger(fields log.Fields) log.Logger {
+	return &logger{log: l.log, fields: l.merge(fields)}
+}
+
+func (l *logger) WithFields(fields log.Fields) log.Entry {
+	return &logger{log: l.log, fields: l.merge(fields)}
+}
+
+func (l *logger) WithField(name string, value interface{}) log.Entry {
+	return &logger{log: l.log, fields: l.merge(log.Fields{name: value})}
+}
+
+func (l *logger) WithError(err error) log.Entry {
+	return &logger{log: l.log, fields: l.merge(log.Fields{"error": err})}
+}
+
+func (l *logger) Debug(args ...interface{}) {
+	l.log.Trace(l.message(fmt.Sprint(args...)))
+}
+
+func (l *logger) Info(args ...interface{}) {
+	l.log.Debug(l.message(fmt.Sprint(args...)))
+}
+
+func (l *logger) Warn(args ...interface{}) {
+	l.log.Warn(l.message(fmt.Sprint(args...)))
+}
+
+func (l *logger) Error(args ...interface{}) {
+	l.log.Error(l.message(fmt.Sprint(args...)))
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) {
+	l.log.Trace(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	l.log.Debug(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *logger) Warnf(format string, args ...interface{}) {
+	l.log.Warn(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) {
+	l.log.Error(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *logger) merge(fields log.Fields) log.Fields {
+	merged := make(log.Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
+}
+
+// message appends the fields to the message in a stable order
+func (l *logger) message(msg string) string {
+	if len(l.fields) == 0 {
+		return msg
+	}
+
+	keys := make([]string, 0, len(l.fields))
+	for k := range l.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, l.fields[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package pulsar
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// ClientConfig contains the connection settings common to all Pulsar
+// clients
+type ClientConfig struct {
+	URL               string          `toml:"url"`
+	Token             config.Secret   `toml:"token"`
+	ConnectionTimeout config.Duration `toml:"connection_timeout"`
+	OperationTimeout  config.Duration `toml:"operation_timeout"`
+	tls.ClientConfig
+}
+
+// NewClient creates a Pulsar client using the given logger for the client's
+// messages
+func (c *ClientConfig) NewClient(log telegraf.Logger) (pulsar.Client, error) {
+	if c.URL == "" {
+		return nil, errors.New("'url' required")
+	}
+
+	opts := pulsar.ClientOptions{
+		URL:                        c.URL,
+		ConnectionTimeout:          time.Duration(c.ConnectionTimeout),
+		OperationTimeout:           time.Duration(c.OperationTimeout),
+		TLSTrustCertsFilePath:      c.TLSCA,
+		TLSCertificateFile:         c.TLSCert,
+		TLSKeyFilePath:             c.TLSKey,
+		TLSAllowInsecureConnection: c.InsecureSkipVerify,
+		TLSValidateHostname:        !c.InsecureSkipVerify,
+		Logger:                     &logger{log: log},
+	}
+
+	// Prefer token authentication and fall back to authenticating with the
+	// client certificate if any
+	switch {
+	case !c.Token.Empty():
+		opts.Authentication = pulsar.NewAuthenticationTokenFromSupplier(func() (string, error) {
+			token, err := c.Token.Get()
+			if err != nil {
+				return "", fmt.Errorf("getting token failed: %w", err)
+			}
+			defer token.Destroy()
+			return token.String(), nil
+		})
+	case c.TLSCert != "":
+		opts.Authentication = pulsar.NewAuthenticationTLS(c.TLSCert, c.TLSKey)
+	}
+
+	return pulsar.NewClient(opts)
+}
@@ -0,0 +1,26 @@
+package pulsar
+
+import (
+	"testing"
+
+	"github.com/apache/pulsar-client-go/pulsar/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestNewClientMissingURL(t *testing.T) {
+	cfg := &ClientConfig{}
+	_, err := cfg.NewClient(testutil.Logger{})
+	require.ErrorContains(t, err, "'url' required")
+}
+
+func TestLoggerFields(t *testing.T) {
+	l := &logger{log: testutil.Logger{}}
+	sub := l.SubLogger(log.Fields{"topic": "telegraf"})
+	entry := sub.WithField("partition", 1).WithFields(log.Fields{"consumer": "c1"})
+
+	require.Equal(t, "closed consumer=c1 partition=1 topic=telegraf", entry.(*logger).message("closed"))
+	require.Equal(t, "closed topic=telegraf", sub.(*logger).message("closed"))
+	require.Equal(t, "closed", l.message("closed"))
+}
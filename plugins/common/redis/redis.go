@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// ClientConfig contains the connection settings common to Redis clients
+type ClientConfig struct {
+	Address  string          `toml:"address"`
+	Username config.Secret   `toml:"username"`
+	Password config.Secret   `toml:"password"`
+	Database int             `toml:"database"`
+	Timeout  config.Duration `toml:"timeout"`
+	tls.ClientConfig
+}
+
+// NewClient creates a Redis client and checks the connection to the server
+func (c *ClientConfig) NewClient() (*redis.Client, error) {
+	if c.Address == "" {
+		return nil, errors.New("'address' required")
+	}
+
+	username, err := c.Username.Get()
+	if err != nil {
+		return nil, fmt.Errorf("getting username failed: %w", err)
+	}
+	defer username.Destroy()
+
+	password, err := c.Password.Get()
+	if err != nil {
+		return nil, fmt.Errorf("getting password failed: %w", err)
+	}
+	defer password.Destroy()
+
+	tlsConfig, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("creating TLS config failed: %w", err)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:                  c.Address,
+		Username:              username.String(),
+		Password:              password.String(),
+		DB:                    c.Database,
+		TLSConfig:             tlsConfig,
+		ContextTimeoutEnabled: true,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout))
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("connecting failed: %w", err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+func TestNewClient(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireUserAuth("telegraf", "secret")
+
+	cfg := &ClientConfig{
+		Address:  server.Addr(),
+		Username: config.NewSecret([]byte("telegraf")),
+		Password: config.NewSecret([]byte("secret")),
+		Timeout:  config.Duration(time.Second),
+	}
+	client, err := cfg.NewClient()
+	require.NoError(t, err)
+	require.NoError(t, client.Close())
+
+	cfg.Password = config.NewSecret([]byte("wrong"))
+	_, err = cfg.NewClient()
+	require.ErrorContains(t, err, "connecting failed")
+}
+
+func TestNewClientMissingAddress(t *testing.T) {
+	cfg := &ClientConfig{}
+	_, err := cfg.NewClient()
+	require.ErrorContains(t, err, "'address' required")
+}
@@ -13,6 +13,7 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -52,6 +53,7 @@ type InfluxDBV2Listener struct {
 	Token                 config.Secret   `toml:"token"`
 	BucketTag             string          `toml:"bucket_tag"`
 	ParserType            string          `toml:"parser_type"`
+	ParseMode             string          `toml:"parse_mode"`
 
 	Log telegraf.Logger `toml:"-"`
 
@@ -106,6 +108,18 @@ func (h *InfluxDBV2Listener) Init() error {
 		return err
 	}
 
+	switch h.ParseMode {
+	case "":
+		h.ParseMode = "strict"
+	case "strict":
+	case "lenient":
+		if h.ParserType == "upstream" {
+			return errors.New("parse mode 'lenient' is not supported by the upstream parser")
+		}
+	default:
+		return fmt.Errorf("invalid parse mode %q", h.ParseMode)
+	}
+
 	if h.MaxBodySize == 0 {
 		h.MaxBodySize = config.Size(defaultMaxBodySize)
 	}
@@ -319,7 +333,7 @@ func (h *InfluxDBV2Listener) handleWrite() http.HandlerFunc {
 
 			metrics, err = parser.Parse(bytes)
 		} else {
-			parser := influx.Parser{}
+			parser := influx.Parser{InfluxParseMode: h.ParseMode}
 			err = parser.Init()
 			if !errors.Is(err, io.EOF) && err != nil {
 				h.Log.Debugf("Error initializing parser: %v", err.Error())
@@ -335,7 +349,10 @@ func (h *InfluxDBV2Listener) handleWrite() http.HandlerFunc {
 			metrics, err = parser.Parse(bytes)
 		}
 
-		if !errors.Is(err, io.EOF) && err != nil {
+		var partialErr *influx.PartialParseError
+		if errors.As(err, &partialErr) {
+			h.Log.Debugf("Skipped %d invalid lines in the request body: %v", len(partialErr.Errors), err)
+		} else if !errors.Is(err, io.EOF) && err != nil {
 			h.Log.Debugf("Error parsing the request body: %v", err.Error())
 			if err := badRequest(res, invalid, err.Error()); err != nil {
 				h.Log.Debugf("error in bad-request: %v", err)
@@ -351,19 +368,31 @@ func (h *InfluxDBV2Listener) handleWrite() http.HandlerFunc {
 		}
 
 		if h.MaxUndeliveredMetrics > 0 {
-			h.writeWithTracking(res, metrics)
+			if !h.writeWithTracking(res, metrics) {
+				return
+			}
 		} else {
-			h.write(res, metrics)
+			h.write(metrics)
 		}
+
+		if partialErr != nil {
+			if err := partialWrite(res, len(metrics), partialErr); err != nil {
+				h.Log.Debugf("error in partial-write: %v", err)
+			}
+			return
+		}
+		res.WriteHeader(http.StatusNoContent)
 	}
 }
 
-func (h *InfluxDBV2Listener) writeWithTracking(res http.ResponseWriter, metrics []telegraf.Metric) {
+// writeWithTracking adds the metrics as tracking group and returns true if
+// the metrics were accepted. Otherwise the rejection is written to the response.
+func (h *InfluxDBV2Listener) writeWithTracking(res http.ResponseWriter, metrics []telegraf.Metric) bool {
 	if len(metrics) > h.MaxUndeliveredMetrics {
 		res.WriteHeader(http.StatusRequestEntityTooLarge)
 		h.Log.Debugf("status %d, always rejecting batch of %d metrics: larger than max_undelivered_metrics %d",
 			http.StatusRequestEntityTooLarge, len(metrics), h.MaxUndeliveredMetrics)
-		return
+		return false
 	}
 
 	pending := h.totalUndeliveredMetrics.Load()
@@ -372,7 +401,7 @@ func (h *InfluxDBV2Listener) writeWithTracking(res http.ResponseWriter, metrics
 		res.WriteHeader(http.StatusTooManyRequests)
 		h.Log.Debugf("status %d, rejecting batch of %d metrics: larger than remaining undelivered metrics %d",
 			http.StatusTooManyRequests, len(metrics), remainingUndeliveredMetrics)
-		return
+		return false
 	}
 
 	h.countLock.Lock()
@@ -381,15 +410,13 @@ func (h *InfluxDBV2Listener) writeWithTracking(res http.ResponseWriter, metrics
 	h.totalUndeliveredMetrics.Add(int64(len(metrics)))
 	h.countLock.Unlock()
 
-	res.WriteHeader(http.StatusNoContent)
+	return true
 }
 
-func (h *InfluxDBV2Listener) write(res http.ResponseWriter, metrics []telegraf.Metric) {
+func (h *InfluxDBV2Listener) write(metrics []telegraf.Metric) {
 	for _, m := range metrics {
 		h.acc.AddMetric(m)
 	}
-
-	res.WriteHeader(http.StatusNoContent)
 }
 
 func tooLarge(res http.ResponseWriter, maxLength int64) error {
@@ -427,6 +454,35 @@ func badRequest(res http.ResponseWriter, code BadRequestCode, errString string)
 	return err
 }
 
+// partialWrite reports the invalid lines of a request in lenient mode in the
+// format of InfluxDB's line-protocol error, including the first invalid line.
+func partialWrite(res http.ResponseWriter, written int, perr *influx.PartialParseError) error {
+	errString := fmt.Sprintf("partial write error (%d written): %s", written, perr.Error())
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("X-Influxdb-Error", errString)
+	res.WriteHeader(http.StatusBadRequest)
+
+	lines := make([]string, 0, len(perr.Errors))
+	for _, e := range perr.Errors {
+		lines = append(lines, e.Error())
+	}
+	body := map[string]interface{}{
+		"code":    fmt.Sprint(invalid),
+		"message": errString,
+		"op":      "",
+		"err":     strings.Join(lines, "\n"),
+	}
+	if len(perr.Errors) > 0 {
+		body["line"] = perr.Errors[0].LineNumber
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	_, err = res.Write(b)
+	return err
+}
+
 func getPrecisionMultiplier(precision string) time.Duration {
 	// Influxdb defaults silently to nanoseconds if precision isn't
 	// one of the following:
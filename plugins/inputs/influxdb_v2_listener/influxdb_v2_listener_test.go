@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/url"
@@ -264,6 +265,48 @@ func TestAllOrNothing(t *testing.T) {
 	}
 }
 
+func TestPartialWriteLenient(t *testing.T) {
+	listener := newTestListener()
+	listener.ParseMode = "lenient"
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Init())
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	resp, err := http.Post(createURL(listener, "http", "/api/v2/write", "bucket=mybucket"), "", bytes.NewBufferString(testPartial))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.EqualValues(t, 400, resp.StatusCode)
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, "invalid", body["code"])
+	require.EqualValues(t, 2, body["line"])
+	require.Contains(t, body["message"], "partial write error (2 written)")
+
+	acc.Wait(2)
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"value1": float64(1)},
+		map[string]string{"host": "a"},
+	)
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"value1": float64(1)},
+		map[string]string{"host": "c"},
+	)
+}
+
+func TestInvalidParseMode(t *testing.T) {
+	listener := newTestListener()
+	listener.ParseMode = "lenient"
+	listener.ParserType = "upstream"
+	require.ErrorContains(t, listener.Init(), "not supported by the upstream parser")
+
+	listener = newTestListener()
+	listener.ParseMode = "foo"
+	require.ErrorContains(t, listener.Init(), `invalid parse mode "foo"`)
+}
+
 func TestWriteMaxLineSizeIncrease(t *testing.T) {
 	// The term 'master_repl' used here is archaic language from redis
 	hugeMetric, err := os.ReadFile("./testdata/huge_metric")
@@ -0,0 +1,263 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package pulsar_consumer
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common_pulsar "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+var once sync.Once
+
+type PulsarConsumer struct {
+	Topics                      []string        `toml:"topics"`
+	TopicsPattern               string          `toml:"topics_pattern"`
+	TopicsRefreshInterval       config.Duration `toml:"topics_refresh_interval"`
+	SubscriptionName            string          `toml:"subscription_name"`
+	SubscriptionType            string          `toml:"subscription_type"`
+	SubscriptionInitialPosition string          `toml:"subscription_initial_position"`
+	ReceiverQueueSize           int             `toml:"receiver_queue_size"`
+	NackRedeliveryDelay         config.Duration `toml:"nack_redelivery_delay"`
+	MaxUndeliveredMessages      int             `toml:"max_undelivered_messages"`
+	TopicTag                    string          `toml:"topic_tag"`
+	Log                         telegraf.Logger `toml:"-"`
+	common_pulsar.ClientConfig
+
+	parser          telegraf.Parser
+	subType         pulsar.SubscriptionType
+	initialPosition pulsar.SubscriptionInitialPosition
+
+	client      pulsar.Client
+	consumer    pulsar.Consumer
+	acc         telegraf.TrackingAccumulator
+	undelivered map[telegraf.TrackingID]pulsar.ConsumerMessage
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+func (*PulsarConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PulsarConsumer) SetParser(parser telegraf.Parser) {
+	p.parser = parser
+}
+
+func (p *PulsarConsumer) Init() error {
+	if len(p.Topics) == 0 && p.TopicsPattern == "" {
+		return errors.New("either 'topics' or 'topics_pattern' required")
+	}
+	if len(p.Topics) > 0 && p.TopicsPattern != "" {
+		return errors.New("'topics' and 'topics_pattern' are mutually exclusive")
+	}
+	if p.SubscriptionName == "" {
+		return errors.New("'subscription_name' required")
+	}
+	if p.MaxUndeliveredMessages < 1 {
+		return errors.New("'max_undelivered_messages' must be at least one")
+	}
+
+	switch p.SubscriptionType {
+	case "exclusive":
+		p.subType = pulsar.Exclusive
+	case "", "shared":
+		p.subType = pulsar.Shared
+	case "failover":
+		p.subType = pulsar.Failover
+	case "key_shared":
+		p.subType = pulsar.KeyShared
+	default:
+		return fmt.Errorf("invalid subscription type %q", p.SubscriptionType)
+	}
+
+	switch p.SubscriptionInitialPosition {
+	case "", "latest":
+		p.initialPosition = pulsar.SubscriptionPositionLatest
+	case "earliest":
+		p.initialPosition = pulsar.SubscriptionPositionEarliest
+	default:
+		return fmt.Errorf("invalid subscription initial position %q", p.SubscriptionInitialPosition)
+	}
+
+	return nil
+}
+
+func (p *PulsarConsumer) Start(acc telegraf.Accumulator) error {
+	client, err := p.NewClient(p.Log)
+	if err != nil {
+		return fmt.Errorf("creating client failed: %w", err)
+	}
+
+	consumer, err := client.Subscribe(pulsar.ConsumerOptions{
+		Topics:                      p.Topics,
+		TopicsPattern:               p.TopicsPattern,
+		AutoDiscoveryPeriod:         time.Duration(p.TopicsRefreshInterval),
+		SubscriptionName:            p.SubscriptionName,
+		Type:                        p.subType,
+		SubscriptionInitialPosition: p.initialPosition,
+		ReceiverQueueSize:           p.ReceiverQueueSize,
+		NackRedeliveryDelay:         time.Duration(p.NackRedeliveryDelay),
+	})
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("subscribing failed: %w", err)
+	}
+	p.client = client
+	p.consumer = consumer
+
+	p.start(acc, consumer.Chan())
+
+	return nil
+}
+
+// start launches the receiver processing the messages of the channel
+func (p *PulsarConsumer) start(acc telegraf.Accumulator, messages <-chan pulsar.ConsumerMessage) {
+	p.acc = acc.WithTracking(p.MaxUndeliveredMessages)
+	p.undelivered = make(map[telegraf.TrackingID]pulsar.ConsumerMessage, p.MaxUndeliveredMessages)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.receive(ctx, messages)
+	}()
+}
+
+// receive parses the incoming messages into metrics and acknowledges the
+// messages once the metrics are delivered. At most max_undelivered_messages
+// messages are in flight at any time.
+func (p *PulsarConsumer) receive(ctx context.Context, messages <-chan pulsar.ConsumerMessage) {
+	sem := make(chan struct{}, p.MaxUndeliveredMessages)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case info := <-p.acc.Delivered():
+			p.onDelivery(info)
+			<-sem
+		case sem <- struct{}{}:
+			select {
+			case <-ctx.Done():
+				return
+			case info := <-p.acc.Delivered():
+				p.onDelivery(info)
+				<-sem
+				<-sem
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				if !p.onMessage(msg) {
+					<-sem
+				}
+			}
+		}
+	}
+}
+
+// onMessage adds the metrics of the message to the accumulator and returns
+// whether the message is tracked
+func (p *PulsarConsumer) onMessage(msg pulsar.ConsumerMessage) bool {
+	metrics, err := p.parser.Parse(msg.Payload())
+	if err != nil {
+		// The message will never be parsable, so acknowledge it to avoid
+		// receiving it again
+		p.Log.Errorf("Parsing message of topic %q failed: %v", msg.Topic(), err)
+		p.ack(msg)
+		return false
+	}
+	if len(metrics) == 0 {
+		once.Do(func() {
+			p.Log.Debug(internal.NoMetricsCreatedMsg)
+		})
+		p.ack(msg)
+		return false
+	}
+
+	if p.TopicTag != "" {
+		for _, m := range metrics {
+			m.AddTag(p.TopicTag, msg.Topic())
+		}
+	}
+
+	id := p.acc.AddTrackingMetricGroup(metrics)
+	p.undelivered[id] = msg
+	return true
+}
+
+// onDelivery acknowledges the message of delivered metrics and requests
+// redelivery of the message otherwise
+func (p *PulsarConsumer) onDelivery(info telegraf.DeliveryInfo) {
+	msg, found := p.undelivered[info.ID()]
+	if !found {
+		return
+	}
+	delete(p.undelivered, info.ID())
+
+	if !info.Delivered() {
+		msg.Nack(msg.Message)
+		return
+	}
+	p.ack(msg)
+}
+
+func (p *PulsarConsumer) ack(msg pulsar.ConsumerMessage) {
+	if err := msg.Ack(msg.Message); err != nil {
+		p.Log.Errorf("Acknowledging message of topic %q failed: %v", msg.Topic(), err)
+	}
+}
+
+func (*PulsarConsumer) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+func (p *PulsarConsumer) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+
+	// Messages not yet acknowledged are redelivered by the broker
+	if p.consumer != nil {
+		p.consumer.Close()
+	}
+	if p.client != nil {
+		p.client.Close()
+	}
+}
+
+func init() {
+	inputs.Add("pulsar_consumer", func() telegraf.Input {
+		return &PulsarConsumer{
+			SubscriptionName:            "telegraf",
+			SubscriptionType:            "shared",
+			SubscriptionInitialPosition: "latest",
+			TopicsRefreshInterval:       config.Duration(time.Minute),
+			NackRedeliveryDelay:         config.Duration(time.Minute),
+			MaxUndeliveredMessages:      1000,
+			TopicTag:                    "topic",
+			ClientConfig: common_pulsar.ClientConfig{
+				URL:               "pulsar://localhost:6650",
+				ConnectionTimeout: config.Duration(5 * time.Second),
+				OperationTimeout:  config.Duration(30 * time.Second),
+			},
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package pulsar_consumer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type fakeMessage struct {
+	pulsar.Message
+	topic   string
+	payload []byte
+}
+
+func (m *fakeMessage) Topic() string {
+	return m.topic
+}
+
+func (m *fakeMessage) Payload() []byte {
+	return m.payload
+}
+
+type fakeConsumer struct {
+	pulsar.Consumer
+
+	sync.Mutex
+	acked  []string
+	nacked []string
+}
+
+func (c *fakeConsumer) Ack(msg pulsar.Message) error {
+	c.Lock()
+	defer c.Unlock()
+	c.acked = append(c.acked, string(msg.Payload()))
+	return nil
+}
+
+func (c *fakeConsumer) Nack(msg pulsar.Message) {
+	c.Lock()
+	defer c.Unlock()
+	c.nacked = append(c.nacked, string(msg.Payload()))
+}
+
+func (c *fakeConsumer) results() (acked, nacked []string) {
+	c.Lock()
+	defer c.Unlock()
+	return append([]string(nil), c.acked...), append([]string(nil), c.nacked...)
+}
+
+func newPlugin(t *testing.T) *PulsarConsumer {
+	plugin := inputs.Inputs["pulsar_consumer"]().(*PulsarConsumer)
+	plugin.Topics = []string{"telegraf"}
+	plugin.Log = testutil.Logger{}
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+
+	return plugin
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(p *PulsarConsumer)
+		expected string
+	}{
+		{
+			name:     "no topics",
+			modify:   func(p *PulsarConsumer) { p.Topics = nil },
+			expected: "either 'topics' or 'topics_pattern' required",
+		},
+		{
+			name:     "topics and pattern",
+			modify:   func(p *PulsarConsumer) { p.TopicsPattern = "telegraf-.*" },
+			expected: "mutually exclusive",
+		},
+		{
+			name:     "invalid subscription type",
+			modify:   func(p *PulsarConsumer) { p.SubscriptionType = "round_robin" },
+			expected: `invalid subscription type "round_robin"`,
+		},
+		{
+			name:     "invalid initial position",
+			modify:   func(p *PulsarConsumer) { p.SubscriptionInitialPosition = "first" },
+			expected: `invalid subscription initial position "first"`,
+		},
+		{
+			name:     "no undelivered messages",
+			modify:   func(p *PulsarConsumer) { p.MaxUndeliveredMessages = 0 },
+			expected: "'max_undelivered_messages' must be at least one",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newPlugin(t)
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	plugin := newPlugin(t)
+	plugin.Topics = nil
+	plugin.TopicsPattern = "persistent://public/default/telegraf-.*"
+	plugin.SubscriptionType = "key_shared"
+	plugin.SubscriptionInitialPosition = "earliest"
+	require.NoError(t, plugin.Init())
+	require.Equal(t, pulsar.KeyShared, plugin.subType)
+	require.Equal(t, pulsar.SubscriptionPositionEarliest, plugin.initialPosition)
+}
+
+func TestReceiveAcknowledgesOnDelivery(t *testing.T) {
+	plugin := newPlugin(t)
+	require.NoError(t, plugin.Init())
+
+	consumer := &fakeConsumer{}
+	messages := make(chan pulsar.ConsumerMessage)
+
+	var acc testutil.Accumulator
+	plugin.start(&acc, messages)
+	defer plugin.Stop()
+
+	for _, payload := range []string{"cpu value=1 0", "cpu value=2 0", "not a metric"} {
+		messages <- pulsar.ConsumerMessage{
+			Consumer: consumer,
+			Message:  &fakeMessage{topic: "persistent://public/default/telegraf", payload: []byte(payload)},
+		}
+	}
+	acc.Wait(2)
+
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"topic": "persistent://public/default/telegraf"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"topic": "persistent://public/default/telegraf"}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+	}
+	actual := acc.GetTelegrafMetrics()
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	// The unparsable message is acknowledged right away, the others only
+	// once their metrics are handled by an output
+	require.Eventually(t, func() bool {
+		acked, _ := consumer.results()
+		return len(acked) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	actual[0].Accept()
+	actual[1].Reject()
+
+	require.Eventually(t, func() bool {
+		acked, nacked := consumer.results()
+		return len(acked) == 2 && len(nacked) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	acked, nacked := consumer.results()
+	require.Equal(t, []string{"not a metric", "cpu value=1 0"}, acked)
+	require.Equal(t, []string{"cpu value=2 0"}, nacked)
+}
+
+func TestReceiveLimitsUndeliveredMessages(t *testing.T) {
+	plugin := newPlugin(t)
+	plugin.MaxUndeliveredMessages = 1
+	require.NoError(t, plugin.Init())
+
+	consumer := &fakeConsumer{}
+	messages := make(chan pulsar.ConsumerMessage)
+
+	var acc testutil.Accumulator
+	plugin.start(&acc, messages)
+	defer plugin.Stop()
+
+	send := func(payload string) {
+		messages <- pulsar.ConsumerMessage{
+			Consumer: consumer,
+			Message:  &fakeMessage{topic: "telegraf", payload: []byte(payload)},
+		}
+	}
+	send("cpu value=1 0")
+	acc.Wait(1)
+
+	// The second message must not be received before the first is delivered
+	select {
+	case messages <- pulsar.ConsumerMessage{
+		Consumer: consumer,
+		Message:  &fakeMessage{topic: "telegraf", payload: []byte("cpu value=2 0")},
+	}:
+		require.Fail(t, "message received despite undelivered messages")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	acc.GetTelegrafMetrics()[0].Accept()
+	send("cpu value=2 0")
+	acc.Wait(2)
+}
+
+func TestReceiveWithoutTopicTag(t *testing.T) {
+	plugin := newPlugin(t)
+	plugin.TopicTag = ""
+	require.NoError(t, plugin.Init())
+
+	messages := make(chan pulsar.ConsumerMessage)
+
+	var acc testutil.Accumulator
+	plugin.start(&acc, messages)
+	defer plugin.Stop()
+
+	messages <- pulsar.ConsumerMessage{
+		Consumer: &fakeConsumer{},
+		Message:  &fakeMessage{topic: "telegraf", payload: []byte("cpu value=1 0")},
+	}
+	acc.Wait(1)
+
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
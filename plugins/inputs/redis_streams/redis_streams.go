@@ -0,0 +1,409 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package redis_streams
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common_redis "github.com/influxdata/telegraf/plugins/common/redis"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+var once sync.Once
+
+type RedisStreams struct {
+	Streams                []string        `toml:"streams"`
+	ConsumerGroup          string          `toml:"consumer_group"`
+	ConsumerName           string          `toml:"consumer_name"`
+	GroupStartID           string          `toml:"group_start_id"`
+	PayloadField           string          `toml:"payload_field"`
+	BatchSize              int64           `toml:"batch_size"`
+	BlockTimeout           config.Duration `toml:"block_timeout"`
+	ClaimMinIdle           config.Duration `toml:"claim_min_idle"`
+	MaxUndeliveredMessages int             `toml:"max_undelivered_messages"`
+	StreamTag              string          `toml:"stream_tag"`
+	Log                    telegraf.Logger `toml:"-"`
+	common_redis.ClientConfig
+
+	parser telegraf.Parser
+
+	client      *redis.Client
+	acc         telegraf.TrackingAccumulator
+	entries     chan entry
+	undelivered map[telegraf.TrackingID]entryID
+	inflight    map[entryID]bool
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// entry is a stream entry received by the consumer
+type entry struct {
+	stream string
+	id     string
+	values map[string]interface{}
+}
+
+// entryID identifies an entry across the streams
+type entryID struct {
+	stream string
+	id     string
+}
+
+func (*RedisStreams) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RedisStreams) SetParser(parser telegraf.Parser) {
+	r.parser = parser
+}
+
+func (r *RedisStreams) Init() error {
+	if len(r.Streams) == 0 {
+		return errors.New("'streams' required")
+	}
+	if r.ConsumerGroup == "" {
+		return errors.New("'consumer_group' required")
+	}
+	if r.PayloadField == "" {
+		return errors.New("'payload_field' required")
+	}
+	if r.BatchSize < 1 {
+		return errors.New("'batch_size' must be at least one")
+	}
+	if r.MaxUndeliveredMessages < 1 {
+		return errors.New("'max_undelivered_messages' must be at least one")
+	}
+
+	// The consumer name must be stable across restarts to resume the
+	// entries received but not acknowledged before
+	if r.ConsumerName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("getting hostname for 'consumer_name' failed: %w", err)
+		}
+		r.ConsumerName = hostname
+	}
+
+	return nil
+}
+
+func (r *RedisStreams) Start(acc telegraf.Accumulator) error {
+	client, err := r.NewClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.Timeout))
+	defer cancel()
+	for _, stream := range r.Streams {
+		err := client.XGroupCreateMkStream(ctx, stream, r.ConsumerGroup, r.GroupStartID).Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			client.Close()
+			return fmt.Errorf("creating consumer group for stream %q failed: %w", stream, err)
+		}
+	}
+	r.client = client
+
+	r.acc = acc.WithTracking(r.MaxUndeliveredMessages)
+	r.entries = make(chan entry)
+	r.undelivered = make(map[telegraf.TrackingID]entryID, r.MaxUndeliveredMessages)
+	r.inflight = make(map[entryID]bool, r.MaxUndeliveredMessages)
+
+	ctx, r.cancel = context.WithCancel(context.Background())
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		r.read(ctx)
+	}()
+	go func() {
+		defer r.wg.Done()
+		r.process(ctx)
+	}()
+
+	return nil
+}
+
+// read receives the stream entries of the consumer group. Entries received
+// but not acknowledged before, e.g. due to a restart, are read first. If
+// enabled, entries pending for longer than the minimum idle time are claimed
+// to redeliver entries of failed consumers and entries rejected by outputs.
+func (r *RedisStreams) read(ctx context.Context) {
+	for _, stream := range r.Streams {
+		if !r.readPending(ctx, stream) {
+			return
+		}
+	}
+
+	args := &redis.XReadGroupArgs{
+		Group:    r.ConsumerGroup,
+		Consumer: r.ConsumerName,
+		Streams:  make([]string, 0, 2*len(r.Streams)),
+		Count:    r.BatchSize,
+		Block:    time.Duration(r.BlockTimeout),
+	}
+	args.Streams = append(args.Streams, r.Streams...)
+	for range r.Streams {
+		args.Streams = append(args.Streams, ">")
+	}
+
+	lastClaim := time.Now()
+	for ctx.Err() == nil {
+		if r.ClaimMinIdle > 0 && time.Since(lastClaim) > time.Duration(r.ClaimMinIdle) {
+			r.claim(ctx)
+			lastClaim = time.Now()
+		}
+
+		streams, err := r.client.XReadGroup(ctx, args).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			r.Log.Errorf("Reading streams failed: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				if !r.forward(ctx, entry{stream: s.Stream, id: msg.ID, values: msg.Values}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// readPending reads the entries of the stream delivered to the consumer but
+// not acknowledged and returns false if reading was canceled
+func (r *RedisStreams) readPending(ctx context.Context, stream string) bool {
+	start := "0"
+	for {
+		streams, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    r.ConsumerGroup,
+			Consumer: r.ConsumerName,
+			Streams:  []string{stream, start},
+			Count:    r.BatchSize,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			if ctx.Err() != nil {
+				return false
+			}
+			r.Log.Errorf("Reading pending entries of stream %q failed: %v", stream, err)
+			return true
+		}
+
+		var n int
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				n++
+				if !r.forward(ctx, entry{stream: s.Stream, id: msg.ID, values: msg.Values}) {
+					return false
+				}
+				start = msg.ID
+			}
+		}
+		if n == 0 {
+			return true
+		}
+	}
+}
+
+// claim takes over the entries of the consumer group pending for longer than
+// the minimum idle time
+func (r *RedisStreams) claim(ctx context.Context) {
+	for _, stream := range r.Streams {
+		start := "0-0"
+		for {
+			msgs, next, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   stream,
+				Group:    r.ConsumerGroup,
+				Consumer: r.ConsumerName,
+				MinIdle:  time.Duration(r.ClaimMinIdle),
+				Start:    start,
+				Count:    r.BatchSize,
+			}).Result()
+			if err != nil {
+				if ctx.Err() == nil {
+					r.Log.Errorf("Claiming pending entries of stream %q failed: %v", stream, err)
+				}
+				break
+			}
+			for _, msg := range msgs {
+				if !r.forward(ctx, entry{stream: stream, id: msg.ID, values: msg.Values}) {
+					return
+				}
+			}
+			if next == "0-0" || next == "" {
+				break
+			}
+			start = next
+		}
+	}
+}
+
+func (r *RedisStreams) forward(ctx context.Context, e entry) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case r.entries <- e:
+		return true
+	}
+}
+
+// process parses the received entries into metrics and acknowledges the
+// entries once the metrics are delivered. At most max_undelivered_messages
+// entries are in flight at any time.
+func (r *RedisStreams) process(ctx context.Context) {
+	sem := make(chan struct{}, r.MaxUndeliveredMessages)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case info := <-r.acc.Delivered():
+			r.onDelivery(ctx, info)
+			<-sem
+		case sem <- struct{}{}:
+			select {
+			case <-ctx.Done():
+				return
+			case info := <-r.acc.Delivered():
+				r.onDelivery(ctx, info)
+				<-sem
+				<-sem
+			case e := <-r.entries:
+				if !r.onEntry(ctx, e) {
+					<-sem
+				}
+			}
+		}
+	}
+}
+
+// onEntry adds the metrics of the entry to the accumulator and returns
+// whether the entry is tracked
+func (r *RedisStreams) onEntry(ctx context.Context, e entry) bool {
+	// The entry is already tracked if it was claimed while still waiting
+	// for delivery
+	eid := entryID{stream: e.stream, id: e.id}
+	if r.inflight[eid] {
+		return false
+	}
+
+	payload, found := e.values[r.PayloadField]
+	if !found {
+		r.Log.Errorf("Entry %s of stream %q has no field %q", e.id, e.stream, r.PayloadField)
+		r.ack(ctx, eid)
+		return false
+	}
+	data, ok := payload.(string)
+	if !ok {
+		r.Log.Errorf("Unexpected payload type %T of entry %s of stream %q", payload, e.id, e.stream)
+		r.ack(ctx, eid)
+		return false
+	}
+
+	metrics, err := r.parser.Parse([]byte(data))
+	if err != nil {
+		// The entry will never be parsable, so acknowledge it to avoid
+		// receiving it again
+		r.Log.Errorf("Parsing entry %s of stream %q failed: %v", e.id, e.stream, err)
+		r.ack(ctx, eid)
+		return false
+	}
+	if len(metrics) == 0 {
+		once.Do(func() {
+			r.Log.Debug(internal.NoMetricsCreatedMsg)
+		})
+		r.ack(ctx, eid)
+		return false
+	}
+
+	if r.StreamTag != "" {
+		for _, m := range metrics {
+			m.AddTag(r.StreamTag, e.stream)
+		}
+	}
+
+	id := r.acc.AddTrackingMetricGroup(metrics)
+	r.undelivered[id] = eid
+	r.inflight[eid] = true
+	return true
+}
+
+// onDelivery acknowledges the entry of delivered metrics. Entries of
+// rejected metrics stay pending and are claimed again after the minimum
+// idle time.
+func (r *RedisStreams) onDelivery(ctx context.Context, info telegraf.DeliveryInfo) {
+	e, found := r.undelivered[info.ID()]
+	if !found {
+		return
+	}
+	delete(r.undelivered, info.ID())
+	delete(r.inflight, e)
+
+	if !info.Delivered() {
+		r.Log.Debugf("Metrics of entry %s of stream %q were not delivered", e.id, e.stream)
+		return
+	}
+	r.ack(ctx, e)
+}
+
+func (r *RedisStreams) ack(ctx context.Context, e entryID) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.Timeout))
+	defer cancel()
+	if err := r.client.XAck(ctx, e.stream, r.ConsumerGroup, e.id).Err(); err != nil {
+		r.Log.Errorf("Acknowledging entry %s of stream %q failed: %v", e.id, e.stream, err)
+	}
+}
+
+func (*RedisStreams) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+func (r *RedisStreams) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+
+	if r.client != nil {
+		r.client.Close()
+	}
+}
+
+func init() {
+	inputs.Add("redis_streams", func() telegraf.Input {
+		return &RedisStreams{
+			ConsumerGroup:          "telegraf",
+			GroupStartID:           "$",
+			PayloadField:           "data",
+			BatchSize:              100,
+			BlockTimeout:           config.Duration(time.Second),
+			ClaimMinIdle:           config.Duration(5 * time.Minute),
+			MaxUndeliveredMessages: 1000,
+			StreamTag:              "stream",
+			ClientConfig: common_redis.ClientConfig{
+				Address: "127.0.0.1:6379",
+				Timeout: config.Duration(10 * time.Second),
+			},
+		}
+	})
+}
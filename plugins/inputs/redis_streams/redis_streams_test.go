@@ -0,0 +1,179 @@
+package redis_streams
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newPlugin(t *testing.T, address string) *RedisStreams {
+	plugin := inputs.Inputs["redis_streams"]().(*RedisStreams)
+	plugin.Address = address
+	plugin.Streams = []string{"telegraf"}
+	plugin.ConsumerName = "test"
+	plugin.BlockTimeout = config.Duration(50 * time.Millisecond)
+	plugin.Log = testutil.Logger{}
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+
+	return plugin
+}
+
+func pending(t *testing.T, client *redis.Client) int64 {
+	p, err := client.XPending(context.Background(), "telegraf", "telegraf").Result()
+	require.NoError(t, err)
+	return p.Count
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(r *RedisStreams)
+		expected string
+	}{
+		{
+			name:     "no streams",
+			modify:   func(r *RedisStreams) { r.Streams = nil },
+			expected: "'streams' required",
+		},
+		{
+			name:     "no consumer group",
+			modify:   func(r *RedisStreams) { r.ConsumerGroup = "" },
+			expected: "'consumer_group' required",
+		},
+		{
+			name:     "no payload field",
+			modify:   func(r *RedisStreams) { r.PayloadField = "" },
+			expected: "'payload_field' required",
+		},
+		{
+			name:     "no undelivered messages",
+			modify:   func(r *RedisStreams) { r.MaxUndeliveredMessages = 0 },
+			expected: "'max_undelivered_messages' must be at least one",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newPlugin(t, "127.0.0.1:6379")
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestConsumeAndAcknowledge(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	plugin := newPlugin(t, server.Addr())
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	for _, data := range []string{"cpu value=1 0", "not a metric", "cpu value=2 0"} {
+		_, err := server.XAdd("telegraf", "*", []string{"data", data})
+		require.NoError(t, err)
+	}
+	_, err := server.XAdd("telegraf", "*", []string{"other", "cpu value=3 0"})
+	require.NoError(t, err)
+	acc.Wait(2)
+
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"stream": "telegraf"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"stream": "telegraf"}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+	}
+	actual := acc.GetTelegrafMetrics()
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	// Invalid entries are acknowledged right away, the others only once
+	// their metrics are written
+	require.Eventually(t, func() bool {
+		return pending(t, client) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	for _, m := range actual {
+		m.Accept()
+	}
+	require.Eventually(t, func() bool {
+		return pending(t, client) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRejectedEntriesStayPending(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	plugin := newPlugin(t, server.Addr())
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+
+	_, err := server.XAdd("telegraf", "*", []string{"data", "cpu value=1 0"})
+	require.NoError(t, err)
+	acc.Wait(1)
+	acc.GetTelegrafMetrics()[0].Reject()
+	plugin.Stop()
+	require.Equal(t, int64(1), pending(t, client))
+
+	// The pending entry is delivered again after a restart
+	acc.ClearMetrics()
+	plugin = newPlugin(t, server.Addr())
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	acc.Wait(1)
+	acc.GetTelegrafMetrics()[0].Accept()
+	require.Eventually(t, func() bool {
+		return pending(t, client) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestClaimIdleEntries(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	// Simulate an entry received by a failed consumer
+	require.NoError(t, client.XGroupCreateMkStream(context.Background(), "telegraf", "telegraf", "$").Err())
+	_, err := server.XAdd("telegraf", "*", []string{"data", "cpu value=1 0"})
+	require.NoError(t, err)
+	require.NoError(t, client.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group:    "telegraf",
+		Consumer: "failed",
+		Streams:  []string{"telegraf", ">"},
+	}).Err())
+
+	plugin := newPlugin(t, server.Addr())
+	plugin.ClaimMinIdle = config.Duration(10 * time.Millisecond)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	acc.Wait(1)
+	acc.GetTelegrafMetrics()[0].Accept()
+	require.Eventually(t, func() bool {
+		return pending(t, client) == 0
+	}, time.Second, 10*time.Millisecond)
+}
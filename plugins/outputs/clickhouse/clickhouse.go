@@ -0,0 +1,269 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package clickhouse
+
+import (
+	gosql "database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	_ "github.com/ClickHouse/clickhouse-go" // register driver
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type ClickHouse struct {
+	DataSourceName        config.Secret   `toml:"data_source_name"`
+	Database              string          `toml:"database"`
+	TableNamePrefix       string          `toml:"table_name_prefix"`
+	TimestampColumn       string          `toml:"timestamp_column"`
+	LowCardinalityTags    bool            `toml:"low_cardinality_tags"`
+	TableEngine           string          `toml:"table_engine"`
+	OrderBy               string          `toml:"order_by"`
+	PartitionBy           string          `toml:"partition_by"`
+	TTL                   string          `toml:"ttl"`
+	CreateTemplate        string          `toml:"create_template"`
+	AddColumnTemplate     string          `toml:"add_column_template"`
+	AsyncInsert           bool            `toml:"async_insert"`
+	WaitForAsyncInsert    bool            `toml:"wait_for_async_insert"`
+	ConnectionMaxIdleTime config.Duration `toml:"connection_max_idle_time"`
+	ConnectionMaxLifetime config.Duration `toml:"connection_max_lifetime"`
+	ConnectionMaxIdle     int             `toml:"connection_max_idle"`
+	ConnectionMaxOpen     int             `toml:"connection_max_open"`
+	Log                   telegraf.Logger `toml:"-"`
+
+	db            *gosql.DB
+	tables        map[string]*table
+	createTmpl    *template.Template
+	addColumnTmpl *template.Template
+}
+
+func (*ClickHouse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *ClickHouse) Init() error {
+	if c.DataSourceName.Empty() {
+		return errors.New("'data_source_name' required")
+	}
+	if c.TimestampColumn == "" {
+		return errors.New("'timestamp_column' required")
+	}
+	if c.TableEngine == "" {
+		return errors.New("'table_engine' required")
+	}
+
+	funcs := template.FuncMap{"join": strings.Join}
+	var err error
+	if c.createTmpl, err = template.New("create").Funcs(funcs).Parse(c.CreateTemplate); err != nil {
+		return fmt.Errorf("parsing 'create_template' failed: %w", err)
+	}
+	if c.AddColumnTemplate != "" {
+		if c.addColumnTmpl, err = template.New("add_column").Funcs(funcs).Parse(c.AddColumnTemplate); err != nil {
+			return fmt.Errorf("parsing 'add_column_template' failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *ClickHouse) Connect() error {
+	dsn, err := c.DataSourceName.Get()
+	if err != nil {
+		return fmt.Errorf("getting data source name failed: %w", err)
+	}
+	defer dsn.Destroy()
+
+	db, err := gosql.Open("clickhouse", dsn.String())
+	if err != nil {
+		return err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return err
+	}
+
+	db.SetConnMaxIdleTime(time.Duration(c.ConnectionMaxIdleTime))
+	db.SetConnMaxLifetime(time.Duration(c.ConnectionMaxLifetime))
+	db.SetMaxIdleConns(c.ConnectionMaxIdle)
+	db.SetMaxOpenConns(c.ConnectionMaxOpen)
+
+	c.db = db
+	c.tables = make(map[string]*table)
+
+	return nil
+}
+
+func (c *ClickHouse) Close() error {
+	if c.db == nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+func (c *ClickHouse) Write(metrics []telegraf.Metric) error {
+	// Group the metrics by table to insert each table's metrics as a single
+	// columnar block
+	batches := make(map[string][]telegraf.Metric)
+	names := make([]string, 0)
+	for _, m := range metrics {
+		name := c.TableNamePrefix + m.Name()
+		if _, found := batches[name]; !found {
+			names = append(names, name)
+		}
+		batches[name] = append(batches[name], m)
+	}
+
+	for _, name := range names {
+		if err := c.writeBatch(name, batches[name]); err != nil {
+			// Forget the table schema as the table might have been changed
+			// externally
+			delete(c.tables, name)
+			return fmt.Errorf("writing to table %q failed: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *ClickHouse) writeBatch(name string, metrics []telegraf.Metric) error {
+	tbl, err := c.ensureTable(name, metrics)
+	if err != nil {
+		return err
+	}
+
+	// Metrics with tags lacking a column cannot be stored without losing
+	// the series identity, so skip those
+	if c.addColumnTmpl == nil {
+		filtered := make([]telegraf.Metric, 0, len(metrics))
+		for _, m := range metrics {
+			if tbl.missingTags(m) {
+				c.Log.Warnf("Skipping metric of table %q with tags lacking a column", name)
+				continue
+			}
+			filtered = append(filtered, m)
+		}
+		if len(filtered) == 0 {
+			return nil
+		}
+		metrics = filtered
+	}
+
+	// Use the union of the metrics' tags and fields existing in the table
+	tags, fields := tbl.columnsOf(metrics)
+	columns := make([]string, 0, 1+len(tags)+len(fields))
+	columns = append(columns, c.TimestampColumn)
+	columns = append(columns, tags...)
+	columns = append(columns, fields...)
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin failed: %w", err)
+	}
+	stmt, err := tx.Prepare(c.insertQuery(name, columns))
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("prepare failed: %w", err)
+	}
+	defer stmt.Close()
+
+	values := make([]interface{}, len(columns))
+	for _, m := range metrics {
+		values[0] = m.Time()
+		for i, key := range tags {
+			v, _ := m.GetTag(key)
+			values[1+i] = v
+		}
+		for i, key := range fields {
+			values[1+len(tags)+i] = nil
+			v, found := m.GetField(key)
+			if !found {
+				continue
+			}
+			converted, ok := convert(v, tbl.columns[key])
+			if !ok {
+				c.Log.Debugf("Cannot store value %v of field %q in column of type %q", v, key, tbl.columns[key])
+				continue
+			}
+			values[1+len(tags)+i] = converted
+		}
+
+		if _, err := stmt.Exec(values...); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("execution failed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+func (c *ClickHouse) insertQuery(name string, columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, quoteIdent(column))
+	}
+
+	var settings string
+	if c.AsyncInsert {
+		wait := 0
+		if c.WaitForAsyncInsert {
+			wait = 1
+		}
+		settings = fmt.Sprintf(" SETTINGS async_insert=1, wait_for_async_insert=%d", wait)
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s)%s VALUES (%s)",
+		c.tableIdent(name),
+		strings.Join(quoted, ", "),
+		settings,
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "),
+	)
+}
+
+func (c *ClickHouse) tableIdent(name string) string {
+	if c.Database == "" {
+		return quoteIdent(name)
+	}
+	return quoteIdent(c.Database) + "." + quoteIdent(name)
+}
+
+// quoteIdent quotes an identifier (database, table or column name)
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(strings.ReplaceAll(name, `\`, `\\`), "`", "\\`") + "`"
+}
+
+// sortedKeys returns the keys of the set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	outputs.Add("clickhouse", func() telegraf.Output {
+		return &ClickHouse{
+			TimestampColumn:    "timestamp",
+			LowCardinalityTags: true,
+			TableEngine:        "MergeTree()",
+			CreateTemplate:     defaultCreateTemplate,
+			AddColumnTemplate:  defaultAddColumnTemplate,
+			WaitForAsyncInsert: true,
+			ConnectionMaxIdle:  2,
+		}
+	})
+}
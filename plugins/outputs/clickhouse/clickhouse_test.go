@@ -0,0 +1,248 @@
+package clickhouse
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newClickHouse() *ClickHouse {
+	plugin := outputs.Outputs["clickhouse"]().(*ClickHouse)
+	plugin.DataSourceName = config.NewSecret([]byte("tcp://localhost:9000"))
+	plugin.Log = testutil.Logger{}
+	return plugin
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *ClickHouse)
+		expected string
+	}{
+		{
+			name:     "missing dsn",
+			modify:   func(c *ClickHouse) { c.DataSourceName = config.NewSecret(nil) },
+			expected: "'data_source_name' required",
+		},
+		{
+			name:     "missing engine",
+			modify:   func(c *ClickHouse) { c.TableEngine = "" },
+			expected: "'table_engine' required",
+		},
+		{
+			name:     "invalid template",
+			modify:   func(c *ClickHouse) { c.CreateTemplate = "CREATE TABLE {{ .table " },
+			expected: "parsing 'create_template' failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newClickHouse()
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestInsertQuery(t *testing.T) {
+	plugin := newClickHouse()
+	plugin.Database = "telegraf"
+	require.NoError(t, plugin.Init())
+
+	require.Equal(t,
+		"INSERT INTO `telegraf`.`cpu` (`timestamp`, `host`, `usage`) VALUES (?, ?, ?)",
+		plugin.insertQuery("cpu", []string{"timestamp", "host", "usage"}),
+	)
+
+	plugin.AsyncInsert = true
+	plugin.WaitForAsyncInsert = false
+	require.Equal(t,
+		"INSERT INTO `telegraf`.`cpu` (`timestamp`, `usage`) SETTINGS async_insert=1, wait_for_async_insert=0 VALUES (?, ?)",
+		plugin.insertQuery("cpu", []string{"timestamp", "usage"}),
+	)
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		typ      string
+		expected interface{}
+		ok       bool
+	}{
+		{value: int64(42), typ: "Nullable(Float64)", expected: float64(42), ok: true},
+		{value: uint64(42), typ: "Int64", expected: int64(42), ok: true},
+		{value: int64(-1), typ: "Nullable(UInt64)", ok: false},
+		{value: true, typ: "Nullable(UInt8)", expected: true, ok: true},
+		{value: 1.5, typ: "LowCardinality(Nullable(String))", expected: "1.5", ok: true},
+		{value: "foo", typ: "Nullable(Int64)", ok: false},
+		{value: int64(1), typ: "Array(Int64)", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v to %s", tt.value, tt.typ), func(t *testing.T) {
+			actual, ok := convert(tt.value, tt.typ)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestWriteCreatesTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	plugin := newClickHouse()
+	plugin.TTL = "toDateTime(`timestamp`) + INTERVAL 30 DAY"
+	require.NoError(t, plugin.Init())
+	plugin.db = db
+	plugin.tables = make(map[string]*table)
+
+	columns := []string{"name", "type"}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT name, type FROM system.columns")).
+		WithArgs("cpu").
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectExec(regexp.QuoteMeta(
+		"CREATE TABLE IF NOT EXISTS `cpu` (`timestamp` DateTime64(9), `host` LowCardinality(String), " +
+			"`idle` Nullable(Float64), `user` Nullable(Int64)) ENGINE = MergeTree() " +
+			"ORDER BY (`host`, `timestamp`) TTL toDateTime(`timestamp`) + INTERVAL 30 DAY",
+	)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT name, type FROM system.columns")).
+		WithArgs("cpu").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("timestamp", "DateTime64(9)").
+			AddRow("host", "LowCardinality(String)").
+			AddRow("idle", "Nullable(Float64)").
+			AddRow("user", "Nullable(Int64)"),
+		)
+	mock.ExpectBegin()
+	prepare := mock.ExpectPrepare(regexp.QuoteMeta(
+		"INSERT INTO `cpu` (`timestamp`, `host`, `idle`, `user`) VALUES (?, ?, ?, ?)",
+	))
+	now := time.Unix(1700000000, 0)
+	prepare.ExpectExec().WithArgs(now, "a", 98.5, int64(10)).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepare.ExpectExec().WithArgs(now, "b", nil, int64(20)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"idle": 98.5, "user": int64(10)}, now),
+		metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"user": int64(20)}, now),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWriteAddsColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	plugin := newClickHouse()
+	require.NoError(t, plugin.Init())
+	plugin.db = db
+	plugin.tables = map[string]*table{
+		"cpu": {columns: map[string]string{"timestamp": "DateTime64(9)", "host": "String", "idle": "Nullable(Float64)"}},
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(
+		"ALTER TABLE `cpu` ADD COLUMN IF NOT EXISTS `cpu` LowCardinality(String), ADD COLUMN IF NOT EXISTS `user` Nullable(Int64)",
+	)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	prepare := mock.ExpectPrepare(regexp.QuoteMeta(
+		"INSERT INTO `cpu` (`timestamp`, `cpu`, `host`, `idle`, `user`) VALUES (?, ?, ?, ?, ?)",
+	))
+	now := time.Unix(1700000000, 0)
+	prepare.ExpectExec().WithArgs(now, "cpu0", "a", float64(98), int64(10)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"idle": int64(98), "user": int64(10)},
+			now,
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWriteWithoutAddingColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	plugin := newClickHouse()
+	plugin.AddColumnTemplate = ""
+	require.NoError(t, plugin.Init())
+	plugin.db = db
+	plugin.tables = map[string]*table{
+		"cpu": {columns: map[string]string{"timestamp": "DateTime64(9)", "host": "String", "idle": "Nullable(Float64)"}},
+	}
+
+	// The metric with the unknown tag is skipped, the unknown field omitted
+	mock.ExpectBegin()
+	prepare := mock.ExpectPrepare(regexp.QuoteMeta(
+		"INSERT INTO `cpu` (`timestamp`, `host`, `idle`) VALUES (?, ?, ?)",
+	))
+	now := time.Unix(1700000000, 0)
+	prepare.ExpectExec().WithArgs(now, "a", 98.0).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"idle": 98.0, "user": int64(10)}, now),
+		metric.New("cpu", map[string]string{"host": "a", "cpu": "cpu0"}, map[string]interface{}{"idle": 97.0}, now),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	servicePort := "9000"
+	container := testutil.Container{
+		Image:        "clickhouse/clickhouse-server",
+		ExposedPorts: []string{servicePort, "8123"},
+		WaitingFor: wait.ForAll(
+			wait.NewHTTPStrategy("/").WithPort(nat.Port("8123")),
+			wait.ForListeningPort(nat.Port(servicePort)),
+		),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	dsn := fmt.Sprintf("tcp://%s:%s?username=default", container.Address, container.Ports[servicePort])
+	plugin := newClickHouse()
+	plugin.DataSourceName = config.NewSecret([]byte(dsn))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	now := time.Now()
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"idle": 98.5}, now),
+	}))
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "b", "cpu": "cpu0"}, map[string]interface{}{"user": int64(3)}, now),
+	}))
+
+	var count int
+	require.NoError(t, plugin.db.QueryRow("SELECT count() FROM `cpu` WHERE `cpu` = 'cpu0' OR `idle` = 98.5").Scan(&count))
+	require.Equal(t, 2, count)
+}
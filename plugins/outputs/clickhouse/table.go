@@ -0,0 +1,302 @@
+package clickhouse
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	defaultCreateTemplate = `CREATE TABLE IF NOT EXISTS {{ .table }} ({{ join .columns ", " }}) ENGINE = {{ .engine }}` +
+		`{{ if .partition_by }} PARTITION BY {{ .partition_by }}{{ end }} ORDER BY {{ .order_by }}{{ if .ttl }} TTL {{ .ttl }}{{ end }}`
+	defaultAddColumnTemplate = `ALTER TABLE {{ .table }} ADD COLUMN IF NOT EXISTS {{ join .columns ", ADD COLUMN IF NOT EXISTS " }}`
+)
+
+// table is the known schema of a table mapping column names to their
+// ClickHouse types
+type table struct {
+	columns map[string]string
+}
+
+// columnsOf returns the tag and field columns of the table used by the
+// metrics
+func (t *table) columnsOf(metrics []telegraf.Metric) (tags, fields []string) {
+	tagSet := make(map[string]bool)
+	fieldSet := make(map[string]bool)
+	for _, m := range metrics {
+		for _, tag := range m.TagList() {
+			if _, found := t.columns[tag.Key]; found {
+				tagSet[tag.Key] = true
+			}
+		}
+		for _, field := range m.FieldList() {
+			if _, found := t.columns[field.Key]; found {
+				fieldSet[field.Key] = true
+			}
+		}
+	}
+	for key := range tagSet {
+		delete(fieldSet, key)
+	}
+	return sortedKeys(tagSet), sortedKeys(fieldSet)
+}
+
+// missingTags returns whether the metric has tags without column
+func (t *table) missingTags(m telegraf.Metric) bool {
+	for _, tag := range m.TagList() {
+		if _, found := t.columns[tag.Key]; !found {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureTable makes sure the table exists and has columns for the tags and
+// fields of the metrics if possible
+func (c *ClickHouse) ensureTable(name string, metrics []telegraf.Metric) (*table, error) {
+	tbl, found := c.tables[name]
+	if !found {
+		columns, err := c.loadColumns(name)
+		if err != nil {
+			return nil, fmt.Errorf("loading columns failed: %w", err)
+		}
+		if len(columns) == 0 {
+			if err := c.createTable(name, metrics); err != nil {
+				return nil, fmt.Errorf("creating table failed: %w", err)
+			}
+			// Reload the columns as the create template might define a
+			// different schema
+			if columns, err = c.loadColumns(name); err != nil {
+				return nil, fmt.Errorf("loading columns failed: %w", err)
+			}
+		}
+		tbl = &table{columns: columns}
+		c.tables[name] = tbl
+	}
+
+	missing := c.missingColumns(tbl, metrics)
+	if len(missing) == 0 {
+		return tbl, nil
+	}
+	if c.addColumnTmpl == nil {
+		return tbl, nil
+	}
+
+	definitions := make([]string, 0, len(missing))
+	for _, key := range sortedKeys(keySet(missing)) {
+		definitions = append(definitions, quoteIdent(key)+" "+missing[key])
+	}
+	query, err := c.render(c.addColumnTmpl, name, definitions)
+	if err != nil {
+		return nil, err
+	}
+	c.Log.Debugf("Adding columns: %s", query)
+	if _, err := c.db.Exec(query); err != nil {
+		return nil, fmt.Errorf("adding columns failed: %w", err)
+	}
+	for key, typ := range missing {
+		tbl.columns[key] = typ
+	}
+
+	return tbl, nil
+}
+
+// loadColumns returns the columns of an existing table or an empty map if
+// the table does not exist
+func (c *ClickHouse) loadColumns(name string) (map[string]string, error) {
+	query := "SELECT name, type FROM system.columns WHERE database = currentDatabase() AND table = ?"
+	args := []interface{}{name}
+	if c.Database != "" {
+		query = "SELECT name, type FROM system.columns WHERE database = ? AND table = ?"
+		args = []interface{}{c.Database, name}
+	}
+
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var column, typ string
+		if err := rows.Scan(&column, &typ); err != nil {
+			return nil, err
+		}
+		columns[column] = typ
+	}
+	return columns, rows.Err()
+}
+
+// createTable creates the table with columns for all tags and fields of the
+// metrics
+func (c *ClickHouse) createTable(name string, metrics []telegraf.Metric) error {
+	columns := c.missingColumns(&table{columns: map[string]string{c.TimestampColumn: "DateTime64(9)"}}, metrics)
+
+	definitions := make([]string, 0, len(columns)+1)
+	definitions = append(definitions, quoteIdent(c.TimestampColumn)+" DateTime64(9)")
+	tags := make([]string, 0)
+	for _, key := range sortedKeys(keySet(columns)) {
+		definitions = append(definitions, quoteIdent(key)+" "+columns[key])
+		if columns[key] == c.tagType() {
+			tags = append(tags, quoteIdent(key))
+		}
+	}
+
+	orderBy := c.OrderBy
+	if orderBy == "" {
+		orderBy = "(" + strings.Join(append(tags, quoteIdent(c.TimestampColumn)), ", ") + ")"
+	}
+
+	var b strings.Builder
+	data := map[string]interface{}{
+		"table":        c.tableIdent(name),
+		"columns":      definitions,
+		"engine":       c.TableEngine,
+		"order_by":     orderBy,
+		"partition_by": c.PartitionBy,
+		"ttl":          c.TTL,
+	}
+	if err := c.createTmpl.Execute(&b, data); err != nil {
+		return fmt.Errorf("rendering create template failed: %w", err)
+	}
+	c.Log.Debugf("Creating table: %s", b.String())
+	_, err := c.db.Exec(b.String())
+	return err
+}
+
+// missingColumns returns the types of the tags and fields of the metrics not
+// existing as columns of the table
+func (c *ClickHouse) missingColumns(tbl *table, metrics []telegraf.Metric) map[string]string {
+	missing := make(map[string]string)
+	for _, m := range metrics {
+		for _, tag := range m.TagList() {
+			if _, found := tbl.columns[tag.Key]; !found {
+				missing[tag.Key] = c.tagType()
+			}
+		}
+	}
+	for _, m := range metrics {
+		for _, field := range m.FieldList() {
+			if _, found := tbl.columns[field.Key]; found {
+				continue
+			}
+			if _, found := missing[field.Key]; found {
+				continue
+			}
+			if typ := columnType(field.Value); typ != "" {
+				missing[field.Key] = typ
+			}
+		}
+	}
+	return missing
+}
+
+func (c *ClickHouse) render(tmpl *template.Template, name string, definitions []string) (string, error) {
+	var b strings.Builder
+	data := map[string]interface{}{
+		"table":   c.tableIdent(name),
+		"columns": definitions,
+	}
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering template failed: %w", err)
+	}
+	return b.String(), nil
+}
+
+func (c *ClickHouse) tagType() string {
+	if c.LowCardinalityTags {
+		return "LowCardinality(String)"
+	}
+	return "String"
+}
+
+// columnType returns the ClickHouse type of the column for a field value
+func columnType(v interface{}) string {
+	switch v.(type) {
+	case int64:
+		return "Nullable(Int64)"
+	case uint64:
+		return "Nullable(UInt64)"
+	case float64:
+		return "Nullable(Float64)"
+	case string:
+		return "Nullable(String)"
+	case bool:
+		return "Nullable(UInt8)"
+	}
+	return ""
+}
+
+// convert converts the field value to the type of an existing column
+func convert(v interface{}, typ string) (interface{}, bool) {
+	// Unwrap types like "LowCardinality(Nullable(String))"
+	for _, wrapper := range []string{"LowCardinality(", "Nullable("} {
+		if strings.HasPrefix(typ, wrapper) && strings.HasSuffix(typ, ")") {
+			typ = typ[len(wrapper) : len(typ)-1]
+		}
+	}
+
+	switch typ {
+	case "Int64":
+		switch v := v.(type) {
+		case int64:
+			return v, true
+		case uint64:
+			if v <= math.MaxInt64 {
+				return int64(v), true
+			}
+		case bool:
+			if v {
+				return int64(1), true
+			}
+			return int64(0), true
+		}
+	case "UInt64":
+		switch v := v.(type) {
+		case uint64:
+			return v, true
+		case int64:
+			if v >= 0 {
+				return uint64(v), true
+			}
+		case bool:
+			if v {
+				return uint64(1), true
+			}
+			return uint64(0), true
+		}
+	case "Float64":
+		switch v := v.(type) {
+		case float64:
+			return v, true
+		case int64:
+			return float64(v), true
+		case uint64:
+			return float64(v), true
+		}
+	case "UInt8", "Bool":
+		if v, ok := v.(bool); ok {
+			return v, true
+		}
+	case "String":
+		switch v := v.(type) {
+		case string:
+			return v, true
+		case int64, uint64, float64, bool:
+			return fmt.Sprint(v), true
+		}
+	}
+	return nil, false
+}
+
+func keySet(m map[string]string) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for k := range m {
+		set[k] = true
+	}
+	return set
+}
@@ -0,0 +1,275 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/influxdata/telegraf"
+)
+
+// destination is a request target with the metrics to send to it and their
+// index within the batch
+type destination struct {
+	method  string
+	url     string
+	headers map[string]string
+	metrics []telegraf.Metric
+	indices []int
+}
+
+// route groups the metrics by their destination rendered from the url,
+// method and header templates
+func (h *HTTP) route(metrics []telegraf.Metric) ([]*destination, error) {
+	// Avoid rendering anything if the destination is fixed
+	if h.urlTmpl == nil && h.methodTmpl == nil && len(h.templateHeaders) == 0 {
+		indices := make([]int, 0, len(metrics))
+		for i := range metrics {
+			indices = append(indices, i)
+		}
+		return []*destination{{method: h.Method, url: h.URL, metrics: metrics, indices: indices}}, nil
+	}
+
+	headerTmpls, err := h.headerTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	destinations := make([]*destination, 0, 1)
+	index := make(map[string]*destination)
+	for i, m := range metrics {
+		d, err := h.render(m, headerTmpls)
+		if err != nil {
+			// Retrying would fail again, so drop the metric
+			h.Log.Errorf("Rendering destination failed, dropping metric: %v", err)
+			h.Log.Debugf("Metric was %v", m)
+			continue
+		}
+
+		key := d.key()
+		if existing, found := index[key]; found {
+			existing.metrics = append(existing.metrics, m)
+			existing.indices = append(existing.indices, i)
+			continue
+		}
+		d.metrics = []telegraf.Metric{m}
+		d.indices = []int{i}
+		index[key] = d
+		destinations = append(destinations, d)
+	}
+
+	return destinations, nil
+}
+
+// checkHeaderTemplates validates the headers containing template actions
+// and records their names. The templates are not kept to avoid holding the
+// plain value of secrets.
+func (h *HTTP) checkHeaderTemplates() error {
+	h.templateHeaders = nil
+	for k, v := range h.Headers {
+		secret, err := v.Get()
+		if err != nil {
+			return fmt.Errorf("getting header %q failed: %w", k, err)
+		}
+		value := secret.String()
+		if !isTemplate(value) {
+			secret.Destroy()
+			continue
+		}
+		_, err = template.New(k).Parse(value)
+		secret.Destroy()
+		if err != nil {
+			return fmt.Errorf("parsing template of header %q failed: %w", k, err)
+		}
+		h.templateHeaders = append(h.templateHeaders, k)
+	}
+	sort.Strings(h.templateHeaders)
+	return nil
+}
+
+// headerTemplates resolves the secrets of the templated headers and parses
+// the templates for rendering the destinations of a single write
+func (h *HTTP) headerTemplates() (map[string]*template.Template, error) {
+	tmpls := make(map[string]*template.Template, len(h.templateHeaders))
+	for _, k := range h.templateHeaders {
+		secret, err := h.Headers[k].Get()
+		if err != nil {
+			return nil, fmt.Errorf("getting header %q failed: %w", k, err)
+		}
+		tmpl, err := template.New(k).Parse(secret.String())
+		secret.Destroy()
+		if err != nil {
+			return nil, fmt.Errorf("parsing template of header %q failed: %w", k, err)
+		}
+		tmpls[k] = tmpl
+	}
+	return tmpls, nil
+}
+
+// render returns the destination of the metric
+func (h *HTTP) render(m telegraf.Metric, headerTmpls map[string]*template.Template) (*destination, error) {
+	// Use the underlying metric to provide helpers like .Tag in templates
+	if wm, ok := m.(telegraf.UnwrappableMetric); ok {
+		m = wm.Unwrap()
+	}
+
+	d := &destination{method: h.Method, url: h.URL}
+
+	if h.urlTmpl != nil {
+		u, err := execute(h.urlTmpl, urlMetric{m})
+		if err != nil {
+			return nil, fmt.Errorf("url: %w", err)
+		}
+		d.url = u
+	}
+
+	if h.methodTmpl != nil {
+		method, err := execute(h.methodTmpl, m)
+		if err != nil {
+			return nil, fmt.Errorf("method: %w", err)
+		}
+		method = strings.ToUpper(method)
+		if err := checkMethod(method); err != nil {
+			return nil, err
+		}
+		d.method = method
+	}
+
+	if len(headerTmpls) > 0 {
+		d.headers = make(map[string]string, len(headerTmpls))
+		for k, tmpl := range headerTmpls {
+			v, err := execute(tmpl, m)
+			if err != nil {
+				return nil, fmt.Errorf("header %q: %w", k, err)
+			}
+			d.headers[k] = v
+		}
+	}
+
+	return d, nil
+}
+
+// key identifies the destination for grouping metrics
+func (d *destination) key() string {
+	var b strings.Builder
+	b.WriteString(d.method)
+	b.WriteByte(0)
+	b.WriteString(d.url)
+
+	keys := make([]string, 0, len(d.headers))
+	for k := range d.headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(d.headers[k])
+	}
+	return b.String()
+}
+
+// wait blocks until the rate limit of the destination URL allows a request
+// or fails if this takes longer than the timeout
+func (h *HTTP) wait(u string) error {
+	if h.RateLimit <= 0 {
+		return nil
+	}
+
+	limiter, found := h.limiters[u]
+	if !found {
+		limiter = rate.NewLimiter(rate.Limit(h.RateLimit), h.RateLimitBurst)
+		h.limiters[u] = limiter
+	}
+
+	ctx := context.Background()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(h.Timeout))
+		defer cancel()
+	}
+	return limiter.Wait(ctx)
+}
+
+// evictLimiters removes the limiters of idle destinations. A limiter with a
+// full bucket behaves like a new one, so removing it does not affect the
+// rate limiting.
+func (h *HTTP) evictLimiters() {
+	now := time.Now()
+	for u, limiter := range h.limiters {
+		if limiter.TokensAt(now) >= float64(h.RateLimitBurst) {
+			delete(h.limiters, u)
+		}
+	}
+}
+
+// urlMetric escapes the name, the tag values and the string fields of the
+// metric for rendering URL templates
+type urlMetric struct {
+	telegraf.Metric
+}
+
+func (m urlMetric) Name() string {
+	return escapeURL(m.Metric.Name())
+}
+
+func (m urlMetric) Tag(key string) string {
+	v, _ := m.Metric.GetTag(key)
+	return escapeURL(v)
+}
+
+func (m urlMetric) Tags() map[string]string {
+	tags := make(map[string]string, len(m.Metric.TagList()))
+	for _, tag := range m.Metric.TagList() {
+		tags[tag.Key] = escapeURL(tag.Value)
+	}
+	return tags
+}
+
+func (m urlMetric) Field(key string) interface{} {
+	v, _ := m.Metric.GetField(key)
+	if s, ok := v.(string); ok {
+		return escapeURL(s)
+	}
+	return v
+}
+
+// escapeURL percent-encodes all reserved characters, so the value can be
+// used in both the path and the query of a URL
+func escapeURL(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// batchID identifies a batch by its first metric as Telegraf retries a batch
+// starting with the same metric
+func batchID(m telegraf.Metric) string {
+	return fmt.Sprintf("%d %d", m.HashID(), m.Time().UnixNano())
+}
+
+func execute(tmpl *template.Template, m telegraf.Metric) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, m); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func isTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+func checkMethod(method string) error {
+	if method != http.MethodPost && method != http.MethodPut && method != http.MethodPatch {
+		return fmt.Errorf("invalid method %q", method)
+	}
+	return nil
+}
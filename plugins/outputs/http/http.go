@@ -8,15 +8,18 @@ import (
 	"crypto/sha256"
 	_ "embed"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	aws_signer "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"golang.org/x/oauth2"
+	"golang.org/x/time/rate"
 	"google.golang.org/api/idtoken"
 
 	"github.com/influxdata/telegraf"
@@ -52,12 +55,26 @@ type HTTP struct {
 	UseBatchFormat          bool                      `toml:"use_batch_format"`
 	AwsService              string                    `toml:"aws_service"`
 	NonRetryableStatusCodes []int                     `toml:"non_retryable_statuscodes"`
+	RateLimit               float64                   `toml:"rate_limit"`
+	RateLimitBurst          int                       `toml:"rate_limit_burst"`
 	common_http.HTTPClientConfig
 	Log telegraf.Logger `toml:"-"`
 
 	client     *http.Client
 	serializer serializers.Serializer
 
+	urlTmpl         *template.Template
+	methodTmpl      *template.Template
+	templateHeaders []string
+	limiters        map[string]*rate.Limiter
+
+	// delivered maps the index of the metrics of the last batch already sent
+	// to the key of their destination if sending to other destinations
+	// failed. As Telegraf retries the whole batch, those metrics are skipped
+	// when writing the same batch again.
+	delivered      map[int]string
+	deliveredBatch string
+
 	awsCfg *aws.Config
 	common_aws.CredentialConfig
 
@@ -85,10 +102,38 @@ func (h *HTTP) Connect() error {
 	if h.Method == "" {
 		h.Method = http.MethodPost
 	}
-	h.Method = strings.ToUpper(h.Method)
-	if h.Method != http.MethodPost && h.Method != http.MethodPut && h.Method != http.MethodPatch {
-		return fmt.Errorf("invalid method [%s] %s", h.URL, h.Method)
+	if isTemplate(h.Method) {
+		tmpl, err := template.New("method").Parse(h.Method)
+		if err != nil {
+			return fmt.Errorf("parsing method template failed: %w", err)
+		}
+		h.methodTmpl = tmpl
+	} else {
+		h.Method = strings.ToUpper(h.Method)
+		if err := checkMethod(h.Method); err != nil {
+			return fmt.Errorf("invalid method [%s] %s", h.URL, h.Method)
+		}
+	}
+
+	if isTemplate(h.URL) {
+		tmpl, err := template.New("url").Parse(h.URL)
+		if err != nil {
+			return fmt.Errorf("parsing url template failed: %w", err)
+		}
+		h.urlTmpl = tmpl
+	}
+
+	if err := h.checkHeaderTemplates(); err != nil {
+		return err
+	}
+
+	if h.RateLimit < 0 {
+		return errors.New("'rate_limit' must not be negative")
+	}
+	if h.RateLimit > 0 && h.RateLimitBurst < 1 {
+		h.RateLimitBurst = 1
 	}
+	h.limiters = make(map[string]*rate.Limiter)
 
 	ctx := context.Background()
 	client, err := h.HTTPClientConfig.CreateClient(ctx, h.Log)
@@ -110,29 +155,95 @@ func (h *HTTP) Close() error {
 }
 
 func (h *HTTP) Write(metrics []telegraf.Metric) error {
+	h.evictLimiters()
+
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	// Only skip metrics delivered by a previous attempt of the same batch
+	batch := batchID(metrics[0])
+	if batch != h.deliveredBatch {
+		h.delivered = nil
+	}
+
+	destinations, err := h.route(metrics)
+	if err != nil {
+		return err
+	}
+
+	// Keep sending to the other destinations if one fails to not block all
+	// destinations by a single failing one
+	delivered := make(map[int]string)
+	var errs []error
+	for _, d := range destinations {
+		key := d.key()
+		pending := &destination{method: d.method, url: d.url, headers: d.headers}
+		for i, m := range d.metrics {
+			idx := d.indices[i]
+			if k, found := h.delivered[idx]; found && k == key {
+				delivered[idx] = key
+				continue
+			}
+			pending.metrics = append(pending.metrics, m)
+			pending.indices = append(pending.indices, idx)
+		}
+		if len(pending.metrics) == 0 {
+			continue
+		}
+
+		if err := h.writeDestination(pending, key, delivered); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		h.delivered = nil
+		h.deliveredBatch = ""
+		return nil
+	}
+	h.delivered = delivered
+	h.deliveredBatch = batch
+	return errors.Join(errs...)
+}
+
+// writeDestination sends the metrics to the destination and marks the
+// successfully sent metrics as delivered to the destination with the key
+func (h *HTTP) writeDestination(d *destination, key string, delivered map[int]string) error {
 	if h.UseBatchFormat {
-		reqBody, err := h.serializer.SerializeBatch(metrics)
+		reqBody, err := h.serializer.SerializeBatch(d.metrics)
 		if err != nil {
 			return err
 		}
 
-		return h.writeMetric(reqBody)
+		if err := h.writeMetric(d, reqBody); err != nil {
+			return err
+		}
+		for _, idx := range d.indices {
+			delivered[idx] = key
+		}
+		return nil
 	}
 
-	for _, metric := range metrics {
+	for i, metric := range d.metrics {
 		reqBody, err := h.serializer.Serialize(metric)
 		if err != nil {
 			return err
 		}
 
-		if err := h.writeMetric(reqBody); err != nil {
+		if err := h.writeMetric(d, reqBody); err != nil {
 			return err
 		}
+		delivered[d.indices[i]] = key
 	}
 	return nil
 }
 
-func (h *HTTP) writeMetric(reqBody []byte) error {
+func (h *HTTP) writeMetric(d *destination, reqBody []byte) error {
+	if err := h.wait(d.url); err != nil {
+		return fmt.Errorf("rate limit for [%s]: %w", d.url, err)
+	}
+
 	var reqBodyBuffer io.Reader = bytes.NewBuffer(reqBody)
 
 	var err error
@@ -159,7 +270,7 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 		payloadHash = &hash
 	}
 
-	req, err := http.NewRequest(h.Method, h.URL, reqBodyBuffer)
+	req, err := http.NewRequest(d.method, d.url, reqBodyBuffer)
 	if err != nil {
 		return err
 	}
@@ -196,7 +307,7 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 
 	// google api auth
 	if h.CredentialsFile != "" {
-		token, err := h.getAccessToken(context.Background(), h.URL)
+		token, err := h.getAccessToken(context.Background(), d.url)
 		if err != nil {
 			return err
 		}
@@ -210,6 +321,11 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 	}
 
 	for k, v := range h.Headers {
+		// Templated headers are rendered per destination
+		if _, found := d.headers[k]; found {
+			continue
+		}
+
 		secret, err := v.Get()
 		if err != nil {
 			return err
@@ -223,6 +339,12 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 
 		secret.Destroy()
 	}
+	for k, v := range d.headers {
+		if strings.EqualFold(k, "host") {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
 
 	resp, err := h.client.Do(req)
 	if err != nil {
@@ -244,12 +366,12 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 			errorLine = scanner.Text()
 		}
 
-		return fmt.Errorf("when writing to [%s] received status code: %d. body: %s", h.URL, resp.StatusCode, errorLine)
+		return fmt.Errorf("when writing to [%s] received status code: %d. body: %s", d.url, resp.StatusCode, errorLine)
 	}
 
 	_, err = io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("when writing to [%s] received error: %w", h.URL, err)
+		return fmt.Errorf("when writing to [%s] received error: %w", d.url, err)
 	}
 
 	return nil
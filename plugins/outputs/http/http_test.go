@@ -781,3 +781,227 @@ func TestAwsCredentials(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplatedDestination(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		tenant string
+		body   string
+	}
+	var requests []request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		requests = append(requests, request{
+			method: r.Method,
+			path:   r.URL.Path,
+			tenant: r.Header.Get("X-Tenant"),
+			body:   string(body),
+		})
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	tenantHeader := config.NewSecret([]byte(`{{.Tag "tenant"}}`))
+	plugin := &HTTP{
+		URL:            ts.URL + `/{{.Tag "tenant"}}/write`,
+		Method:         `{{if eq .Name "event"}}put{{else}}post{{end}}`,
+		UseBatchFormat: true,
+		Headers: map[string]*config.Secret{
+			"X-Tenant": &tenantHeader,
+		},
+		Log: testutil.Logger{},
+	}
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"tenant": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"tenant": "b"}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+		metric.New("event", map[string]string{"tenant": "a"}, map[string]interface{}{"value": 3.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"tenant": "a"}, map[string]interface{}{"value": 4.0}, time.Unix(0, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	expected := []request{
+		{
+			method: http.MethodPost,
+			path:   "/a/write",
+			tenant: "a",
+			body:   "cpu,tenant=a value=1 0\ncpu,tenant=a value=4 0\n",
+		},
+		{
+			method: http.MethodPost,
+			path:   "/b/write",
+			tenant: "b",
+			body:   "cpu,tenant=b value=2 0\n",
+		},
+		{
+			method: http.MethodPut,
+			path:   "/a/write",
+			tenant: "a",
+			body:   "event,tenant=a value=3 0\n",
+		},
+	}
+	require.Equal(t, expected, requests)
+}
+
+func TestTemplatedDestinationErrors(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/a" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	plugin := &HTTP{
+		URL:            ts.URL + `/{{.Tag "tenant"}}`,
+		Method:         `{{.Tag "method"}}`,
+		UseBatchFormat: true,
+		Log:            testutil.Logger{},
+	}
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	// The metric with the invalid method is dropped and the failing
+	// destination does not prevent sending to the other destination
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"tenant": "a", "method": "post"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"tenant": "b", "method": "get"}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"tenant": "c", "method": "post"}, map[string]interface{}{"value": 3.0}, time.Unix(0, 0)),
+	}
+	err := plugin.Write(metrics)
+	require.ErrorContains(t, err, "received status code: 503")
+	require.Equal(t, []string{"/a", "/c"}, paths)
+
+	// Retrying the batch only sends to the failed destination, even if the
+	// metrics are new instances as with the disk buffer
+	retry := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		retry = append(retry, m.Copy())
+	}
+	paths = nil
+	require.ErrorContains(t, plugin.Write(retry), "received status code: 503")
+	require.Equal(t, []string{"/a"}, paths)
+
+	// A different batch is sent to all destinations
+	paths = nil
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{metrics[2], metrics[0]}), "received status code: 503")
+	require.Equal(t, []string{"/c", "/a"}, paths)
+}
+
+func TestTemplatedURLEscaping(t *testing.T) {
+	var paths, queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		queries = append(queries, r.URL.Query().Get("host"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	plugin := &HTTP{
+		URL:            ts.URL + `/{{.Tag "tenant"}}/write?host={{.Tag "host"}}&db=test`,
+		Method:         defaultMethod,
+		UseBatchFormat: true,
+		Log:            testutil.Logger{},
+	}
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"tenant": "../a b?c", "host": "x&db=other"},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(0, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, []string{"/..%2Fa%20b%3Fc/write"}, paths)
+	require.Equal(t, []string{"x&db=other"}, queries)
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	plugin := &HTTP{
+		URL:    `http://localhost/{{.Tag "tenant"`,
+		Method: defaultMethod,
+	}
+	require.ErrorContains(t, plugin.Connect(), "parsing url template failed")
+}
+
+func TestRateLimit(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	plugin := &HTTP{
+		URL:            ts.URL + `/{{.Tag "tenant"}}`,
+		Method:         defaultMethod,
+		UseBatchFormat: true,
+		RateLimit:      0.001,
+		Log:            testutil.Logger{},
+		HTTPClientConfig: common_http.HTTPClientConfig{
+			Timeout: config.Duration(100 * time.Millisecond),
+		},
+	}
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	tenantA := metric.New("cpu", map[string]string{"tenant": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	tenantB := metric.New("cpu", map[string]string{"tenant": "b"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+
+	// The limits apply per destination
+	require.NoError(t, plugin.Write([]telegraf.Metric{tenantA}))
+	require.NoError(t, plugin.Write([]telegraf.Metric{tenantB}))
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{tenantA}), "rate limit")
+	require.Equal(t, 2, requests)
+}
+
+func TestRateLimitEviction(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	plugin := &HTTP{
+		URL:            ts.URL + `/{{.Tag "tenant"}}`,
+		Method:         defaultMethod,
+		UseBatchFormat: true,
+		RateLimit:      100,
+		Log:            testutil.Logger{},
+	}
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	tenantA := metric.New("cpu", map[string]string{"tenant": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	tenantB := metric.New("cpu", map[string]string{"tenant": "b"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{tenantA}))
+	require.Len(t, plugin.limiters, 1)
+
+	// The limiter of the idle destination is removed once refilled
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, plugin.Write([]telegraf.Metric{tenantB}))
+	require.Len(t, plugin.limiters, 1)
+	require.Contains(t, plugin.limiters, ts.URL+"/b")
+}
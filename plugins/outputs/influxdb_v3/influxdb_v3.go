@@ -0,0 +1,329 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package influxdb_v3
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common_http "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+const (
+	maxErrMsgLen = 1024
+	// The error body lists all rejected lines and might be large
+	maxErrBodyLen = 16 * 1024 * 1024
+)
+
+type InfluxDB struct {
+	URL                string            `toml:"url"`
+	Token              config.Secret     `toml:"token"`
+	Database           string            `toml:"database"`
+	DatabaseTag        string            `toml:"database_tag"`
+	ExcludeDatabaseTag bool              `toml:"exclude_database_tag"`
+	NoSync             bool              `toml:"no_sync"`
+	AcceptPartial      bool              `toml:"accept_partial"`
+	ContentEncoding    string            `toml:"content_encoding"`
+	Headers            map[string]string `toml:"http_headers"`
+	UintSupport        bool              `toml:"influx_uint_support"`
+	Log                telegraf.Logger   `toml:"-"`
+	common_http.HTTPClientConfig
+
+	writeURL   *url.URL
+	client     *http.Client
+	serializer *influx.Serializer
+}
+
+// apiError is a failed write not rejecting individual lines
+type apiError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s: %s", e.Status, e.Message)
+	}
+	return e.Status
+}
+
+// lineError is a single line rejected by the server, the line number is
+// one-based and refers to the lines of the request body
+type lineError struct {
+	OriginalLine string `json:"original_line"`
+	LineNumber   int    `json:"line_number"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// errorResponse is the body of failed write requests, the data is only set
+// if individual lines were rejected
+type errorResponse struct {
+	Error string          `json:"error"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// partialWriteError is a write with some of the lines rejected by the server
+type partialWriteError struct {
+	Message string
+	Lines   []lineError
+}
+
+func (e *partialWriteError) Error() string {
+	return fmt.Sprintf("%s: %d line(s) rejected", e.Message, len(e.Lines))
+}
+
+func (*InfluxDB) SampleConfig() string {
+	return sampleConfig
+}
+
+func (i *InfluxDB) Init() error {
+	if i.URL == "" {
+		return errors.New("'url' required")
+	}
+	if i.Database == "" {
+		return errors.New("'database' required")
+	}
+
+	switch i.ContentEncoding {
+	case "", "identity", "gzip":
+	default:
+		return fmt.Errorf("invalid content encoding %q", i.ContentEncoding)
+	}
+
+	u, err := url.Parse(i.URL)
+	if err != nil {
+		return fmt.Errorf("parsing url failed: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	default:
+		return fmt.Errorf("unsupported scheme %q in url", u.Scheme)
+	}
+	i.writeURL = u.JoinPath("api", "v3", "write_lp")
+
+	i.serializer = &influx.Serializer{UintSupport: i.UintSupport}
+	return i.serializer.Init()
+}
+
+func (i *InfluxDB) Connect() error {
+	client, err := i.HTTPClientConfig.CreateClient(context.Background(), i.Log)
+	if err != nil {
+		return err
+	}
+	i.client = client
+
+	return nil
+}
+
+func (i *InfluxDB) Close() error {
+	if i.client != nil {
+		i.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
+	batches := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		database := i.Database
+		if i.DatabaseTag != "" {
+			if v, found := m.GetTag(i.DatabaseTag); found && v != "" {
+				database = v
+			}
+			if i.ExcludeDatabaseTag {
+				// Avoid modifying the metric in case we need to retry the request
+				m = m.Copy()
+				m.Accept()
+				m.RemoveTag(i.DatabaseTag)
+			}
+		}
+		batches[database] = append(batches[database], m)
+	}
+
+	// Keep writing to the other databases if one fails to not block all
+	// databases by a single failing one
+	var errs []error
+	for database, batch := range batches {
+		lines := make([][]byte, 0, len(batch))
+		for _, m := range batch {
+			line, err := i.serializer.Serialize(m)
+			if err != nil {
+				i.Log.Errorf("Could not serialize metric: %v", err)
+				continue
+			}
+			lines = append(lines, line)
+		}
+		if err := i.writeLines(database, lines); err != nil {
+			errs = append(errs, fmt.Errorf("writing to database %q failed: %w", database, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (i *InfluxDB) writeLines(database string, lines [][]byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	err := i.send(database, lines)
+	if err == nil {
+		return nil
+	}
+
+	var partialErr *partialWriteError
+	if errors.As(err, &partialErr) {
+		rejected := make(map[int]bool, len(partialErr.Lines))
+		for _, l := range partialErr.Lines {
+			i.Log.Errorf("Line %d rejected by database %q and dropped: %s: %s",
+				l.LineNumber, database, l.ErrorMessage, l.OriginalLine)
+			if l.LineNumber > 0 && l.LineNumber <= len(lines) {
+				rejected[l.LineNumber-1] = true
+			}
+		}
+
+		// The server already wrote the valid lines
+		if i.AcceptPartial {
+			return nil
+		}
+
+		// Without partial writes nothing was written so resend the valid lines
+		if len(rejected) == 0 {
+			i.Log.Errorf("Cannot identify rejected lines, dropping %d line(s): %v", len(lines), err)
+			return nil
+		}
+		remaining := make([][]byte, 0, len(lines)-len(rejected))
+		for idx, line := range lines {
+			if !rejected[idx] {
+				remaining = append(remaining, line)
+			}
+		}
+		return i.writeLines(database, remaining)
+	}
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusRequestEntityTooLarge && len(lines) > 1 {
+		i.Log.Warnf("Retrying write after splitting metric payload in half to reduce batch size")
+		midpoint := len(lines) / 2
+		if err := i.writeLines(database, lines[:midpoint]); err != nil {
+			return err
+		}
+		return i.writeLines(database, lines[midpoint:])
+	}
+
+	return err
+}
+
+func (i *InfluxDB) send(database string, lines [][]byte) error {
+	params := url.Values{}
+	params.Set("db", database)
+	params.Set("precision", "nanosecond")
+	params.Set("accept_partial", strconv.FormatBool(i.AcceptPartial))
+	params.Set("no_sync", strconv.FormatBool(i.NoSync))
+	u := *i.writeURL
+	u.RawQuery = params.Encode()
+
+	var body io.Reader = bytes.NewReader(bytes.Join(lines, nil))
+	if i.ContentEncoding == "gzip" {
+		rc := internal.CompressWithGzip(body)
+		defer rc.Close()
+		body = rc
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.ContentEncoding == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range i.Headers {
+		if strings.EqualFold(k, "host") {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+	if !i.Token.Empty() {
+		token, err := i.Token.Get()
+		if err != nil {
+			return fmt.Errorf("getting token failed: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.String())
+		token.Destroy()
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		internal.OnClientError(i.client, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+
+	// The error body is JSON in most cases but fall back to the raw content
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxErrBodyLen))
+	if err != nil {
+		return fmt.Errorf("reading response failed: %w", err)
+	}
+	var msg errorResponse
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		msg.Error = strings.TrimSpace(string(raw[:min(len(raw), maxErrMsgLen)]))
+	}
+
+	// Rejected lines are reported individually as data of the response
+	if resp.StatusCode == http.StatusBadRequest && len(msg.Data) > 0 {
+		var lines []lineError
+		if err := json.Unmarshal(msg.Data, &lines); err == nil && len(lines) > 0 {
+			return &partialWriteError{Message: msg.Error, Lines: lines}
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusRequestEntityTooLarge,
+		http.StatusTooManyRequests:
+		return &apiError{StatusCode: resp.StatusCode, Status: resp.Status, Message: msg.Error}
+	}
+
+	// Any other 4xx is the client's mistake and retrying will not make the
+	// request succeed
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		i.Log.Errorf("Failed to write metrics to database %q (will be dropped: %s): %s", database, resp.Status, msg.Error)
+		return nil
+	}
+
+	return &apiError{StatusCode: resp.StatusCode, Status: resp.Status, Message: msg.Error}
+}
+
+func init() {
+	outputs.Add("influxdb_v3", func() telegraf.Output {
+		return &InfluxDB{
+			URL:             "http://localhost:8181",
+			AcceptPartial:   true,
+			ContentEncoding: "gzip",
+		}
+	})
+}
@@ -0,0 +1,364 @@
+package influxdb_v3
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newPlugin(u string) *InfluxDB {
+	plugin := outputs.Outputs["influxdb_v3"]().(*InfluxDB)
+	plugin.URL = u
+	plugin.Database = "telegraf"
+	plugin.ContentEncoding = "identity"
+	plugin.Log = testutil.Logger{}
+	return plugin
+}
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 1)),
+		metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.0}, time.Unix(0, 2)),
+		metric.New("cpu", map[string]string{"host": "c"}, map[string]interface{}{"value": 3.0}, time.Unix(0, 3)),
+	}
+}
+
+// request is a write request received by the test server
+type request struct {
+	database string
+	query    map[string]string
+	header   http.Header
+	lines    []string
+}
+
+// server records the write requests and responds using the given handler
+type server struct {
+	requests []request
+	respond  func(w http.ResponseWriter, r request)
+	sync.Mutex
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v3/write_lp" || r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	req := request{
+		database: r.URL.Query().Get("db"),
+		query:    make(map[string]string),
+		header:   r.Header,
+		lines:    strings.Split(strings.TrimSuffix(string(body), "\n"), "\n"),
+	}
+	for k := range r.URL.Query() {
+		req.query[k] = r.URL.Query().Get(k)
+	}
+
+	s.Lock()
+	s.requests = append(s.requests, req)
+	s.Unlock()
+
+	if s.respond == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.respond(w, req)
+}
+
+// rejectHost responds with a partial write error for all lines of the given host
+func rejectHost(host string) func(w http.ResponseWriter, r request) {
+	return func(w http.ResponseWriter, r request) {
+		var rejected []lineError
+		for i, line := range r.lines {
+			if strings.Contains(line, "host="+host+" ") {
+				rejected = append(rejected, lineError{
+					OriginalLine: line,
+					LineNumber:   i + 1,
+					ErrorMessage: "invalid column type for column 'value'",
+				})
+			}
+		}
+		if len(rejected) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		data, err := json.Marshal(rejected)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		//nolint:errcheck // Ignore the returned error as we cannot do anything about it anyway
+		json.NewEncoder(w).Encode(errorResponse{Error: "partial write of line protocol occurred", Data: data})
+	}
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(i *InfluxDB)
+		expected string
+	}{
+		{
+			name:     "no url",
+			modify:   func(i *InfluxDB) { i.URL = "" },
+			expected: "'url' required",
+		},
+		{
+			name:     "no database",
+			modify:   func(i *InfluxDB) { i.Database = "" },
+			expected: "'database' required",
+		},
+		{
+			name:     "invalid encoding",
+			modify:   func(i *InfluxDB) { i.ContentEncoding = "br" },
+			expected: `invalid content encoding "br"`,
+		},
+		{
+			name:     "invalid scheme",
+			modify:   func(i *InfluxDB) { i.URL = "udp://localhost:8181" },
+			expected: `unsupported scheme "udp"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newPlugin("http://localhost:8181")
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.Token = config.NewSecret([]byte("secret"))
+	plugin.NoSync = true
+	plugin.Headers = map[string]string{"X-Test": "value"}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	require.Len(t, srv.requests, 1)
+	req := srv.requests[0]
+	expectedQuery := map[string]string{
+		"db":             "telegraf",
+		"precision":      "nanosecond",
+		"accept_partial": "true",
+		"no_sync":        "true",
+	}
+	require.Equal(t, expectedQuery, req.query)
+	require.Equal(t, "Bearer secret", req.header.Get("Authorization"))
+	require.Equal(t, "value", req.header.Get("X-Test"))
+	expected := []string{
+		"cpu,host=a value=1 1",
+		"cpu,host=b value=2 2",
+		"cpu,host=c value=3 3",
+	}
+	require.Equal(t, expected, req.lines)
+}
+
+func TestWriteGzip(t *testing.T) {
+	var encoding string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.ContentEncoding = "gzip"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Equal(t, "gzip", encoding)
+}
+
+func TestWriteDatabaseTag(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.DatabaseTag = "db"
+	plugin.ExcludeDatabaseTag = true
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"db": "foo"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 1)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(0, 2)),
+		metric.New("cpu", map[string]string{"db": "foo"}, map[string]interface{}{"value": 3.0}, time.Unix(0, 3)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	actual := make(map[string][]string)
+	for _, req := range srv.requests {
+		actual[req.database] = req.lines
+	}
+	expected := map[string][]string{
+		"foo":      {"cpu value=1 1", "cpu value=3 3"},
+		"telegraf": {"cpu value=2 2"},
+	}
+	require.Equal(t, expected, actual)
+
+	// The original metrics must not be modified
+	require.True(t, metrics[0].HasTag("db"))
+}
+
+func TestWritePartialAccepted(t *testing.T) {
+	srv := &server{respond: rejectHost("b")}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// The server already wrote the valid lines so nothing is resent
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Len(t, srv.requests, 1)
+}
+
+func TestWritePartialRejected(t *testing.T) {
+	srv := &server{respond: rejectHost("b")}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.AcceptPartial = false
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// The batch is resent without the rejected line
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Len(t, srv.requests, 2)
+	require.Equal(t, "false", srv.requests[0].query["accept_partial"])
+	expected := []string{
+		"cpu,host=a value=1 1",
+		"cpu,host=c value=3 3",
+	}
+	require.Equal(t, expected, srv.requests[1].lines)
+}
+
+func TestWriteSplitTooLarge(t *testing.T) {
+	srv := &server{
+		respond: func(w http.ResponseWriter, r request) {
+			if len(r.lines) > 1 {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	var written []string
+	for _, req := range srv.requests {
+		if len(req.lines) == 1 {
+			written = append(written, req.lines...)
+		}
+	}
+	expected := []string{
+		"cpu,host=a value=1 1",
+		"cpu,host=b value=2 2",
+		"cpu,host=c value=3 3",
+	}
+	require.Equal(t, expected, written)
+}
+
+func TestWriteErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{
+			name:     "unauthorized",
+			status:   http.StatusUnauthorized,
+			body:     `{"error": "invalid token"}`,
+			expected: "401 Unauthorized: invalid token",
+		},
+		{
+			name:     "rate limited",
+			status:   http.StatusTooManyRequests,
+			expected: "429 Too Many Requests",
+		},
+		{
+			name:     "server error",
+			status:   http.StatusInternalServerError,
+			body:     "internal failure",
+			expected: "500 Internal Server Error: internal failure",
+		},
+		{
+			name:   "bad request without lines",
+			status: http.StatusBadRequest,
+			body:   `{"error": "invalid precision", "data": null}`,
+		},
+		{
+			name:   "unprocessable",
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				//nolint:errcheck // Ignore the returned error as we cannot do anything about it anyway
+				w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			plugin := newPlugin(ts.URL)
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			defer plugin.Close()
+
+			err := plugin.Write(testMetrics())
+			if tt.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -40,6 +41,8 @@ type Kafka struct {
 	RoutingKey        string          `toml:"routing_key"`
 	ProducerTimestamp string          `toml:"producer_timestamp"`
 	MetricNameHeader  string          `toml:"metric_name_header"`
+	ExactlyOnce       bool            `toml:"exactly_once"`
+	TransactionalID   string          `toml:"transactional_id"`
 	Log               telegraf.Logger `toml:"-"`
 	proxy.Socks5ProxyConfig
 	kafka.WriteConfig
@@ -52,6 +55,7 @@ type Kafka struct {
 	// TLS certificate authority
 	CA string
 
+	pluginID     string
 	saramaConfig *sarama.Config
 	producerFunc func(addrs []string, config *sarama.Config) (sarama.SyncProducer, error)
 	producer     sarama.SyncProducer
@@ -114,6 +118,10 @@ func (k *Kafka) GetTopicName(metric telegraf.Metric) (telegraf.Metric, string) {
 	return metric, topicName
 }
 
+func (k *Kafka) SetPluginID(id string) {
+	k.pluginID = id
+}
+
 func (k *Kafka) SetSerializer(serializer serializers.Serializer) {
 	k.serializer = serializer
 }
@@ -146,6 +154,12 @@ func (k *Kafka) Init() error {
 		}
 		config.Net.Proxy.Dialer = dialer
 	}
+
+	if k.ExactlyOnce {
+		if err := k.setTransactionConfig(config); err != nil {
+			return err
+		}
+	}
 	k.saramaConfig = config
 
 	switch k.ProducerTimestamp {
@@ -159,6 +173,41 @@ func (k *Kafka) Init() error {
 	return nil
 }
 
+// setTransactionConfig configures an idempotent, transactional producer
+func (k *Kafka) setTransactionConfig(config *sarama.Config) error {
+	// Use a transactional ID stable across restarts so the broker can fence
+	// transactions of a previous producer instance
+	id := k.TransactionalID
+	if id == "" {
+		if k.pluginID == "" {
+			return errors.New("'transactional_id' required as the plugin ID is unknown")
+		}
+		// Hosts sharing the same configuration end up with the same plugin
+		// ID and would fence each other, so include the hostname
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("'transactional_id' required as getting the hostname failed: %w", err)
+		}
+		id = "telegraf-" + hostname + "-" + k.pluginID
+	}
+
+	if !config.Version.IsAtLeast(sarama.V0_11_0_0) {
+		return errors.New("exactly-once mode requires a kafka version of 0.11.0 or later")
+	}
+	if k.RequiredAcks != int(sarama.WaitForAll) {
+		return errors.New("exactly-once mode requires 'required_acks = -1'")
+	}
+	if k.MaxRetry < 1 {
+		return errors.New("exactly-once mode requires 'max_retry' to be greater than zero")
+	}
+
+	config.Producer.Idempotent = true
+	config.Producer.Transaction.ID = id
+	config.Net.MaxOpenRequests = 1
+
+	return nil
+}
+
 func (k *Kafka) Connect() error {
 	producer, err := k.producerFunc(k.Brokers, k.saramaConfig)
 	if err != nil {
@@ -235,32 +284,89 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 		msgs = append(msgs, m)
 	}
 
-	err := k.producer.SendMessages(msgs)
-	if err != nil {
-		// We could have many errors, return only the first encountered.
-		var errs sarama.ProducerErrors
-		if errors.As(err, &errs) && len(errs) > 0 {
-			// Just return the first error encountered
-			firstErr := errs[0]
-			if errors.Is(firstErr.Err, sarama.ErrMessageSizeTooLarge) {
-				k.Log.Error("Message too large, consider increasing `max_message_bytes`; dropping batch")
-				return nil
-			}
-			if errors.Is(firstErr.Err, sarama.ErrInvalidTimestamp) {
-				k.Log.Error(
-					"The timestamp of the message is out of acceptable range, consider increasing broker `message.timestamp.difference.max.ms`; " +
-						"dropping batch",
-				)
-				return nil
-			}
-			return firstErr
+	if k.ExactlyOnce {
+		return k.sendTransaction(msgs)
+	}
+	return k.handleSendError(k.producer.SendMessages(msgs))
+}
+
+// sendTransaction sends the messages within a transaction and aborts the
+// transaction on error so a retried write does not duplicate messages
+func (k *Kafka) sendTransaction(msgs []*sarama.ProducerMessage) error {
+	// Recreate a producer in fatal state
+	if k.producer == nil {
+		producer, err := k.producerFunc(k.Brokers, k.saramaConfig)
+		if err != nil {
+			return fmt.Errorf("creating producer failed: %w", err)
 		}
-		return err
+		k.producer = producer
+	}
+
+	if err := k.producer.BeginTxn(); err != nil {
+		k.checkFatal()
+		return fmt.Errorf("beginning transaction failed: %w", err)
 	}
 
+	if err := k.producer.SendMessages(msgs); err != nil {
+		k.abort()
+		return k.handleSendError(err)
+	}
+
+	if err := k.producer.CommitTxn(); err != nil {
+		k.abort()
+		return fmt.Errorf("committing transaction failed: %w", err)
+	}
 	return nil
 }
 
+func (k *Kafka) abort() {
+	if k.producer.TxnStatus()&sarama.ProducerTxnFlagInTransaction != 0 {
+		if err := k.producer.AbortTxn(); err != nil {
+			k.Log.Errorf("Aborting transaction failed: %v", err)
+		}
+	}
+	k.checkFatal()
+}
+
+// checkFatal closes a producer in fatal state, a new producer is created on
+// the next write
+func (k *Kafka) checkFatal() {
+	if k.producer.TxnStatus()&sarama.ProducerTxnFlagFatalError == 0 {
+		return
+	}
+	k.Log.Error("Producer is in fatal state, recreating producer")
+	if err := k.producer.Close(); err != nil {
+		k.Log.Errorf("Closing producer failed: %v", err)
+	}
+	k.producer = nil
+}
+
+func (k *Kafka) handleSendError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// We could have many errors, return only the first encountered.
+	var errs sarama.ProducerErrors
+	if errors.As(err, &errs) && len(errs) > 0 {
+		// Just return the first error encountered
+		firstErr := errs[0]
+		if errors.Is(firstErr.Err, sarama.ErrMessageSizeTooLarge) {
+			k.Log.Error("Message too large, consider increasing `max_message_bytes`; dropping batch")
+			return nil
+		}
+		if errors.Is(firstErr.Err, sarama.ErrInvalidTimestamp) {
+			k.Log.Error(
+				"The timestamp of the message is out of acceptable range, consider increasing broker `message.timestamp.difference.max.ms`; " +
+					"dropping batch",
+			)
+			return nil
+		}
+		return firstErr
+	}
+	return err
+}
+
 func init() {
 	outputs.Add("kafka", func() telegraf.Output {
 		return &Kafka{
@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"context"
+	"os"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -312,3 +314,177 @@ func TestTopicTag(t *testing.T) {
 		})
 	}
 }
+
+func TestExactlyOnceInit(t *testing.T) {
+	plugin := outputs.Outputs["kafka"]().(*Kafka)
+	plugin.Brokers = []string{"127.0.0.1"}
+	plugin.Topic = "telegraf"
+	plugin.ExactlyOnce = true
+	plugin.Log = testutil.Logger{}
+
+	// The transactional ID is derived from the hostname and plugin ID
+	require.ErrorContains(t, plugin.Init(), "'transactional_id' required")
+	plugin.SetPluginID("abc")
+	require.NoError(t, plugin.Init())
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	require.Equal(t, "telegraf-"+hostname+"-abc", plugin.saramaConfig.Producer.Transaction.ID)
+	require.True(t, plugin.saramaConfig.Producer.Idempotent)
+	require.NoError(t, plugin.saramaConfig.Validate())
+
+	// An explicit transactional ID takes precedence
+	plugin.TransactionalID = "billing"
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "billing", plugin.saramaConfig.Producer.Transaction.ID)
+
+	plugin.RequiredAcks = 1
+	require.ErrorContains(t, plugin.Init(), "requires 'required_acks = -1'")
+}
+
+type MockTxnProducer struct {
+	MockProducer
+	status  sarama.ProducerTxnStatusFlag
+	fail    error
+	fatal   bool
+	commits int
+	aborts  int
+	closed  bool
+}
+
+func (p *MockTxnProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	if p.fail != nil {
+		if p.fatal {
+			p.status |= sarama.ProducerTxnFlagFatalError
+		} else {
+			p.status |= sarama.ProducerTxnFlagAbortableError
+		}
+		return p.fail
+	}
+	return p.MockProducer.SendMessages(msgs)
+}
+
+func (p *MockTxnProducer) BeginTxn() error {
+	p.status = sarama.ProducerTxnFlagInTransaction
+	return nil
+}
+
+func (p *MockTxnProducer) CommitTxn() error {
+	p.commits++
+	p.status = sarama.ProducerTxnFlagReady
+	return nil
+}
+
+func (p *MockTxnProducer) AbortTxn() error {
+	p.aborts++
+	p.status &^= sarama.ProducerTxnFlagInTransaction | sarama.ProducerTxnFlagAbortableError
+	return nil
+}
+
+func (p *MockTxnProducer) TxnStatus() sarama.ProducerTxnStatusFlag {
+	return p.status
+}
+
+func (p *MockTxnProducer) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestExactlyOnceWrite(t *testing.T) {
+	var created []*MockTxnProducer
+	plugin := outputs.Outputs["kafka"]().(*Kafka)
+	plugin.Brokers = []string{"127.0.0.1"}
+	plugin.Topic = "telegraf"
+	plugin.ExactlyOnce = true
+	plugin.Log = testutil.Logger{}
+	plugin.producerFunc = func([]string, *sarama.Config) (sarama.SyncProducer, error) {
+		p := &MockTxnProducer{}
+		created = append(created, p)
+		return p, nil
+	}
+	plugin.SetPluginID("abc")
+
+	s := &influx.Serializer{}
+	require.NoError(t, s.Init())
+	plugin.SetSerializer(s)
+
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Each write is committed as one transaction
+	require.NoError(t, plugin.Write(testutil.MockMetrics()))
+	require.NoError(t, plugin.Write(testutil.MockMetrics()))
+	require.Len(t, created, 1)
+	require.Equal(t, 2, created[0].commits)
+	require.Len(t, created[0].sent, 2)
+
+	// A failed write aborts the transaction
+	created[0].fail = sarama.ErrOutOfBrokers
+	require.ErrorIs(t, plugin.Write(testutil.MockMetrics()), sarama.ErrOutOfBrokers)
+	require.Equal(t, 1, created[0].aborts)
+	require.Equal(t, 2, created[0].commits)
+	require.False(t, created[0].closed)
+
+	// A producer in fatal state is replaced on the next write
+	created[0].fatal = true
+	require.Error(t, plugin.Write(testutil.MockMetrics()))
+	require.True(t, created[0].closed)
+	require.NoError(t, plugin.Write(testutil.MockMetrics()))
+	require.Len(t, created, 2)
+	require.Equal(t, 1, created[1].commits)
+}
+
+func TestExactlyOnceMockBroker(t *testing.T) {
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	metadata := sarama.NewMockMetadataResponse(t).
+		SetController(broker.BrokerID()).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetLeader("telegraf", 0, broker.BrokerID())
+	coordinator := sarama.NewMockFindCoordinatorResponse(t).
+		SetCoordinator(sarama.CoordinatorTransaction, "telegraf-"+hostname+"-abc", broker)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest":        metadata,
+		"FindCoordinatorRequest": coordinator,
+		"InitProducerIDRequest": sarama.NewMockWrapper(&sarama.InitProducerIDResponse{
+			ProducerID: 1,
+		}),
+		"AddPartitionsToTxnRequest": sarama.NewMockWrapper(&sarama.AddPartitionsToTxnResponse{
+			Errors: map[string][]*sarama.PartitionError{"telegraf": {{Partition: 0}}},
+		}),
+		"ProduceRequest": sarama.NewMockProduceResponse(t).SetVersion(3),
+		"EndTxnRequest":  sarama.NewMockWrapper(&sarama.EndTxnResponse{}),
+	})
+
+	plugin := outputs.Outputs["kafka"]().(*Kafka)
+	plugin.Brokers = []string{broker.Addr()}
+	plugin.Topic = "telegraf"
+	plugin.Version = "0.11.0.0"
+	plugin.ExactlyOnce = true
+	plugin.Log = testutil.Logger{}
+	plugin.SetPluginID("abc")
+
+	s := &influx.Serializer{}
+	require.NoError(t, s.Init())
+	plugin.SetSerializer(s)
+
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(testutil.MockMetrics()))
+
+	var endTxn int
+	for _, rr := range broker.History() {
+		if req, ok := rr.Request.(*sarama.EndTxnRequest); ok {
+			require.Equal(t, "telegraf-"+hostname+"-abc", req.TransactionalID)
+			require.True(t, req.TransactionResult)
+			endTxn++
+		}
+	}
+	require.Equal(t, 1, endTxn)
+}
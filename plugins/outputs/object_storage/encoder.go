@@ -0,0 +1,271 @@
+package object_storage
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/apache/arrow/go/v18/parquet"
+	"github.com/apache/arrow/go/v18/parquet/compress"
+	"github.com/apache/arrow/go/v18/parquet/pqarrow"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers/json"
+)
+
+// encoder creates the content of an object from the metrics of a partition
+type encoder interface {
+	encode(metrics []telegraf.Metric) ([]byte, error)
+	extension() string
+}
+
+type jsonEncoder struct {
+	serializer *json.Serializer
+	encoder    internal.ContentEncoder
+	ext        string
+}
+
+func newJSONEncoder(compression string) (*jsonEncoder, error) {
+	var ext string
+	switch compression {
+	case "", "gzip":
+		compression, ext = "gzip", ".ndjson.gz"
+	case "zstd":
+		ext = ".ndjson.zst"
+	case "none":
+		compression, ext = "identity", ".ndjson"
+	default:
+		return nil, fmt.Errorf("invalid compression %q for ndjson", compression)
+	}
+
+	enc, err := internal.NewContentEncoder(compression)
+	if err != nil {
+		return nil, err
+	}
+
+	serializer := &json.Serializer{TimestampUnits: config.Duration(time.Nanosecond)}
+	if err := serializer.Init(); err != nil {
+		return nil, err
+	}
+
+	return &jsonEncoder{serializer: serializer, encoder: enc, ext: ext}, nil
+}
+
+func (e *jsonEncoder) encode(metrics []telegraf.Metric) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		line, err := e.serializer.Serialize(m)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+	}
+	return e.encoder.Encode(buf.Bytes())
+}
+
+func (e *jsonEncoder) extension() string {
+	return e.ext
+}
+
+// column of the parquet schema taken either from a tag or a field
+type column struct {
+	name  string
+	key   string
+	tag   bool
+	dtype arrow.DataType
+}
+
+type parquetEncoder struct {
+	codec compress.Compression
+	log   telegraf.Logger
+}
+
+func newParquetEncoder(compression string, log telegraf.Logger) (*parquetEncoder, error) {
+	var codec compress.Compression
+	switch compression {
+	case "", "snappy":
+		codec = compress.Codecs.Snappy
+	case "gzip":
+		codec = compress.Codecs.Gzip
+	case "zstd":
+		codec = compress.Codecs.Zstd
+	case "none":
+		codec = compress.Codecs.Uncompressed
+	default:
+		return nil, fmt.Errorf("invalid compression %q for parquet", compression)
+	}
+	return &parquetEncoder{codec: codec, log: log}, nil
+}
+
+func (e *parquetEncoder) encode(metrics []telegraf.Metric) ([]byte, error) {
+	columns := schemaColumns(metrics)
+	for _, c := range columns {
+		if c.name != c.key {
+			e.log.Debugf("Storing %q as column %q due to a name collision", c.key, c.name)
+		}
+	}
+
+	fields := make([]arrow.Field, 0, len(columns)+2)
+	fields = append(fields,
+		arrow.Field{Name: "timestamp", Type: arrow.FixedWidthTypes.Timestamp_ns},
+		arrow.Field{Name: "name", Type: arrow.BinaryTypes.String},
+	)
+	for _, c := range columns {
+		fields = append(fields, arrow.Field{Name: c.name, Type: c.dtype, Nullable: true})
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+
+	for _, m := range metrics {
+		builder.Field(0).(*array.TimestampBuilder).Append(arrow.Timestamp(m.Time().UnixNano()))
+		builder.Field(1).(*array.StringBuilder).Append(m.Name())
+
+		for i, c := range columns {
+			var value interface{}
+			var found bool
+			if c.tag {
+				value, found = m.GetTag(c.key)
+			} else {
+				value, found = m.GetField(c.key)
+			}
+			appendValue(builder.Field(i+2), value, found)
+		}
+	}
+
+	record := builder.NewRecord()
+	defer record.Release()
+
+	var buf bytes.Buffer
+	props := parquet.NewWriterProperties(parquet.WithCompression(e.codec))
+	writer, err := pqarrow.NewFileWriter(schema, &buf, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return nil, fmt.Errorf("creating parquet writer failed: %w", err)
+	}
+	if err := writer.Write(record); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("writing record failed: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("closing parquet writer failed: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (*parquetEncoder) extension() string {
+	return ".parquet"
+}
+
+// schemaColumns returns the sorted tag columns followed by the sorted field
+// columns of all metrics. The type of a field is the type of its first
+// occurrence. Tags colliding with the timestamp or name column are prefixed
+// with "tag_" and fields colliding with any other column with "field_".
+func schemaColumns(metrics []telegraf.Metric) []column {
+	tagKeys := make(map[string]bool)
+	for _, m := range metrics {
+		for _, tag := range m.TagList() {
+			tagKeys[tag.Key] = true
+		}
+	}
+
+	used := map[string]bool{"timestamp": true, "name": true}
+	tags := make([]column, 0, len(tagKeys))
+	for key := range tagKeys {
+		tags = append(tags, column{key: key, tag: true, dtype: arrow.BinaryTypes.String})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].key < tags[j].key })
+	for i := range tags {
+		tags[i].name = uniqueName(tags[i].key, "tag_", used)
+	}
+
+	seen := make(map[string]bool)
+	fields := make([]column, 0)
+	for _, m := range metrics {
+		for _, field := range m.FieldList() {
+			if seen[field.Key] {
+				continue
+			}
+			dtype := arrowType(field.Value)
+			if dtype == nil {
+				continue
+			}
+			seen[field.Key] = true
+			fields = append(fields, column{key: field.Key, dtype: dtype})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+	for i := range fields {
+		fields[i].name = uniqueName(fields[i].key, "field_", used)
+	}
+
+	return append(tags, fields...)
+}
+
+// uniqueName prefixes the name until it does not collide with the used names
+// and marks the result as used
+func uniqueName(name, prefix string, used map[string]bool) string {
+	for used[name] {
+		name = prefix + name
+	}
+	used[name] = true
+	return name
+}
+
+func arrowType(value interface{}) arrow.DataType {
+	switch value.(type) {
+	case int64:
+		return arrow.PrimitiveTypes.Int64
+	case uint64:
+		return arrow.PrimitiveTypes.Uint64
+	case float64:
+		return arrow.PrimitiveTypes.Float64
+	case string:
+		return arrow.BinaryTypes.String
+	case bool:
+		return arrow.FixedWidthTypes.Boolean
+	}
+	return nil
+}
+
+// appendValue adds the value to the column, missing values and values not
+// matching the type of the column are stored as null
+func appendValue(b array.Builder, value interface{}, found bool) {
+	if found {
+		switch b := b.(type) {
+		case *array.Int64Builder:
+			if v, ok := value.(int64); ok {
+				b.Append(v)
+				return
+			}
+		case *array.Uint64Builder:
+			if v, ok := value.(uint64); ok {
+				b.Append(v)
+				return
+			}
+		case *array.Float64Builder:
+			if v, ok := value.(float64); ok {
+				b.Append(v)
+				return
+			}
+		case *array.StringBuilder:
+			if v, ok := value.(string); ok {
+				b.Append(v)
+				return
+			}
+		case *array.BooleanBuilder:
+			if v, ok := value.(bool); ok {
+				b.Append(v)
+				return
+			}
+		}
+	}
+	b.AppendNull()
+}
@@ -0,0 +1,297 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package object_storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/object"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+const defaultPartition = `measurement={{.Name}}/date={{.Time.UTC.Format "2006-01-02"}}/hour={{.Time.UTC.Format "15"}}`
+
+type ObjectStorage struct {
+	Remote        config.Secret   `toml:"remote"`
+	Partition     string          `toml:"partition"`
+	Format        string          `toml:"format"`
+	Compression   string          `toml:"compression"`
+	MaxSize       config.Size     `toml:"max_size"`
+	UploadTimeout config.Duration `toml:"upload_timeout"`
+	Log           telegraf.Logger `toml:"-"`
+
+	root   fs.Fs
+	cancel context.CancelFunc
+
+	partition *template.Template
+	encoder   encoder
+}
+
+func (*ObjectStorage) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *ObjectStorage) Init() error {
+	// Set defaults
+	if o.Remote.Empty() {
+		if err := o.Remote.Set([]byte("local:")); err != nil {
+			return fmt.Errorf("setting default remote failed: %w", err)
+		}
+	}
+	if o.Partition == "" {
+		o.Partition = defaultPartition
+	}
+
+	if o.MaxSize <= 0 {
+		return errors.New("'max_size' must be positive")
+	}
+	if o.UploadTimeout <= 0 {
+		return errors.New("'upload_timeout' must be positive")
+	}
+
+	var err error
+	switch o.Format {
+	case "parquet":
+		o.encoder, err = newParquetEncoder(o.Compression, o.Log)
+	case "ndjson":
+		o.encoder, err = newJSONEncoder(o.Compression)
+	default:
+		return fmt.Errorf("invalid format %q", o.Format)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Setup the partition template
+	tmpl, err := template.New("partition").Parse(o.Partition)
+	if err != nil {
+		return fmt.Errorf("parsing partition template failed: %w", err)
+	}
+	o.partition = tmpl
+
+	// Redirect logging
+	fs.LogPrint = func(level fs.LogLevel, text string) {
+		o.Log.Tracef("[%s] %s", level.String(), text)
+	}
+
+	return nil
+}
+
+func (o *ObjectStorage) Connect() error {
+	remoteRaw, err := o.Remote.Get()
+	if err != nil {
+		return fmt.Errorf("getting remote secret failed: %w", err)
+	}
+	remote := remoteRaw.String()
+	remoteRaw.Destroy()
+
+	// Construct the underlying filesystem config
+	parsed, err := fspath.Parse(remote)
+	if err != nil {
+		return fmt.Errorf("parsing remote failed: %w", err)
+	}
+	info, err := fs.Find(parsed.Name)
+	if err != nil {
+		return fmt.Errorf("cannot find remote type %q: %w", parsed.Name, err)
+	}
+
+	// Setup the remote filesystem
+	ctx, cancel := context.WithCancel(context.Background())
+	root, err := info.NewFs(ctx, parsed.Name, parsed.Path, fs.ConfigMap(info, parsed.Name, parsed.Config))
+	if err != nil {
+		cancel()
+		return fmt.Errorf("creating remote failed: %w", err)
+	}
+
+	// Force connection to make sure we actually can connect
+	if _, err := root.List(ctx, ""); err != nil {
+		cancel()
+		return err
+	}
+	o.root = root
+	o.cancel = cancel
+	o.Log.Debugf("Connected to %s", root.String())
+
+	return nil
+}
+
+func (o *ObjectStorage) Close() error {
+	if o.cancel != nil {
+		o.cancel()
+		o.cancel = nil
+	}
+	o.root = nil
+
+	return nil
+}
+
+// Write uploads the metrics of each partition of the batch before returning
+// so Telegraf keeps the metrics if an upload fails. Object names are derived
+// from the batch, so retrying the batch replaces the objects uploaded before
+// instead of duplicating the metrics.
+func (o *ObjectStorage) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	batch := batchID(metrics[0])
+
+	keys := make([]string, 0)
+	partitions := make(map[string][]telegraf.Metric)
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		buf.Reset()
+		if err := o.partition.Execute(&buf, m); err != nil {
+			o.Log.Errorf("Cannot create partition for metric %v: %v", m, err)
+			continue
+		}
+		key := cleanPartition(buf.String())
+		if _, found := partitions[key]; !found {
+			keys = append(keys, key)
+		}
+		partitions[key] = append(partitions[key], m)
+	}
+
+	var failed int
+	for _, key := range keys {
+		for i, chunk := range o.split(partitions[key]) {
+			if err := o.store(key, batch, i, chunk); err != nil {
+				o.Log.Errorf("Storing %d metric(s) of partition %q failed: %v", len(chunk), key, err)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("uploading %d object(s) failed", failed)
+	}
+
+	return nil
+}
+
+// split divides the metrics of a partition into chunks not exceeding the
+// maximum object size
+func (o *ObjectStorage) split(metrics []telegraf.Metric) [][]telegraf.Metric {
+	var chunks [][]telegraf.Metric
+	var start int
+	var size int64
+	for i, m := range metrics {
+		s := estimateSize(m)
+		if i > start && size+s > int64(o.MaxSize) {
+			chunks = append(chunks, metrics[start:i])
+			start, size = i, 0
+		}
+		size += s
+	}
+	return append(chunks, metrics[start:])
+}
+
+// store encodes and uploads the metrics as the object with the given index
+// of the partition within the batch
+func (o *ObjectStorage) store(key, batch string, index int, metrics []telegraf.Metric) error {
+	data, err := o.encoder.encode(metrics)
+	if err != nil {
+		// Encoding will not succeed on retry so drop the metrics
+		o.Log.Errorf("Encoding %d metric(s) of partition %q failed, dropping them: %v", len(metrics), key, err)
+		return nil
+	}
+
+	name := path.Join(key, fmt.Sprintf("part-%s-%s-%04d%s",
+		metrics[0].Time().UTC().Format("20060102T150405Z"),
+		batch,
+		index,
+		o.encoder.extension(),
+	))
+	if err := o.upload(name, data); err != nil {
+		return fmt.Errorf("uploading object %q failed: %w", name, err)
+	}
+	o.Log.Debugf("Uploaded %d metric(s) to %q", len(metrics), name)
+
+	return nil
+}
+
+// upload writes the object atomically so readers never see partial data
+func (o *ObjectStorage) upload(name string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(o.UploadTimeout))
+	defer cancel()
+
+	// Object stores only expose objects after completing the upload, for
+	// filesystem-like backends upload to a hidden name and move the object
+	features := o.root.Features()
+	if !features.PartialUploads || features.Move == nil {
+		info := object.NewStaticObjectInfo(name, time.Now(), int64(len(data)), true, nil, o.root)
+		_, err := o.root.Put(ctx, bytes.NewReader(data), info)
+		return err
+	}
+
+	tmpname := path.Join(path.Dir(name), "."+path.Base(name)+".partial")
+	info := object.NewStaticObjectInfo(tmpname, time.Now(), int64(len(data)), true, nil, o.root)
+	obj, err := o.root.Put(ctx, bytes.NewReader(data), info)
+	if err != nil {
+		return err
+	}
+	if _, err := features.Move(ctx, obj, name); err != nil {
+		if rerr := obj.Remove(ctx); rerr != nil {
+			o.Log.Errorf("Removing temporary object %q failed: %v", tmpname, rerr)
+		}
+		return fmt.Errorf("moving object failed: %w", err)
+	}
+	return nil
+}
+
+// batchID identifies a batch by its first metric. Telegraf retries a batch
+// starting with the same metric, only appending metrics not sent before, so
+// the chunks of the partitions keep their names and contents on retry.
+func batchID(m telegraf.Metric) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d %d %v", m.HashID(), m.Time().UnixNano(), m.Fields())
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// cleanPartition prevents partitions from escaping the root of the remote
+func cleanPartition(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
+
+// estimateSize approximates the uncompressed size of the metric's data
+func estimateSize(m telegraf.Metric) int64 {
+	size := len(m.Name()) + 8
+	for _, tag := range m.TagList() {
+		size += len(tag.Key) + len(tag.Value)
+	}
+	for _, field := range m.FieldList() {
+		size += len(field.Key)
+		if v, ok := field.Value.(string); ok {
+			size += len(v)
+		} else {
+			size += 8
+		}
+	}
+	return int64(size)
+}
+
+func init() {
+	outputs.Add("object_storage", func() telegraf.Output {
+		return &ObjectStorage{
+			Partition:     defaultPartition,
+			Format:        "parquet",
+			MaxSize:       config.Size(64 * 1024 * 1024),
+			UploadTimeout: config.Duration(5 * time.Minute),
+		}
+	})
+}
@@ -0,0 +1,314 @@
+package object_storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/apache/arrow/go/v18/parquet"
+	"github.com/apache/arrow/go/v18/parquet/pqarrow"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newPlugin(dir string) *ObjectStorage {
+	plugin := outputs.Outputs["object_storage"]().(*ObjectStorage)
+	plugin.Remote = config.NewSecret([]byte("local:" + dir))
+	plugin.Log = &testutil.Logger{}
+	return plugin
+}
+
+// listObjects returns the slash-separated paths of all files below the directory
+func listObjects(t *testing.T, dir string) []string {
+	var objects []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, filepath.ToSlash(rel))
+		return nil
+	})
+	require.NoError(t, err)
+	return objects
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(o *ObjectStorage)
+		expected string
+	}{
+		{
+			name:     "invalid format",
+			modify:   func(o *ObjectStorage) { o.Format = "csv" },
+			expected: `invalid format "csv"`,
+		},
+		{
+			name:     "invalid parquet compression",
+			modify:   func(o *ObjectStorage) { o.Compression = "lz4" },
+			expected: `invalid compression "lz4" for parquet`,
+		},
+		{
+			name: "invalid ndjson compression",
+			modify: func(o *ObjectStorage) {
+				o.Format = "ndjson"
+				o.Compression = "snappy"
+			},
+			expected: `invalid compression "snappy" for ndjson`,
+		},
+		{
+			name:     "no max size",
+			modify:   func(o *ObjectStorage) { o.MaxSize = 0 },
+			expected: "'max_size' must be positive",
+		},
+		{
+			name:     "invalid partition",
+			modify:   func(o *ObjectStorage) { o.Partition = "{{.Name" },
+			expected: "parsing partition template failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newPlugin(t.TempDir())
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestParquetPartitions(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	plugin := newPlugin(tmpdir)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 1.5, "count": int64(1)},
+			time.Date(2026, 10, 17, 13, 5, 0, 0, time.UTC),
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "b", "zone": "eu"},
+			map[string]interface{}{"usage": 2.5, "count": "invalid"},
+			time.Date(2026, 10, 17, 13, 10, 0, 0, time.UTC),
+		),
+		metric.New(
+			"mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"free": uint64(42)},
+			time.Date(2026, 10, 17, 14, 0, 0, 0, time.UTC),
+		),
+	}
+	require.NoError(t, plugin.Write(input))
+
+	objects := listObjects(t, tmpdir)
+	require.Len(t, objects, 2)
+	require.Regexp(t, `^measurement=cpu/date=2026-10-17/hour=13/part-20261017T130500Z-[0-9a-f]{32}-0000\.parquet$`, objects[0])
+	require.Regexp(t, `^measurement=mem/date=2026-10-17/hour=14/part-20261017T140000Z-[0-9a-f]{32}-0000\.parquet$`, objects[1])
+
+	data, err := os.ReadFile(filepath.Join(tmpdir, objects[0]))
+	require.NoError(t, err)
+	table, err := pqarrow.ReadTable(
+		context.Background(),
+		bytes.NewReader(data),
+		parquet.NewReaderProperties(memory.DefaultAllocator),
+		pqarrow.ArrowReadProperties{},
+		memory.DefaultAllocator,
+	)
+	require.NoError(t, err)
+	defer table.Release()
+
+	names := make([]string, 0, table.Schema().NumFields())
+	for _, f := range table.Schema().Fields() {
+		names = append(names, f.Name)
+	}
+	require.Equal(t, []string{"timestamp", "name", "host", "zone", "count", "usage"}, names)
+	require.EqualValues(t, 2, table.NumRows())
+
+	// The mismatching type of the second count value is stored as null
+	count := table.Column(4).Data().Chunk(0).(*array.Int64)
+	require.Equal(t, int64(1), count.Value(0))
+	require.True(t, count.IsNull(1))
+
+	zone := table.Column(3).Data().Chunk(0).(*array.String)
+	require.True(t, zone.IsNull(0))
+	require.Equal(t, "eu", zone.Value(1))
+}
+
+func TestNDJSONMaxSize(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	plugin := newPlugin(tmpdir)
+	plugin.Format = "ndjson"
+	plugin.Partition = `{{.Tag "tenant"}}`
+	plugin.MaxSize = 1
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		metric.New(
+			"test",
+			map[string]string{"tenant": "../../escape"},
+			map[string]interface{}{"value": int64(42)},
+			time.Unix(1719410485, 0),
+		),
+		metric.New(
+			"test",
+			map[string]string{"tenant": "../../escape"},
+			map[string]interface{}{"value": int64(43)},
+			time.Unix(1719410486, 0),
+		),
+	}
+
+	// Each metric exceeds the size limit and results in a separate object
+	require.NoError(t, plugin.Write(input))
+	objects := listObjects(t, tmpdir)
+	require.Len(t, objects, 2)
+	for _, o := range objects {
+		require.True(t, strings.HasPrefix(o, "escape/part-"), o)
+		require.True(t, strings.HasSuffix(o, ".ndjson.gz"), o)
+	}
+
+	f, err := os.Open(filepath.Join(tmpdir, objects[0]))
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	actual, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	expected := `{"fields":{"value":42},"name":"test","tags":{"tenant":"../../escape"},"timestamp":1719410485000000000}` + "\n"
+	require.Equal(t, expected, string(actual))
+}
+
+func TestRetryReplacesObjects(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	plugin := newPlugin(tmpdir)
+	plugin.Format = "ndjson"
+	plugin.Compression = "none"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+
+	// Writing the same batch again must not duplicate the metrics
+	require.NoError(t, plugin.Write(input))
+	require.NoError(t, plugin.Write(input))
+
+	objects := listObjects(t, tmpdir)
+	require.Len(t, objects, 1)
+	require.True(t, strings.HasSuffix(objects[0], ".ndjson"), objects[0])
+
+	// No temporary objects are left behind
+	require.False(t, strings.HasPrefix(filepath.Base(objects[0]), "."), objects[0])
+
+	// A retry of the batch with additional metrics replaces the object
+	input = append(input,
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(1, 0)),
+	)
+	require.NoError(t, plugin.Write(input))
+
+	objects = listObjects(t, tmpdir)
+	require.Len(t, objects, 1)
+	data, err := os.ReadFile(filepath.Join(tmpdir, objects[0]))
+	require.NoError(t, err)
+	require.Equal(t, 2, bytes.Count(data, []byte("\n")))
+
+	// A different batch creates a new object
+	require.NoError(t, plugin.Write(input[1:]))
+	require.Len(t, listObjects(t, tmpdir), 2)
+}
+
+func TestUploadFail(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	plugin := newPlugin(tmpdir)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Make uploads fail by replacing the root with a file
+	require.NoError(t, os.RemoveAll(tmpdir))
+	require.NoError(t, os.WriteFile(tmpdir, []byte{}, 0600))
+	defer os.Remove(tmpdir)
+
+	// The error makes Telegraf keep the metrics for a retry
+	input := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+	require.ErrorContains(t, plugin.Write(input), "uploading 1 object(s) failed")
+}
+
+func TestParquetColumnCollisions(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	plugin := newPlugin(tmpdir)
+	plugin.Partition = "data"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		metric.New(
+			"test",
+			map[string]string{"name": "a", "host": "b"},
+			map[string]interface{}{"host": 1.0, "timestamp": int64(2), "value": 3.0},
+			time.Unix(0, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(input))
+
+	objects := listObjects(t, tmpdir)
+	require.Len(t, objects, 1)
+
+	data, err := os.ReadFile(filepath.Join(tmpdir, objects[0]))
+	require.NoError(t, err)
+	table, err := pqarrow.ReadTable(
+		context.Background(),
+		bytes.NewReader(data),
+		parquet.NewReaderProperties(memory.DefaultAllocator),
+		pqarrow.ArrowReadProperties{},
+		memory.DefaultAllocator,
+	)
+	require.NoError(t, err)
+	defer table.Release()
+
+	names := make([]string, 0, table.Schema().NumFields())
+	for _, f := range table.Schema().Fields() {
+		names = append(names, f.Name)
+	}
+	require.Equal(t, []string{"timestamp", "name", "host", "tag_name", "field_host", "field_timestamp", "value"}, names)
+
+	require.Equal(t, "a", table.Column(3).Data().Chunk(0).(*array.String).Value(0))
+	require.InDelta(t, 1.0, table.Column(4).Data().Chunk(0).(*array.Float64).Value(0), 0)
+	require.Equal(t, int64(2), table.Column(5).Data().Chunk(0).(*array.Int64).Value(0))
+}
@@ -0,0 +1,426 @@
+package prometheus_remote_write
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
+)
+
+type sample struct {
+	timestamp int64
+	value     float64
+}
+
+type histogramSample struct {
+	timestamp int64
+	histogram *histogram.Histogram
+}
+
+// series is a Prometheus time series with its samples ordered by time
+type series struct {
+	labels     labels.Labels
+	valueType  telegraf.ValueType
+	samples    []sample
+	histograms []histogramSample
+}
+
+// size returns the number of samples and histograms of the series
+func (s *series) size() int {
+	return len(s.samples) + len(s.histograms)
+}
+
+// newest returns the timestamp of the newest sample or histogram
+func (s *series) newest() int64 {
+	var newest int64 = math.MinInt64
+	if n := len(s.samples); n > 0 {
+		newest = s.samples[n-1].timestamp
+	}
+	if n := len(s.histograms); n > 0 {
+		newest = max(newest, s.histograms[n-1].timestamp)
+	}
+	return newest
+}
+
+// skipUntil removes the samples and histograms not newer than the timestamp
+func (s *series) skipUntil(timestamp int64) {
+	s.samples = slices.DeleteFunc(s.samples, func(v sample) bool { return v.timestamp <= timestamp })
+	s.histograms = slices.DeleteFunc(s.histograms, func(v histogramSample) bool { return v.timestamp <= timestamp })
+}
+
+// nativeHistogram collects the classic buckets of a histogram at one point
+// in time for converting them to a native histogram
+type nativeHistogram struct {
+	labels    labels.Labels
+	timestamp int64
+	buckets   map[float64]uint64
+	sum       float64
+	count     uint64
+	hasCount  bool
+}
+
+type converter struct {
+	nativeHistograms bool
+	schema           int32
+	log              telegraf.Logger
+
+	entries map[string]*series
+	natives map[string]*nativeHistogram
+	lastErr error
+}
+
+// convert groups the metrics into series, samples of the same series with
+// the same timestamp are deduplicated keeping the last one
+func (c *converter) convert(metrics []telegraf.Metric) []*series {
+	c.entries = make(map[string]*series)
+	c.natives = make(map[string]*nativeHistogram)
+	c.lastErr = nil
+
+	for _, m := range metrics {
+		c.add(m)
+	}
+
+	for _, n := range c.natives {
+		// A histogram without any observations, e.g. with only the sum being
+		// present, cannot be represented
+		if len(n.buckets) == 0 && !n.hasCount {
+			c.keepErr("converting histogram %q to native histogram failed: no buckets or count", n.labels.Get(labels.MetricName))
+			continue
+		}
+		h, err := n.histogram(c.schema)
+		if err != nil {
+			c.keepErr("converting histogram %q to native histogram failed: %w", n.labels.Get(labels.MetricName), err)
+			continue
+		}
+		s := c.series(n.labels, telegraf.Histogram)
+		s.histograms = append(s.histograms, histogramSample{timestamp: n.timestamp, histogram: h})
+	}
+
+	if c.lastErr != nil {
+		// Only log the last error as logging all of them might be too verbose
+		c.log.Errorf("Some series were dropped, %d series left to send; last recorded error: %v", len(c.entries), c.lastErr)
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for k := range c.entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]*series, 0, len(keys))
+	for _, k := range keys {
+		s := c.entries[k]
+		s.samples = dedupSamples(s.samples)
+		s.histograms = dedupHistograms(s.histograms)
+		result = append(result, s)
+	}
+	return result
+}
+
+func (c *converter) add(m telegraf.Metric) {
+	ts := m.Time().UnixMilli()
+	base := commonLabels(m)
+
+	for _, field := range m.FieldList() {
+		name, ok := prometheus.SanitizeMetricName(prometheus.MetricName(m.Name(), field.Key, m.Type()))
+		if !ok {
+			c.keepErr("failed to parse metric name %q", name)
+			continue
+		}
+
+		switch m.Type() {
+		case telegraf.Histogram:
+			c.addHistogram(m, name, field, base, ts)
+		case telegraf.Summary:
+			c.addSummary(m, name, field, base, ts)
+		default:
+			value, ok := prometheus.SampleValue(field.Value)
+			if !ok {
+				c.keepErr("failed to parse %q: bad sample value %#v", name, field.Value)
+				continue
+			}
+			c.addSample(withName(base, name), m.Type(), ts, value)
+		}
+	}
+}
+
+func (c *converter) addHistogram(m telegraf.Metric, name string, field *telegraf.Field, base []labels.Label, ts int64) {
+	switch {
+	case strings.HasSuffix(field.Key, "_bucket"):
+		le, ok := m.GetTag("le")
+		if !ok {
+			c.keepErr("failed to parse %q: can't find `le` label", name)
+			return
+		}
+		bound, err := strconv.ParseFloat(le, 64)
+		if err != nil {
+			c.keepErr("failed to parse %q: can't parse %q value: %w", name, le, err)
+			return
+		}
+		count, ok := prometheus.SampleCount(field.Value)
+		if !ok {
+			c.keepErr("failed to parse %q: bad sample value %#v", name, field.Value)
+			return
+		}
+		if c.nativeHistograms {
+			c.native(base, name, ts).buckets[bound] = count
+			return
+		}
+		c.addSample(withName(base, name+"_bucket", labels.Label{Name: "le", Value: fmt.Sprint(bound)}), telegraf.Histogram, ts, float64(count))
+	case strings.HasSuffix(field.Key, "_sum"):
+		sum, ok := prometheus.SampleSum(field.Value)
+		if !ok {
+			c.keepErr("failed to parse %q: bad sample value %#v", name, field.Value)
+			return
+		}
+		if c.nativeHistograms {
+			c.native(base, name, ts).sum = sum
+			return
+		}
+		c.addSample(withName(base, name+"_sum"), telegraf.Histogram, ts, sum)
+	case strings.HasSuffix(field.Key, "_count"):
+		count, ok := prometheus.SampleCount(field.Value)
+		if !ok {
+			c.keepErr("failed to parse %q: bad sample value %#v", name, field.Value)
+			return
+		}
+		if c.nativeHistograms {
+			native := c.native(base, name, ts)
+			native.count = count
+			native.hasCount = true
+			return
+		}
+		c.addSample(withName(base, name+"_count"), telegraf.Histogram, ts, float64(count))
+		c.addSample(withName(base, name+"_bucket", labels.Label{Name: "le", Value: "+Inf"}), telegraf.Histogram, ts, float64(count))
+	default:
+		c.keepErr("failed to parse %q: series %q should have `_count`, `_sum` or `_bucket` suffix", name, field.Key)
+	}
+}
+
+// native returns the native histogram of the series at the given timestamp
+func (c *converter) native(base []labels.Label, name string, ts int64) *nativeHistogram {
+	lbls := withName(base, name)
+	key := lbls.String() + "@" + strconv.FormatInt(ts, 10)
+	native, found := c.natives[key]
+	if !found {
+		native = &nativeHistogram{labels: lbls, timestamp: ts, buckets: make(map[float64]uint64)}
+		c.natives[key] = native
+	}
+	return native
+}
+
+func (c *converter) addSummary(m telegraf.Metric, name string, field *telegraf.Field, base []labels.Label, ts int64) {
+	switch {
+	case strings.HasSuffix(field.Key, "_sum"):
+		sum, ok := prometheus.SampleSum(field.Value)
+		if !ok {
+			c.keepErr("failed to parse %q: bad sample value %#v", name, field.Value)
+			return
+		}
+		c.addSample(withName(base, name+"_sum"), telegraf.Summary, ts, sum)
+	case strings.HasSuffix(field.Key, "_count"):
+		count, ok := prometheus.SampleCount(field.Value)
+		if !ok {
+			c.keepErr("failed to parse %q: bad sample value %#v", name, field.Value)
+			return
+		}
+		c.addSample(withName(base, name+"_count"), telegraf.Summary, ts, float64(count))
+	default:
+		quantileTag, ok := m.GetTag("quantile")
+		if !ok {
+			c.keepErr("failed to parse %q: can't find `quantile` label", name)
+			return
+		}
+		quantile, err := strconv.ParseFloat(quantileTag, 64)
+		if err != nil {
+			c.keepErr("failed to parse %q: can't parse %q value: %w", name, quantileTag, err)
+			return
+		}
+		value, ok := prometheus.SampleValue(field.Value)
+		if !ok {
+			c.keepErr("failed to parse %q: bad sample value %#v", name, field.Value)
+			return
+		}
+		c.addSample(withName(base, name, labels.Label{Name: "quantile", Value: fmt.Sprint(quantile)}), telegraf.Summary, ts, value)
+	}
+}
+
+func (c *converter) addSample(lbls labels.Labels, valueType telegraf.ValueType, ts int64, value float64) {
+	s := c.series(lbls, valueType)
+	s.samples = append(s.samples, sample{timestamp: ts, value: value})
+}
+
+func (c *converter) series(lbls labels.Labels, valueType telegraf.ValueType) *series {
+	key := lbls.String()
+	s, found := c.entries[key]
+	if !found {
+		s = &series{labels: lbls, valueType: valueType}
+		c.entries[key] = s
+	}
+	return s
+}
+
+// keepErr traces every error and keeps the last one for logging it later
+func (c *converter) keepErr(format string, a ...any) {
+	c.lastErr = fmt.Errorf(format, a...)
+	c.log.Trace(c.lastErr)
+}
+
+// commonLabels returns the labels of the metric's tags ignoring the special
+// tags of histograms and summaries
+func commonLabels(m telegraf.Metric) []labels.Label {
+	lbls := make([]labels.Label, 0, len(m.TagList())+2)
+	for _, tag := range m.TagList() {
+		switch {
+		case m.Type() == telegraf.Histogram && tag.Key == "le":
+			continue
+		case m.Type() == telegraf.Summary && tag.Key == "quantile":
+			continue
+		case tag.Value == "":
+			continue
+		}
+
+		name, ok := prometheus.SanitizeLabelName(tag.Key)
+		if !ok {
+			continue
+		}
+		lbls = append(lbls, labels.Label{Name: name, Value: tag.Value})
+	}
+	return lbls
+}
+
+// withName returns the sorted labels including the metric name and the extra labels
+func withName(base []labels.Label, name string, extra ...labels.Label) labels.Labels {
+	lbls := make([]labels.Label, 0, len(base)+len(extra)+1)
+	lbls = append(lbls, base...)
+	lbls = append(lbls, extra...)
+	lbls = append(lbls, labels.Label{Name: labels.MetricName, Value: name})
+	return labels.New(lbls...)
+}
+
+func dedupSamples(samples []sample) []sample {
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].timestamp < samples[j].timestamp })
+	result := samples[:0]
+	for _, s := range samples {
+		if n := len(result); n > 0 && result[n-1].timestamp == s.timestamp {
+			result[n-1] = s
+			continue
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func dedupHistograms(histograms []histogramSample) []histogramSample {
+	sort.SliceStable(histograms, func(i, j int) bool { return histograms[i].timestamp < histograms[j].timestamp })
+	result := histograms[:0]
+	for _, h := range histograms {
+		if n := len(result); n > 0 && result[n-1].timestamp == h.timestamp {
+			result[n-1] = h
+			continue
+		}
+		result = append(result, h)
+	}
+	return result
+}
+
+// histogram converts the classic buckets to an exponential native histogram
+// of the given schema. The observations of a classic bucket are accounted to
+// the exponential bucket containing the upper bound of the classic bucket,
+// observations of buckets with a non-positive bound to the zero bucket and
+// observations above the highest finite bound to the highest bucket.
+func (n *nativeHistogram) histogram(schema int32) (*histogram.Histogram, error) {
+	bounds := make([]float64, 0, len(n.buckets))
+	for b := range n.buckets {
+		bounds = append(bounds, b)
+	}
+	sort.Float64s(bounds)
+
+	h := &histogram.Histogram{
+		Schema: schema,
+		Sum:    n.sum,
+	}
+	counts := make(map[int32]uint64, len(bounds))
+	var cumulative uint64
+	var highest int32
+	var finite bool
+	for _, bound := range bounds {
+		count := n.buckets[bound]
+		if count < cumulative {
+			return nil, fmt.Errorf("bucket %v is not cumulative", bound)
+		}
+		delta := count - cumulative
+		cumulative = count
+
+		switch {
+		case math.IsInf(bound, 1):
+			if delta > 0 && !finite {
+				return nil, errors.New("no finite bucket for observations")
+			}
+			counts[highest] += delta
+		case bound <= 0:
+			h.ZeroCount += delta
+		default:
+			idx := bucketIndex(bound, schema)
+			counts[idx] += delta
+			highest = idx
+			finite = true
+		}
+	}
+
+	// Observations not covered by any bucket due to a missing +Inf bucket
+	h.Count = cumulative
+	if n.hasCount && n.count > cumulative {
+		if !finite {
+			return nil, errors.New("no finite bucket for observations")
+		}
+		counts[highest] += n.count - cumulative
+		h.Count = n.count
+	}
+
+	indices := make([]int32, 0, len(counts))
+	for idx, count := range counts {
+		if count > 0 {
+			indices = append(indices, idx)
+		}
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	// Encode the buckets as spans of consecutive buckets with delta encoded counts
+	var previous int64
+	for i, idx := range indices {
+		if i == 0 || idx != indices[i-1]+1 {
+			offset := idx
+			if i > 0 {
+				offset = idx - indices[i-1] - 1
+			}
+			h.PositiveSpans = append(h.PositiveSpans, histogram.Span{Offset: offset, Length: 0})
+		}
+		h.PositiveSpans[len(h.PositiveSpans)-1].Length++
+
+		count := int64(counts[idx])
+		h.PositiveBuckets = append(h.PositiveBuckets, count-previous)
+		previous = count
+	}
+
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// bucketIndex returns the index of the exponential bucket containing the
+// positive value, bucket i covers the range (base^(i-1), base^i] with
+// base = 2^(2^-schema)
+func bucketIndex(v float64, schema int32) int32 {
+	return int32(math.Ceil(math.Ldexp(math.Log2(v), int(schema))))
+}
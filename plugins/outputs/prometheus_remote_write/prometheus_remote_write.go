@@ -0,0 +1,472 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package prometheus_remote_write
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common_http "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+const maxErrMsgLen = 1024
+
+const (
+	contentTypeV1 = "application/x-protobuf"
+	contentTypeV2 = "application/x-protobuf;proto=io.prometheus.write.v2.Request"
+)
+
+type PrometheusRemoteWrite struct {
+	URL                   string            `toml:"url"`
+	ProtocolVersion       string            `toml:"protocol_version"`
+	Username              config.Secret     `toml:"username"`
+	Password              config.Secret     `toml:"password"`
+	Headers               map[string]string `toml:"headers"`
+	Shards                int               `toml:"shards"`
+	MaxSamplesPerSend     int               `toml:"max_samples_per_send"`
+	MaxRetries            int               `toml:"max_retries"`
+	MinBackoff            config.Duration   `toml:"min_backoff"`
+	MaxBackoff            config.Duration   `toml:"max_backoff"`
+	RetryOnRateLimit      bool              `toml:"retry_on_rate_limit"`
+	NativeHistograms      bool              `toml:"native_histograms"`
+	NativeHistogramSchema int32             `toml:"native_histogram_schema"`
+	Log                   telegraf.Logger   `toml:"-"`
+	common_http.HTTPClientConfig
+
+	client *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Newest timestamp of the series delivered by a partially failed write
+	// to skip the samples already delivered when retrying the batch
+	delivered     map[uint64]int64
+	deliveredLock sync.Mutex
+}
+
+// requestError is a failed request, the request is retried if the error
+// is recoverable
+type requestError struct {
+	statusCode  int
+	message     string
+	recoverable bool
+	retryAfter  time.Duration
+}
+
+func (e *requestError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("received status %d", e.statusCode)
+	}
+	return fmt.Sprintf("received status %d: %s", e.statusCode, e.message)
+}
+
+func (*PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Init() error {
+	if p.URL == "" {
+		return errors.New("'url' required")
+	}
+
+	switch p.ProtocolVersion {
+	case "":
+		p.ProtocolVersion = "1.0"
+	case "1.0", "2.0":
+	default:
+		return fmt.Errorf("invalid protocol version %q", p.ProtocolVersion)
+	}
+
+	if p.Shards < 1 {
+		return errors.New("'shards' must be positive")
+	}
+	if p.MaxSamplesPerSend < 1 {
+		return errors.New("'max_samples_per_send' must be positive")
+	}
+	if p.MaxRetries < 0 {
+		return errors.New("'max_retries' must not be negative")
+	}
+	if p.MinBackoff <= 0 || p.MaxBackoff < p.MinBackoff {
+		return errors.New("'min_backoff' must be positive and not exceed 'max_backoff'")
+	}
+
+	// Schemas supported for exponential native histograms
+	if p.NativeHistogramSchema < -4 || p.NativeHistogramSchema > 8 {
+		return fmt.Errorf("native histogram schema %d out of range [-4, 8]", p.NativeHistogramSchema)
+	}
+
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Connect() error {
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	client, err := p.HTTPClientConfig.CreateClient(p.ctx, p.Log)
+	if err != nil {
+		p.cancel()
+		return err
+	}
+	p.client = client
+
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Close() error {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Write(metrics []telegraf.Metric) error {
+	c := &converter{
+		nativeHistograms: p.NativeHistograms,
+		schema:           p.NativeHistogramSchema,
+		log:              p.Log,
+	}
+	entries := c.convert(metrics)
+	if len(p.delivered) > 0 {
+		entries = p.skipDelivered(entries)
+	}
+	if len(entries) == 0 {
+		p.delivered = nil
+		return nil
+	}
+
+	// Distribute the series to the shards by their labels so the samples
+	// of a series are always sent in order by the same shard
+	shards := make([][]*series, p.Shards)
+	for _, s := range entries {
+		idx := s.labels.Hash() % uint64(p.Shards)
+		shards[idx] = append(shards[idx], s)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, p.Shards)
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, shard []*series) {
+			defer wg.Done()
+			errs[i] = p.writeShard(shard)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	p.delivered = nil
+
+	return nil
+}
+
+// skipDelivered removes the samples already delivered by a previous attempt
+// of writing the batch, as the receiver rejects them as duplicates
+func (p *PrometheusRemoteWrite) skipDelivered(entries []*series) []*series {
+	remaining := entries[:0]
+	for _, s := range entries {
+		newest, found := p.delivered[s.labels.Hash()]
+		if found {
+			s.skipUntil(newest)
+		}
+		if s.size() > 0 {
+			remaining = append(remaining, s)
+		}
+	}
+	return remaining
+}
+
+// markDelivered records the newest timestamp of the delivered series
+func (p *PrometheusRemoteWrite) markDelivered(entries []*series) {
+	p.deliveredLock.Lock()
+	defer p.deliveredLock.Unlock()
+
+	if p.delivered == nil {
+		p.delivered = make(map[uint64]int64)
+	}
+	for _, s := range entries {
+		hash := s.labels.Hash()
+		if newest, found := p.delivered[hash]; !found || s.newest() > newest {
+			p.delivered[hash] = s.newest()
+		}
+	}
+}
+
+// writeShard sends the series in requests of limited size, remaining
+// requests are not sent if a request fails to keep the order of the samples
+func (p *PrometheusRemoteWrite) writeShard(entries []*series) error {
+	for len(entries) > 0 {
+		var n, size int
+		for n < len(entries) && (n == 0 || size+entries[n].size() <= p.MaxSamplesPerSend) {
+			size += entries[n].size()
+			n++
+		}
+		batch := entries[:n]
+		entries = entries[n:]
+
+		var body []byte
+		var err error
+		if p.ProtocolVersion == "2.0" {
+			body, err = encodeV2(batch)
+		} else {
+			body, err = encodeV1(batch)
+		}
+		if err != nil {
+			return fmt.Errorf("encoding request failed: %w", err)
+		}
+
+		if err := p.sendWithRetry(body, size); err != nil {
+			var reqErr *requestError
+			if errors.As(err, &reqErr) && !reqErr.recoverable {
+				// Retrying will never succeed so drop the samples
+				p.Log.Errorf("Dropping %d sample(s) rejected by the receiver: %v", size, err)
+				p.markDelivered(batch)
+				continue
+			}
+			return err
+		}
+		p.markDelivered(batch)
+	}
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) sendWithRetry(body []byte, size int) error {
+	backoff := time.Duration(p.MinBackoff)
+	for attempt := 0; ; attempt++ {
+		err := p.send(body, size)
+		if err == nil {
+			return nil
+		}
+
+		var reqErr *requestError
+		if errors.As(err, &reqErr) && !reqErr.recoverable {
+			return err
+		}
+		if attempt >= p.MaxRetries {
+			return err
+		}
+
+		// Honor the delay requested by the receiver but leave the samples to
+		// Telegraf's buffer if the delay exceeds the maximum backoff
+		wait := backoff
+		if reqErr != nil && reqErr.retryAfter > 0 {
+			if reqErr.retryAfter > time.Duration(p.MaxBackoff) {
+				return fmt.Errorf("%w; retry requested after %s", err, reqErr.retryAfter)
+			}
+			wait = reqErr.retryAfter
+		}
+		p.Log.Debugf("Retrying request in %s after error: %v", wait, err)
+
+		select {
+		case <-p.ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff = min(2*backoff, time.Duration(p.MaxBackoff))
+	}
+}
+
+func (p *PrometheusRemoteWrite) send(body []byte, size int) error {
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Content-Encoding", "snappy")
+	if p.ProtocolVersion == "2.0" {
+		req.Header.Set("Content-Type", contentTypeV2)
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "2.0.0")
+	} else {
+		req.Header.Set("Content-Type", contentTypeV1)
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	}
+	for k, v := range p.Headers {
+		if strings.EqualFold(k, "host") {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+
+	if !p.Username.Empty() || !p.Password.Empty() {
+		username, err := p.Username.Get()
+		if err != nil {
+			return fmt.Errorf("getting username failed: %w", err)
+		}
+		password, err := p.Password.Get()
+		if err != nil {
+			username.Destroy()
+			return fmt.Errorf("getting password failed: %w", err)
+		}
+		req.SetBasicAuth(username.String(), password.String())
+		username.Destroy()
+		password.Destroy()
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		internal.OnClientError(p.client, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		// Receivers of version 2.0 report the number of written samples
+		if p.ProtocolVersion == "2.0" {
+			samples := resp.Header.Get("X-Prometheus-Remote-Write-Samples-Written")
+			histograms := resp.Header.Get("X-Prometheus-Remote-Write-Histograms-Written")
+			if samples != "" || histograms != "" {
+				s, _ := strconv.Atoi(samples)
+				h, _ := strconv.Atoi(histograms)
+				if s+h < size {
+					p.Log.Warnf("Receiver only wrote %d of %d sample(s)", s+h, size)
+				}
+			}
+		}
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+
+	msg, err := io.ReadAll(io.LimitReader(resp.Body, maxErrMsgLen))
+	if err != nil {
+		return fmt.Errorf("reading response failed: %w", err)
+	}
+	reqErr := &requestError{
+		statusCode: resp.StatusCode,
+		message:    strings.TrimSpace(string(msg)),
+	}
+
+	// Only server errors and rate limiting are recoverable, other client
+	// errors must not be retried
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		reqErr.recoverable = p.RetryOnRateLimit
+	case resp.StatusCode >= 500:
+		reqErr.recoverable = true
+	}
+	if reqErr.recoverable {
+		reqErr.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	return reqErr
+}
+
+// parseRetryAfter supports both the delay in seconds and the HTTP date format
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func encodeV1(entries []*series) ([]byte, error) {
+	req := &prompb.WriteRequest{
+		Timeseries: make([]prompb.TimeSeries, 0, len(entries)),
+	}
+	for _, s := range entries {
+		ts := prompb.TimeSeries{
+			Labels: prompb.FromLabels(s.labels, nil),
+		}
+		for _, smpl := range s.samples {
+			ts.Samples = append(ts.Samples, prompb.Sample{Timestamp: smpl.timestamp, Value: smpl.value})
+		}
+		for _, h := range s.histograms {
+			ts.Histograms = append(ts.Histograms, prompb.FromIntHistogram(h.timestamp, h.histogram))
+		}
+		req.Timeseries = append(req.Timeseries, ts)
+	}
+
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, data), nil
+}
+
+// encodeV2 creates a remote-write 2.0 request interning all label names and
+// values in the symbols table
+func encodeV2(entries []*series) ([]byte, error) {
+	symbols := writev2.NewSymbolTable()
+	req := &writev2.Request{
+		Timeseries: make([]writev2.TimeSeries, 0, len(entries)),
+	}
+	for _, s := range entries {
+		ts := writev2.TimeSeries{
+			LabelsRefs: symbols.SymbolizeLabels(s.labels, nil),
+			Metadata:   writev2.Metadata{Type: metadataType(s.valueType)},
+		}
+		for _, smpl := range s.samples {
+			ts.Samples = append(ts.Samples, writev2.Sample{Timestamp: smpl.timestamp, Value: smpl.value})
+		}
+		for _, h := range s.histograms {
+			ts.Histograms = append(ts.Histograms, writev2.FromIntHistogram(h.timestamp, h.histogram))
+		}
+		req.Timeseries = append(req.Timeseries, ts)
+	}
+	req.Symbols = symbols.Symbols()
+
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, data), nil
+}
+
+func metadataType(valueType telegraf.ValueType) writev2.Metadata_MetricType {
+	switch valueType {
+	case telegraf.Counter:
+		return writev2.Metadata_METRIC_TYPE_COUNTER
+	case telegraf.Gauge:
+		return writev2.Metadata_METRIC_TYPE_GAUGE
+	case telegraf.Histogram:
+		return writev2.Metadata_METRIC_TYPE_HISTOGRAM
+	case telegraf.Summary:
+		return writev2.Metadata_METRIC_TYPE_SUMMARY
+	}
+	return writev2.Metadata_METRIC_TYPE_UNSPECIFIED
+}
+
+func init() {
+	outputs.Add("prometheus_remote_write", func() telegraf.Output {
+		return &PrometheusRemoteWrite{
+			ProtocolVersion:       "1.0",
+			Shards:                4,
+			MaxSamplesPerSend:     2000,
+			MaxRetries:            3,
+			MinBackoff:            config.Duration(30 * time.Millisecond),
+			MaxBackoff:            config.Duration(5 * time.Second),
+			RetryOnRateLimit:      true,
+			NativeHistogramSchema: 3,
+		}
+	})
+}
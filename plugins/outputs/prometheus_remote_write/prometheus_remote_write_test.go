@@ -0,0 +1,560 @@
+package prometheus_remote_write
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newPlugin(u string) *PrometheusRemoteWrite {
+	plugin := outputs.Outputs["prometheus_remote_write"]().(*PrometheusRemoteWrite)
+	plugin.URL = u
+	plugin.MinBackoff = config.Duration(time.Millisecond)
+	plugin.MaxBackoff = config.Duration(10 * time.Millisecond)
+	plugin.Log = testutil.Logger{}
+	return plugin
+}
+
+// receiver decodes the remote-write requests and responds with the given
+// status codes in order, succeeding once all codes are used. Version 1
+// requests matching the reject function fail with a server error.
+type receiver struct {
+	status  []int
+	reject  func(*prompb.WriteRequest) bool
+	headers []http.Header
+	v1      []*prompb.WriteRequest
+	v2      []*writev2.Request
+	sync.Mutex
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.headers = append(r.headers, req.Header)
+	if len(r.status) > 0 {
+		status := r.status[0]
+		r.status = r.status[1:]
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(status)
+		return
+	}
+
+	compressed, err := io.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch req.Header.Get("Content-Type") {
+	case contentTypeV2:
+		var msg writev2.Request
+		if err := msg.Unmarshal(data); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.v2 = append(r.v2, &msg)
+	default:
+		var msg prompb.WriteRequest
+		if err := msg.Unmarshal(data); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.reject != nil && r.reject(&msg) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		r.v1 = append(r.v1, &msg)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// samplesV1 returns the received samples keyed by the series labels
+func (r *receiver) samplesV1() map[string][]prompb.Sample {
+	result := make(map[string][]prompb.Sample)
+	var b labels.ScratchBuilder
+	for _, req := range r.v1 {
+		for _, ts := range req.Timeseries {
+			key := ts.ToLabels(&b, nil).String()
+			result[key] = append(result[key], ts.Samples...)
+		}
+	}
+	return result
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(p *PrometheusRemoteWrite)
+		expected string
+	}{
+		{
+			name:     "no url",
+			modify:   func(p *PrometheusRemoteWrite) { p.URL = "" },
+			expected: "'url' required",
+		},
+		{
+			name:     "invalid protocol version",
+			modify:   func(p *PrometheusRemoteWrite) { p.ProtocolVersion = "1.1" },
+			expected: `invalid protocol version "1.1"`,
+		},
+		{
+			name:     "no shards",
+			modify:   func(p *PrometheusRemoteWrite) { p.Shards = 0 },
+			expected: "'shards' must be positive",
+		},
+		{
+			name:     "invalid backoff",
+			modify:   func(p *PrometheusRemoteWrite) { p.MaxBackoff = 0 },
+			expected: "'min_backoff' must be positive and not exceed 'max_backoff'",
+		},
+		{
+			name:     "invalid schema",
+			modify:   func(p *PrometheusRemoteWrite) { p.NativeHistogramSchema = 9 },
+			expected: "native histogram schema 9 out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newPlugin("http://localhost:9090/api/v1/write")
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestWriteV1(t *testing.T) {
+	recv := &receiver{}
+	ts := httptest.NewServer(recv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.Username = config.NewSecret([]byte("user"))
+	plugin.Password = config.NewSecret([]byte("pass"))
+	plugin.Headers = map[string]string{"X-Scope-OrgID": "tenant"}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"time_idle": 42.0},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"time_idle": 43.0},
+			time.Unix(1, 0),
+			telegraf.Gauge,
+		),
+		metric.New(
+			"prometheus",
+			map[string]string{"empty": ""},
+			map[string]interface{}{"requests_total": int64(7)},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+	}
+	require.NoError(t, plugin.Write(input))
+
+	require.NotEmpty(t, recv.headers)
+	header := recv.headers[0]
+	require.Equal(t, "snappy", header.Get("Content-Encoding"))
+	require.Equal(t, "0.1.0", header.Get("X-Prometheus-Remote-Write-Version"))
+	require.Equal(t, "tenant", header.Get("X-Scope-OrgID"))
+	username, password, ok := (&http.Request{Header: header}).BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "user", username)
+	require.Equal(t, "pass", password)
+
+	expected := map[string][]prompb.Sample{
+		`{__name__="cpu_time_idle", host="a"}`: {{Timestamp: 0, Value: 42}, {Timestamp: 1000, Value: 43}},
+		`{__name__="requests_total"}`:          {{Timestamp: 0, Value: 7}},
+	}
+	require.Equal(t, expected, recv.samplesV1())
+}
+
+func TestWriteV2(t *testing.T) {
+	recv := &receiver{}
+	ts := httptest.NewServer(recv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.ProtocolVersion = "2.0"
+	plugin.Shards = 1
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		metric.New(
+			"disk",
+			map[string]string{"host": "a", "path": "/"},
+			map[string]interface{}{"free": uint64(1), "used": uint64(2)},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		metric.New(
+			"net",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"bytes_recv": int64(3)},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+	}
+	require.NoError(t, plugin.Write(input))
+
+	require.Len(t, recv.headers, 1)
+	require.Equal(t, "2.0.0", recv.headers[0].Get("X-Prometheus-Remote-Write-Version"))
+	require.Len(t, recv.v2, 1)
+	req := recv.v2[0]
+
+	// Each string is only transmitted once
+	seen := make(map[string]bool)
+	for _, s := range req.Symbols {
+		require.False(t, seen[s], "duplicate symbol %q", s)
+		seen[s] = true
+	}
+	require.Equal(t, "", req.Symbols[0])
+
+	actual := make(map[string]writev2.Metadata_MetricType)
+	var b labels.ScratchBuilder
+	for _, series := range req.Timeseries {
+		actual[series.ToLabels(&b, req.Symbols).String()] = series.Metadata.Type
+	}
+	expected := map[string]writev2.Metadata_MetricType{
+		`{__name__="disk_free", host="a", path="/"}`: writev2.Metadata_METRIC_TYPE_GAUGE,
+		`{__name__="disk_used", host="a", path="/"}`: writev2.Metadata_METRIC_TYPE_GAUGE,
+		`{__name__="net_bytes_recv", host="a"}`:      writev2.Metadata_METRIC_TYPE_COUNTER,
+	}
+	require.Equal(t, expected, actual)
+}
+
+func histogramMetrics() []telegraf.Metric {
+	tags := func(le string) map[string]string {
+		return map[string]string{"handler": "/api", "le": le}
+	}
+	return []telegraf.Metric{
+		metric.New("prometheus", tags("1"), map[string]interface{}{"latency_bucket": 1.0}, time.Unix(0, 0), telegraf.Histogram),
+		metric.New("prometheus", tags("2"), map[string]interface{}{"latency_bucket": 3.0}, time.Unix(0, 0), telegraf.Histogram),
+		metric.New("prometheus", tags("4"), map[string]interface{}{"latency_bucket": 6.0}, time.Unix(0, 0), telegraf.Histogram),
+		metric.New("prometheus", tags("+Inf"), map[string]interface{}{"latency_bucket": 7.0}, time.Unix(0, 0), telegraf.Histogram),
+		metric.New(
+			"prometheus",
+			map[string]string{"handler": "/api"},
+			map[string]interface{}{"latency_sum": 15.5, "latency_count": 7.0},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		),
+	}
+}
+
+func TestClassicHistogram(t *testing.T) {
+	recv := &receiver{}
+	ts := httptest.NewServer(recv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(histogramMetrics()))
+
+	expected := map[string][]prompb.Sample{
+		`{__name__="latency_bucket", handler="/api", le="1"}`:    {{Value: 1}},
+		`{__name__="latency_bucket", handler="/api", le="2"}`:    {{Value: 3}},
+		`{__name__="latency_bucket", handler="/api", le="4"}`:    {{Value: 6}},
+		`{__name__="latency_bucket", handler="/api", le="+Inf"}`: {{Value: 7}},
+		`{__name__="latency_sum", handler="/api"}`:               {{Value: 15.5}},
+		`{__name__="latency_count", handler="/api"}`:             {{Value: 7}},
+	}
+	require.Equal(t, expected, recv.samplesV1())
+}
+
+func TestNativeHistogram(t *testing.T) {
+	recv := &receiver{}
+	ts := httptest.NewServer(recv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.NativeHistograms = true
+	plugin.NativeHistogramSchema = 0
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(histogramMetrics()))
+
+	require.Len(t, recv.v1, 1)
+	require.Len(t, recv.v1[0].Timeseries, 1)
+	series := recv.v1[0].Timeseries[0]
+	var b labels.ScratchBuilder
+	require.Equal(t, `{__name__="latency", handler="/api"}`, series.ToLabels(&b, nil).String())
+	require.Empty(t, series.Samples)
+	require.Len(t, series.Histograms, 1)
+
+	// The bounds 1, 2 and 4 are the upper bounds of the buckets 0, 1 and 2
+	// of schema 0, the observation above 4 is accounted to bucket 2
+	expected := &histogram.Histogram{
+		Schema:          0,
+		Count:           7,
+		Sum:             15.5,
+		PositiveSpans:   []histogram.Span{{Offset: 0, Length: 3}},
+		NegativeSpans:   []histogram.Span{},
+		PositiveBuckets: []int64{1, 1, 2},
+	}
+	require.Equal(t, expected, series.Histograms[0].ToIntHistogram())
+}
+
+func TestNativeHistogramIncomplete(t *testing.T) {
+	// Neither fields with unknown suffixes nor histograms with only a sum
+	// result in a native histogram
+	metrics := append(histogramMetrics(),
+		metric.New("prometheus", map[string]string{"handler": "/api"}, map[string]interface{}{"latency_foo": 1.0}, time.Unix(0, 0), telegraf.Histogram),
+		metric.New("prometheus", map[string]string{"handler": "/api"}, map[string]interface{}{"duration_sum": 2.0}, time.Unix(0, 0), telegraf.Histogram),
+	)
+
+	c := &converter{nativeHistograms: true, log: testutil.Logger{}}
+	result := c.convert(metrics)
+	require.Len(t, result, 1)
+	require.Equal(t, `{__name__="latency", handler="/api"}`, result[0].labels.String())
+	require.Len(t, result[0].histograms, 1)
+	require.ErrorContains(t, c.lastErr, "no buckets or count")
+}
+
+func TestNativeHistogramSpans(t *testing.T) {
+	n := &nativeHistogram{
+		buckets: map[float64]uint64{
+			0:    2,
+			0.25: 3,
+			0.5:  3,
+			16:   5,
+		},
+		sum:      10,
+		count:    5,
+		hasCount: true,
+	}
+	h, err := n.histogram(0)
+	require.NoError(t, err)
+
+	expected := &histogram.Histogram{
+		Schema:          0,
+		Count:           5,
+		Sum:             10,
+		ZeroCount:       2,
+		PositiveSpans:   []histogram.Span{{Offset: -2, Length: 1}, {Offset: 5, Length: 1}},
+		PositiveBuckets: []int64{1, 1},
+	}
+	require.Equal(t, expected, h)
+}
+
+func TestRetry(t *testing.T) {
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+
+	tests := []struct {
+		name      string
+		status    []int
+		rateLimit bool
+		requests  int
+		samples   int
+		expected  string
+	}{
+		{
+			name:     "server error recovered",
+			status:   []int{http.StatusServiceUnavailable, http.StatusInternalServerError},
+			requests: 3,
+			samples:  1,
+		},
+		{
+			name:      "rate limit recovered",
+			status:    []int{http.StatusTooManyRequests},
+			rateLimit: true,
+			requests:  2,
+			samples:   1,
+		},
+		{
+			name:     "rate limit not retried",
+			status:   []int{http.StatusTooManyRequests},
+			requests: 1,
+		},
+		{
+			name:     "client error dropped",
+			status:   []int{http.StatusBadRequest},
+			requests: 1,
+		},
+		{
+			name:     "retries exhausted",
+			status:   []int{500, 500, 500, 500, 500},
+			requests: 4,
+			expected: "received status 500",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recv := &receiver{status: tt.status}
+			ts := httptest.NewServer(recv)
+			defer ts.Close()
+
+			plugin := newPlugin(ts.URL)
+			plugin.RetryOnRateLimit = tt.rateLimit
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			defer plugin.Close()
+
+			err := plugin.Write(input)
+			if tt.expected == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.expected)
+			}
+			require.Len(t, recv.headers, tt.requests)
+			require.Len(t, recv.samplesV1(), tt.samples)
+		})
+	}
+}
+
+func TestRetryAfterExceedsBackoff(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+	require.ErrorContains(t, plugin.Write(input), "retry requested after 1m0s")
+	require.Equal(t, 1, requests)
+}
+
+func TestSharding(t *testing.T) {
+	recv := &receiver{}
+	ts := httptest.NewServer(recv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.Shards = 3
+	plugin.MaxSamplesPerSend = 4
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := make([]telegraf.Metric, 0, 50)
+	expected := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		host := string(rune('a' + i%26))
+		field := "value"
+		if i >= 26 {
+			field = "other"
+		}
+		input = append(input, metric.New("cpu", map[string]string{"host": host}, map[string]interface{}{field: float64(i)}, time.Unix(0, 0)))
+		expected = append(expected, `{__name__="cpu_`+field+`", host="`+host+`"}`)
+	}
+	require.NoError(t, plugin.Write(input))
+
+	var count int
+	for _, req := range recv.v1 {
+		require.LessOrEqual(t, len(req.Timeseries), 4)
+		count += len(req.Timeseries)
+	}
+	require.Equal(t, 50, count)
+
+	actual := make([]string, 0, 50)
+	for k := range recv.samplesV1() {
+		actual = append(actual, k)
+	}
+	sort.Strings(actual)
+	sort.Strings(expected)
+	require.Equal(t, expected, actual)
+}
+
+func TestShardFailureRetry(t *testing.T) {
+	recv := &receiver{
+		reject: func(req *prompb.WriteRequest) bool {
+			for _, ts := range req.Timeseries {
+				for _, l := range ts.Labels {
+					if l.Name == "host" && l.Value == "b" {
+						return true
+					}
+				}
+			}
+			return false
+		},
+	}
+	ts := httptest.NewServer(recv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.Shards = 2
+	plugin.MaxSamplesPerSend = 1
+	plugin.MaxRetries = 0
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := make([]telegraf.Metric, 0, 4)
+	for _, host := range []string{"a", "b", "c", "d"} {
+		input = append(input, metric.New("cpu", map[string]string{"host": host}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)))
+	}
+
+	// The shard of the failing series fails the write while the other
+	// series are delivered
+	require.ErrorContains(t, plugin.Write(input), "received status 500")
+	delivered := len(recv.samplesV1())
+	require.NotZero(t, delivered)
+	require.Less(t, delivered, 4)
+
+	// Retrying the batch only sends the series not delivered before
+	recv.reject = nil
+	requests := len(recv.v1)
+	require.NoError(t, plugin.Write(input))
+	require.Len(t, recv.v1, requests+4-delivered)
+	samples := recv.samplesV1()
+	require.Len(t, samples, 4)
+	for k, v := range samples {
+		require.Len(t, v, 1, k)
+	}
+
+	// New samples of the series are sent after the successful retry
+	input[0] = metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 2.0}, time.Unix(1, 0))
+	require.NoError(t, plugin.Write(input[:1]))
+	require.Len(t, recv.samplesV1()[`{__name__="cpu_value", host="a"}`], 2)
+}
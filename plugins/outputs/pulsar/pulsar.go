@@ -0,0 +1,219 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package pulsar
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	common_pulsar "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Pulsar struct {
+	Topic                   string          `toml:"topic"`
+	TopicTag                string          `toml:"topic_tag"`
+	ExcludeTopicTag         bool            `toml:"exclude_topic_tag"`
+	RoutingTag              string          `toml:"routing_tag"`
+	Compression             string          `toml:"compression"`
+	SendTimeout             config.Duration `toml:"send_timeout"`
+	DisableBatching         bool            `toml:"disable_batching"`
+	BatchingMaxPublishDelay config.Duration `toml:"batching_max_publish_delay"`
+	BatchingMaxMessages     uint            `toml:"batching_max_messages"`
+	Log                     telegraf.Logger `toml:"-"`
+	common_pulsar.ClientConfig
+
+	serializer  serializers.Serializer
+	compression pulsar.CompressionType
+
+	client      pulsar.Client
+	producers   map[string]pulsar.Producer
+	newProducer func(pulsar.ProducerOptions) (pulsar.Producer, error)
+}
+
+func (*Pulsar) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Pulsar) SetSerializer(serializer serializers.Serializer) {
+	p.serializer = serializer
+}
+
+func (p *Pulsar) Init() error {
+	if p.Topic == "" {
+		return errors.New("'topic' required")
+	}
+
+	switch p.Compression {
+	case "", "none":
+		p.compression = pulsar.NoCompression
+	case "lz4":
+		p.compression = pulsar.LZ4
+	case "zlib":
+		p.compression = pulsar.ZLib
+	case "zstd":
+		p.compression = pulsar.ZSTD
+	default:
+		return fmt.Errorf("invalid compression %q", p.Compression)
+	}
+
+	p.producers = make(map[string]pulsar.Producer)
+
+	return nil
+}
+
+func (p *Pulsar) Connect() error {
+	client, err := p.NewClient(p.Log)
+	if err != nil {
+		return fmt.Errorf("creating client failed: %w", err)
+	}
+	p.client = client
+	p.newProducer = client.CreateProducer
+
+	return nil
+}
+
+func (p *Pulsar) Close() error {
+	for topic, producer := range p.producers {
+		producer.Close()
+		delete(p.producers, topic)
+	}
+	if p.client != nil {
+		p.client.Close()
+	}
+	return nil
+}
+
+func (p *Pulsar) Write(metrics []telegraf.Metric) error {
+	// Create the producers of all topics before sending anything to avoid
+	// partially sending the batch if a producer cannot be created
+	type message struct {
+		topic    string
+		metric   telegraf.Metric
+		producer pulsar.Producer
+	}
+	messages := make([]message, 0, len(metrics))
+	used := make(map[string]pulsar.Producer)
+	for _, m := range metrics {
+		topic, m := p.topic(m)
+
+		producer, err := p.producer(topic)
+		if err != nil {
+			return err
+		}
+		used[topic] = producer
+		messages = append(messages, message{topic: topic, metric: m, producer: producer})
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	for _, entry := range messages {
+		buf, err := p.serializer.Serialize(entry.metric)
+		if err != nil {
+			p.Log.Debugf("Could not serialize metric: %v", err)
+			continue
+		}
+
+		msg := &pulsar.ProducerMessage{
+			Payload:   buf,
+			EventTime: entry.metric.Time(),
+		}
+		if p.RoutingTag != "" {
+			msg.Key, _ = entry.metric.GetTag(p.RoutingTag)
+		}
+
+		topic := entry.topic
+		wg.Add(1)
+		entry.producer.SendAsync(context.Background(), msg, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+			defer wg.Done()
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("sending to topic %q failed: %w", topic, err))
+				mu.Unlock()
+			}
+		})
+	}
+
+	// Flush the batches of the producers and wait for all messages to be
+	// acknowledged by the broker
+	for topic, producer := range used {
+		if err := producer.FlushWithCtx(context.Background()); err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("flushing topic %q failed: %w", topic, err))
+			mu.Unlock()
+		}
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// topic returns the topic of the metric and the metric to send, which lacks
+// the topic tag if it should be excluded
+func (p *Pulsar) topic(m telegraf.Metric) (string, telegraf.Metric) {
+	if p.TopicTag == "" {
+		return p.Topic, m
+	}
+
+	topic, found := m.GetTag(p.TopicTag)
+	if !found {
+		return p.Topic, m
+	}
+
+	// A copy is required to avoid modifying the metric for other outputs
+	if p.ExcludeTopicTag {
+		m = m.Copy()
+		m.Accept()
+		m.RemoveTag(p.TopicTag)
+	}
+	return topic, m
+}
+
+// producer returns the producer of the topic creating it if necessary
+func (p *Pulsar) producer(topic string) (pulsar.Producer, error) {
+	if producer, found := p.producers[topic]; found {
+		return producer, nil
+	}
+
+	producer, err := p.newProducer(pulsar.ProducerOptions{
+		Topic:                   topic,
+		SendTimeout:             time.Duration(p.SendTimeout),
+		CompressionType:         p.compression,
+		DisableBatching:         p.DisableBatching,
+		BatchingMaxPublishDelay: time.Duration(p.BatchingMaxPublishDelay),
+		BatchingMaxMessages:     p.BatchingMaxMessages,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating producer for topic %q failed: %w", topic, err)
+	}
+	p.producers[topic] = producer
+
+	return producer, nil
+}
+
+func init() {
+	outputs.Add("pulsar", func() telegraf.Output {
+		return &Pulsar{
+			SendTimeout:             config.Duration(30 * time.Second),
+			BatchingMaxPublishDelay: config.Duration(10 * time.Millisecond),
+			BatchingMaxMessages:     1000,
+			ClientConfig: common_pulsar.ClientConfig{
+				URL:               "pulsar://localhost:6650",
+				ConnectionTimeout: config.Duration(5 * time.Second),
+				OperationTimeout:  config.Duration(30 * time.Second),
+			},
+		}
+	})
+}
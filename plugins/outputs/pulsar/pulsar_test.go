@@ -0,0 +1,206 @@
+package pulsar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type message struct {
+	key     string
+	payload string
+}
+
+type fakeProducer struct {
+	pulsar.Producer
+
+	sync.Mutex
+	topic    string
+	messages []message
+	err      error
+	flushed  int
+}
+
+func (p *fakeProducer) SendAsync(_ context.Context, msg *pulsar.ProducerMessage, callback func(pulsar.MessageID, *pulsar.ProducerMessage, error)) {
+	p.Lock()
+	p.messages = append(p.messages, message{key: msg.Key, payload: string(msg.Payload)})
+	p.Unlock()
+	go callback(nil, msg, p.err)
+}
+
+func (p *fakeProducer) FlushWithCtx(context.Context) error {
+	p.Lock()
+	defer p.Unlock()
+	p.flushed++
+	return nil
+}
+
+func (*fakeProducer) Close() {}
+
+func newPulsar(t *testing.T) (*Pulsar, map[string]*fakeProducer) {
+	plugin := outputs.Outputs["pulsar"]().(*Pulsar)
+	plugin.Topic = "telegraf"
+	plugin.Log = testutil.Logger{}
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+
+	producers := make(map[string]*fakeProducer)
+	plugin.newProducer = func(options pulsar.ProducerOptions) (pulsar.Producer, error) {
+		producer := &fakeProducer{topic: options.Topic}
+		producers[options.Topic] = producer
+		return producer, nil
+	}
+
+	return plugin, producers
+}
+
+func TestInitFail(t *testing.T) {
+	plugin, _ := newPulsar(t)
+	plugin.Topic = ""
+	require.ErrorContains(t, plugin.Init(), "'topic' required")
+
+	plugin.Topic = "telegraf"
+	plugin.Compression = "gzip"
+	require.ErrorContains(t, plugin.Init(), `invalid compression "gzip"`)
+}
+
+func TestWrite(t *testing.T) {
+	plugin, producers := newPulsar(t)
+	plugin.TopicTag = "topic"
+	plugin.ExcludeTopicTag = true
+	plugin.RoutingTag = "host"
+	require.NoError(t, plugin.Init())
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "b", "topic": "other"}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+		metric.New("mem", map[string]string{}, map[string]interface{}{"value": 3.0}, time.Unix(0, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Write(metrics[:1]))
+
+	require.Len(t, producers, 2)
+	require.Equal(t, []message{
+		{key: "a", payload: "cpu,host=a value=1 0\n"},
+		{payload: "mem value=3 0\n"},
+		{key: "a", payload: "cpu,host=a value=1 0\n"},
+	}, producers["telegraf"].messages)
+	require.Equal(t, 2, producers["telegraf"].flushed)
+	require.Equal(t, []message{
+		{key: "b", payload: "cpu,host=b value=2 0\n"},
+	}, producers["other"].messages)
+	require.Equal(t, 1, producers["other"].flushed)
+
+	// The topic tag must not be removed from the original metric
+	require.True(t, metrics[1].HasTag("topic"))
+}
+
+func TestWriteSendError(t *testing.T) {
+	plugin, producers := newPulsar(t)
+	require.NoError(t, plugin.Init())
+	producers["telegraf"] = &fakeProducer{topic: "telegraf", err: errors.New("timeout")}
+	plugin.producers["telegraf"] = producers["telegraf"]
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+	require.ErrorContains(t, plugin.Write(metrics), `sending to topic "telegraf" failed: timeout`)
+}
+
+func TestWriteProducerError(t *testing.T) {
+	plugin, _ := newPulsar(t)
+	require.NoError(t, plugin.Init())
+	plugin.newProducer = func(pulsar.ProducerOptions) (pulsar.Producer, error) {
+		return nil, errors.New("topic not found")
+	}
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+	require.ErrorContains(t, plugin.Write(metrics), `creating producer for topic "telegraf" failed: topic not found`)
+	require.Empty(t, plugin.producers)
+}
+
+func TestWriteProducerErrorNothingSent(t *testing.T) {
+	plugin, producers := newPulsar(t)
+	plugin.TopicTag = "topic"
+	require.NoError(t, plugin.Init())
+	create := plugin.newProducer
+	plugin.newProducer = func(options pulsar.ProducerOptions) (pulsar.Producer, error) {
+		if options.Topic == "missing" {
+			return nil, errors.New("topic not found")
+		}
+		return create(options)
+	}
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"topic": "missing"}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 3.0}, time.Unix(0, 0)),
+	}
+	require.ErrorContains(t, plugin.Write(metrics), `creating producer for topic "missing" failed: topic not found`)
+	require.Contains(t, producers, "telegraf")
+	require.Empty(t, producers["telegraf"].messages)
+}
+
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	servicePort := "6650"
+	container := testutil.Container{
+		Image:        "apachepulsar/pulsar",
+		ExposedPorts: []string{servicePort, "8080"},
+		Cmd:          []string{"bin/pulsar", "standalone"},
+		WaitingFor: wait.ForAll(
+			wait.NewHTTPStrategy("/admin/v2/clusters").WithPort(nat.Port("8080")),
+			wait.ForListeningPort(nat.Port(servicePort)),
+		),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	plugin := outputs.Outputs["pulsar"]().(*Pulsar)
+	plugin.URL = fmt.Sprintf("pulsar://%s:%s", container.Address, container.Ports[servicePort])
+	plugin.Topic = "persistent://public/default/telegraf"
+	plugin.Log = testutil.Logger{}
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Subscribe before writing to receive the messages
+	consumer, err := plugin.client.Subscribe(pulsar.ConsumerOptions{
+		Topic:            plugin.Topic,
+		SubscriptionName: "test",
+	})
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	require.NoError(t, plugin.Write(testutil.MockMetrics()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	msg, err := consumer.Receive(ctx)
+	require.NoError(t, err)
+	require.Contains(t, string(msg.Payload()), "test1,tag1=value1 value=1")
+}
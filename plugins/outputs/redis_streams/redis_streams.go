@@ -0,0 +1,146 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package redis_streams
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	common_redis "github.com/influxdata/telegraf/plugins/common/redis"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type RedisStreams struct {
+	Stream           string          `toml:"stream"`
+	StreamTag        string          `toml:"stream_tag"`
+	ExcludeStreamTag bool            `toml:"exclude_stream_tag"`
+	PayloadField     string          `toml:"payload_field"`
+	MaxLen           int64           `toml:"max_len"`
+	ExactTrimming    bool            `toml:"exact_trimming"`
+	Log              telegraf.Logger `toml:"-"`
+	common_redis.ClientConfig
+
+	serializer serializers.Serializer
+	client     *redis.Client
+}
+
+func (*RedisStreams) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RedisStreams) SetSerializer(serializer serializers.Serializer) {
+	r.serializer = serializer
+}
+
+func (r *RedisStreams) Init() error {
+	if r.Stream == "" {
+		return errors.New("'stream' required")
+	}
+	if r.PayloadField == "" {
+		return errors.New("'payload_field' required")
+	}
+	if r.MaxLen < 0 {
+		return errors.New("'max_len' must not be negative")
+	}
+	return nil
+}
+
+func (r *RedisStreams) Connect() error {
+	client, err := r.NewClient()
+	if err != nil {
+		return err
+	}
+	r.client = client
+	return nil
+}
+
+func (r *RedisStreams) Close() error {
+	if r.client == nil {
+		return nil
+	}
+	return r.client.Close()
+}
+
+func (r *RedisStreams) Write(metrics []telegraf.Metric) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.Timeout))
+	defer cancel()
+
+	// Send all entries in a single round-trip
+	pipe := r.client.Pipeline()
+	for _, m := range metrics {
+		stream, m := r.stream(m)
+
+		buf, err := r.serializer.Serialize(m)
+		if err != nil {
+			r.Log.Debugf("Could not serialize metric: %v", err)
+			continue
+		}
+
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: stream,
+			MaxLen: r.MaxLen,
+			Approx: !r.ExactTrimming,
+			Values: []interface{}{r.PayloadField, buf},
+		})
+	}
+	if pipe.Len() == 0 {
+		return nil
+	}
+
+	cmds, err := pipe.Exec(ctx)
+	if err != nil {
+		// Report the first failed command as it contains the stream
+		for _, cmd := range cmds {
+			if cmd.Err() != nil {
+				return fmt.Errorf("adding entry to stream %q failed: %w", cmd.Args()[1], cmd.Err())
+			}
+		}
+		return fmt.Errorf("adding entries failed: %w", err)
+	}
+
+	return nil
+}
+
+// stream returns the stream of the metric and the metric to send, which
+// lacks the stream tag if it should be excluded
+func (r *RedisStreams) stream(m telegraf.Metric) (string, telegraf.Metric) {
+	if r.StreamTag == "" {
+		return r.Stream, m
+	}
+
+	stream, found := m.GetTag(r.StreamTag)
+	if !found {
+		return r.Stream, m
+	}
+
+	// A copy is required to avoid modifying the metric for other outputs
+	if r.ExcludeStreamTag {
+		m = m.Copy()
+		m.Accept()
+		m.RemoveTag(r.StreamTag)
+	}
+	return stream, m
+}
+
+func init() {
+	outputs.Add("redis_streams", func() telegraf.Output {
+		return &RedisStreams{
+			Stream:       "telegraf",
+			PayloadField: "data",
+			ClientConfig: common_redis.ClientConfig{
+				Address: "127.0.0.1:6379",
+				Timeout: config.Duration(10 * time.Second),
+			},
+		}
+	})
+}
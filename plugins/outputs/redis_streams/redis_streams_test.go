@@ -0,0 +1,115 @@
+package redis_streams
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newPlugin(t *testing.T, address string) *RedisStreams {
+	plugin := outputs.Outputs["redis_streams"]().(*RedisStreams)
+	plugin.Address = address
+	plugin.Log = testutil.Logger{}
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+
+	return plugin
+}
+
+func entries(t *testing.T, client *redis.Client, stream string) []string {
+	msgs, err := client.XRange(context.Background(), stream, "-", "+").Result()
+	require.NoError(t, err)
+
+	data := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		data = append(data, msg.Values["data"].(string))
+	}
+	return data
+}
+
+func TestInitFail(t *testing.T) {
+	plugin := newPlugin(t, "127.0.0.1:6379")
+	plugin.Stream = ""
+	require.ErrorContains(t, plugin.Init(), "'stream' required")
+
+	plugin.Stream = "telegraf"
+	plugin.MaxLen = -1
+	require.ErrorContains(t, plugin.Init(), "'max_len' must not be negative")
+}
+
+func TestWrite(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	plugin := newPlugin(t, server.Addr())
+	plugin.StreamTag = "stream"
+	plugin.ExcludeStreamTag = true
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "b", "stream": "other"}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+		metric.New("mem", map[string]string{}, map[string]interface{}{"value": 3.0}, time.Unix(0, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Equal(t, []string{"cpu,host=a value=1 0\n", "mem value=3 0\n"}, entries(t, client, "telegraf"))
+	require.Equal(t, []string{"cpu,host=b value=2 0\n"}, entries(t, client, "other"))
+
+	// The stream tag must not be removed from the original metric
+	require.True(t, metrics[1].HasTag("stream"))
+}
+
+func TestWriteTrimsStream(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	plugin := newPlugin(t, server.Addr())
+	plugin.MaxLen = 2
+	plugin.ExactTrimming = true
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 3.0}, time.Unix(0, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Equal(t, []string{"cpu value=2 0\n", "cpu value=3 0\n"}, entries(t, client, "telegraf"))
+}
+
+func TestWriteError(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	plugin := newPlugin(t, server.Addr())
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Adding entries to a key of another type fails
+	require.NoError(t, server.Set("telegraf", "value"))
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+	require.ErrorContains(t, plugin.Write(metrics), `adding entry to stream "telegraf" failed: WRONGTYPE`)
+}
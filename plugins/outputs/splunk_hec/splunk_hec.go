@@ -0,0 +1,350 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package splunk_hec
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common_http "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+const maxErrMsgLen = 1024
+
+type SplunkHEC struct {
+	URL             string          `toml:"url"`
+	Token           config.Secret   `toml:"token"`
+	Index           string          `toml:"index"`
+	Source          string          `toml:"source"`
+	Sourcetype      string          `toml:"sourcetype"`
+	IndexTag        string          `toml:"index_tag"`
+	SourceTag       string          `toml:"source_tag"`
+	SourcetypeTag   string          `toml:"sourcetype_tag"`
+	MultiMetric     bool            `toml:"multimetric"`
+	ContentEncoding string          `toml:"content_encoding"`
+	UseAck          bool            `toml:"use_ack"`
+	Channel         string          `toml:"channel"`
+	AckPollInterval config.Duration `toml:"ack_poll_interval"`
+	AckTimeout      config.Duration `toml:"ack_timeout"`
+	Log             telegraf.Logger `toml:"-"`
+	common_http.HTTPClientConfig
+
+	eventURL   string
+	ackURL     string
+	client     *http.Client
+	serializer *splunkmetric.Serializer
+}
+
+func (*SplunkHEC) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SplunkHEC) Init() error {
+	if s.URL == "" {
+		return errors.New("'url' required")
+	}
+	if s.Token.Empty() {
+		return errors.New("'token' required")
+	}
+
+	switch s.ContentEncoding {
+	case "", "identity", "gzip":
+	default:
+		return fmt.Errorf("invalid content encoding %q", s.ContentEncoding)
+	}
+
+	// Indexer acknowledgement requires a channel identified by a GUID
+	if s.UseAck {
+		if s.Channel == "" {
+			id, err := uuid.NewV4()
+			if err != nil {
+				return fmt.Errorf("generating channel failed: %w", err)
+			}
+			s.Channel = id.String()
+		}
+		if s.AckPollInterval <= 0 {
+			return errors.New("'ack_poll_interval' must be positive")
+		}
+		if s.AckTimeout <= 0 {
+			return errors.New("'ack_timeout' must be positive")
+		}
+	}
+
+	base := strings.TrimSuffix(s.URL, "/")
+	s.eventURL = base + "/services/collector/event"
+	s.ackURL = base + "/services/collector/ack"
+	if s.Channel != "" {
+		s.ackURL += "?" + url.Values{"channel": []string{s.Channel}}.Encode()
+	}
+
+	s.serializer = &splunkmetric.Serializer{
+		HecRouting:  true,
+		MultiMetric: s.MultiMetric,
+	}
+
+	return nil
+}
+
+func (s *SplunkHEC) Connect() error {
+	client, err := s.HTTPClientConfig.CreateClient(context.Background(), s.Log)
+	if err != nil {
+		return err
+	}
+	s.client = client
+
+	return nil
+}
+
+func (s *SplunkHEC) Close() error {
+	if s.client != nil {
+		s.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (s *SplunkHEC) Write(metrics []telegraf.Metric) error {
+	body := make([]byte, 0, 256*len(metrics))
+	for _, m := range metrics {
+		events, err := s.events(m)
+		if err != nil {
+			s.Log.Errorf("Could not serialize metric: %v", err)
+			continue
+		}
+		body = append(body, events...)
+	}
+	if len(body) == 0 {
+		return nil
+	}
+
+	resp, err := s.send(s.eventURL, body)
+	if err != nil {
+		var hecErr *hecError
+		if errors.As(err, &hecErr) && !hecErr.retryable() {
+			s.Log.Errorf("Dropping metrics as the request was rejected: %v", err)
+			return nil
+		}
+		return err
+	}
+
+	if !s.UseAck {
+		return nil
+	}
+	if resp.AckID == nil {
+		return errors.New("no acknowledgement ID received, is indexer acknowledgement enabled for the token?")
+	}
+
+	return s.waitForAck(*resp.AckID)
+}
+
+// events serializes the metric to HEC events and sets the index, source and
+// sourcetype of the events
+func (s *SplunkHEC) events(m telegraf.Metric) ([]byte, error) {
+	metadata := make(map[string]string, 3)
+	var copied bool
+	for _, r := range []struct{ key, tag, value string }{
+		{"index", s.IndexTag, s.Index},
+		{"source", s.SourceTag, s.Source},
+		{"sourcetype", s.SourcetypeTag, s.Sourcetype},
+	} {
+		if v, found := m.GetTag(r.tag); r.tag != "" && found {
+			r.value = v
+
+			// Avoid adding the routing tags as dimensions, a copy is
+			// required to avoid modifying the metric for other outputs
+			if !copied {
+				m = m.Copy()
+				m.Accept()
+				copied = true
+			}
+			m.RemoveTag(r.tag)
+		}
+		if r.value != "" {
+			metadata[r.key] = r.value
+		}
+	}
+
+	serialized, err := s.serializer.Serialize(m)
+	if err != nil || len(metadata) == 0 {
+		return serialized, err
+	}
+
+	// The serializer produces a stream of JSON objects, one per event
+	var buf bytes.Buffer
+	decoder := json.NewDecoder(bytes.NewReader(serialized))
+	for decoder.More() {
+		var event map[string]json.RawMessage
+		if err := decoder.Decode(&event); err != nil {
+			return nil, err
+		}
+		for k, v := range metadata {
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			event[k] = raw
+		}
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encoded)
+	}
+	return buf.Bytes(), nil
+}
+
+// waitForAck polls the acknowledgement endpoint until the events of the
+// request are indexed
+func (s *SplunkHEC) waitForAck(id int64) error {
+	body, err := json.Marshal(map[string][]int64{"acks": {id}})
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(time.Duration(s.AckPollInterval))
+	defer ticker.Stop()
+	timeout := time.NewTimer(time.Duration(s.AckTimeout))
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-timeout.C:
+			return fmt.Errorf("events with acknowledgement ID %d not indexed within %s", id, time.Duration(s.AckTimeout))
+		case <-ticker.C:
+		}
+
+		resp, err := s.send(s.ackURL, body)
+		if err != nil {
+			return fmt.Errorf("querying acknowledgement failed: %w", err)
+		}
+		if resp.Acks[fmt.Sprint(id)] {
+			return nil
+		}
+	}
+}
+
+// response is the body of HEC responses
+type response struct {
+	Text  string          `json:"text"`
+	Code  int             `json:"code"`
+	AckID *int64          `json:"ackId"`
+	Acks  map[string]bool `json:"acks"`
+}
+
+func (s *SplunkHEC) send(u string, body []byte) (*response, error) {
+	var reader io.Reader = bytes.NewReader(body)
+	if s.ContentEncoding == "gzip" {
+		rc := internal.CompressWithGzip(reader)
+		defer rc.Close()
+		reader = rc
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.Token.Get()
+	if err != nil {
+		return nil, fmt.Errorf("getting token failed: %w", err)
+	}
+	req.Header.Set("Authorization", "Splunk "+token.String())
+	token.Destroy()
+
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Content-Type", "application/json")
+	if s.ContentEncoding == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.Channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", s.Channel)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, fmt.Errorf("reading response of [%s] failed: %w", u, err)
+	}
+
+	var r response
+	if err := json.Unmarshal(buf, &r); err != nil {
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("when writing to [%s] received status code: %d. body: %s",
+				u, resp.StatusCode, truncate(buf))
+		}
+		return nil, fmt.Errorf("decoding response of [%s] failed: %w", u, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || r.Code != 0 {
+		return nil, &hecError{status: resp.StatusCode, code: r.Code, text: r.Text}
+	}
+
+	return &r, nil
+}
+
+// hecError is an error response of the HTTP Event Collector
+type hecError struct {
+	status int
+	code   int
+	text   string
+}
+
+func (e *hecError) Error() string {
+	return fmt.Sprintf("received status code %d with HEC error code %d: %s", e.status, e.code, e.text)
+}
+
+// retryable returns whether sending the same data again might succeed, as
+// opposed to errors caused by the data or the configuration like an invalid
+// token
+func (e *hecError) retryable() bool {
+	switch e.code {
+	case 8, // Internal server error
+		9,  // Server is busy
+		18: // HEC is unhealthy, queues are full
+		return true
+	case 0:
+		// Errors without HEC error code are retried on server errors
+		return e.status >= 500 || e.status == http.StatusTooManyRequests
+	}
+	return false
+}
+
+func truncate(buf []byte) string {
+	if len(buf) > maxErrMsgLen {
+		buf = buf[:maxErrMsgLen]
+	}
+	return string(buf)
+}
+
+func init() {
+	outputs.Add("splunk_hec", func() telegraf.Output {
+		return &SplunkHEC{
+			AckPollInterval: config.Duration(time.Second),
+			AckTimeout:      config.Duration(time.Minute),
+			HTTPClientConfig: common_http.HTTPClientConfig{
+				Timeout: config.Duration(5 * time.Second),
+			},
+		}
+	})
+}
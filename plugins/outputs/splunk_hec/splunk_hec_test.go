@@ -0,0 +1,324 @@
+package splunk_hec
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newPlugin(u string) *SplunkHEC {
+	plugin := outputs.Outputs["splunk_hec"]().(*SplunkHEC)
+	plugin.URL = u
+	plugin.Token = config.NewSecret([]byte("token"))
+	plugin.Log = testutil.Logger{}
+	return plugin
+}
+
+func decodeEvents(t *testing.T, body io.Reader) []map[string]interface{} {
+	var events []map[string]interface{}
+	decoder := json.NewDecoder(body)
+	for decoder.More() {
+		var event map[string]interface{}
+		require.NoError(t, decoder.Decode(&event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(s *SplunkHEC)
+		expected string
+	}{
+		{
+			name:     "no url",
+			modify:   func(s *SplunkHEC) { s.URL = "" },
+			expected: "'url' required",
+		},
+		{
+			name:     "no token",
+			modify:   func(s *SplunkHEC) { s.Token = config.NewSecret(nil) },
+			expected: "'token' required",
+		},
+		{
+			name:     "invalid encoding",
+			modify:   func(s *SplunkHEC) { s.ContentEncoding = "br" },
+			expected: `invalid content encoding "br"`,
+		},
+		{
+			name: "no ack timeout",
+			modify: func(s *SplunkHEC) {
+				s.UseAck = true
+				s.AckTimeout = 0
+			},
+			expected: "'ack_timeout' must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newPlugin("http://localhost:8088")
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestWriteEvents(t *testing.T) {
+	var events []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/collector/event" || r.Header.Get("Authorization") != "Splunk token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		events = decodeEvents(t, r.Body)
+		_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.Sourcetype = "telegraf"
+	plugin.IndexTag = "index"
+	plugin.SourcetypeTag = "sourcetype"
+	plugin.MultiMetric = true
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "index": "metrics", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 42.0},
+			time.Unix(1700000000, 0),
+		),
+		metric.New(
+			"mem",
+			map[string]string{"host": "b", "sourcetype": "custom"},
+			map[string]interface{}{"free": int64(1)},
+			time.Unix(1700000000, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	expected := []map[string]interface{}{
+		{
+			"time":       1700000000.0,
+			"event":      "metric",
+			"host":       "a",
+			"index":      "metrics",
+			"sourcetype": "telegraf",
+			"fields":     map[string]interface{}{"cpu": "cpu0", "metric_name:cpu.usage": 42.0},
+		},
+		{
+			"time":       1700000000.0,
+			"event":      "metric",
+			"host":       "b",
+			"sourcetype": "custom",
+			"fields":     map[string]interface{}{"metric_name:mem.free": 1.0},
+		},
+	}
+	require.Equal(t, expected, events)
+
+	// The routing tags must not be removed from the original metrics
+	require.True(t, metrics[0].HasTag("index"))
+	require.True(t, metrics[1].HasTag("sourcetype"))
+}
+
+func TestWriteEventsKeepTagsByDefault(t *testing.T) {
+	var events []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events = decodeEvents(t, r.Body)
+		_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.MultiMetric = true
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "sourcetype": "sensor"},
+			map[string]interface{}{"usage": 42.0},
+			time.Unix(1700000000, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	expected := []map[string]interface{}{
+		{
+			"time":   1700000000.0,
+			"event":  "metric",
+			"host":   "a",
+			"fields": map[string]interface{}{"sourcetype": "sensor", "metric_name:cpu.usage": 42.0},
+		},
+	}
+	require.Equal(t, expected, events)
+}
+
+func TestWriteWithAck(t *testing.T) {
+	var mu sync.Mutex
+	var polls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Splunk-Request-Channel") != "1a2b3c4d-0000-0000-0000-000000000000" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"text":"Data channel is missing","code":10}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/services/collector/event":
+			_, _ = w.Write([]byte(`{"text":"Success","code":0,"ackId":7}`))
+		case "/services/collector/ack":
+			var req map[string][]int64
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req["acks"]) != 1 || req["acks"][0] != 7 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if r.URL.Query().Get("channel") != "1a2b3c4d-0000-0000-0000-000000000000" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			// Report the events as indexed on the third poll
+			mu.Lock()
+			polls++
+			indexed := polls >= 3
+			mu.Unlock()
+			if indexed {
+				_, _ = w.Write([]byte(`{"acks":{"7":true}}`))
+			} else {
+				_, _ = w.Write([]byte(`{"acks":{"7":false}}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.UseAck = true
+	plugin.Channel = "1a2b3c4d-0000-0000-0000-000000000000"
+	plugin.AckPollInterval = config.Duration(10 * time.Millisecond)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(testutil.MockMetrics()))
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 3, polls)
+}
+
+func TestWriteAckTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/collector/event" {
+			_, _ = w.Write([]byte(`{"text":"Success","code":0,"ackId":1}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"acks":{"1":false}}`))
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.UseAck = true
+	plugin.AckPollInterval = config.Duration(10 * time.Millisecond)
+	plugin.AckTimeout = config.Duration(50 * time.Millisecond)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.ErrorContains(t, plugin.Write(testutil.MockMetrics()), "events with acknowledgement ID 1 not indexed within 50ms")
+}
+
+func TestWriteErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{
+			name:   "invalid data format is dropped",
+			status: http.StatusBadRequest,
+			body:   `{"text":"Invalid data format","code":6,"invalid-event-number":0}`,
+		},
+		{
+			name:   "incorrect index is dropped",
+			status: http.StatusBadRequest,
+			body:   `{"text":"Incorrect index","code":7,"invalid-event-number":0}`,
+		},
+		{
+			name:     "server busy is retried",
+			status:   http.StatusServiceUnavailable,
+			body:     `{"text":"Server is busy","code":9}`,
+			expected: "received status code 503 with HEC error code 9: Server is busy",
+		},
+		{
+			name:     "internal server error is retried",
+			status:   http.StatusInternalServerError,
+			body:     `{"text":"Internal server error","code":8}`,
+			expected: "HEC error code 8: Internal server error",
+		},
+		{
+			name:   "invalid token is dropped",
+			status: http.StatusForbidden,
+			body:   `{"text":"Invalid token","code":4}`,
+		},
+		{
+			name:   "ack disabled is dropped",
+			status: http.StatusBadRequest,
+			body:   `{"text":"ACK is disabled","code":14}`,
+		},
+		{
+			name:   "unknown code is dropped",
+			status: http.StatusBadRequest,
+			body:   `{"text":"Something new","code":42}`,
+		},
+		{
+			name:     "non-HEC error is retried",
+			status:   http.StatusBadGateway,
+			body:     "bad gateway",
+			expected: "received status code: 502. body: bad gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			plugin := newPlugin(ts.URL)
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			defer plugin.Close()
+
+			err := plugin.Write(testutil.MockMetrics())
+			if tt.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
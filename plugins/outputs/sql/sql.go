@@ -4,7 +4,9 @@ package sql
 import (
 	gosql "database/sql"
 	_ "embed"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -30,6 +32,7 @@ type ConvertStruct struct {
 	Integer         string `toml:"integer"`
 	Real            string `toml:"real"`
 	Text            string `toml:"text"`
+	Key             string `toml:"key"`
 	Timestamp       string `toml:"timestamp"`
 	Defaultvalue    string `toml:"defaultvalue"`
 	Unsigned        string `toml:"unsigned"`
@@ -49,16 +52,49 @@ type SQL struct {
 	ConnectionMaxLifetime config.Duration `toml:"connection_max_lifetime"`
 	ConnectionMaxIdle     int             `toml:"connection_max_idle"`
 	ConnectionMaxOpen     int             `toml:"connection_max_open"`
+	BatchMode             string          `toml:"batch_mode"`
+	BatchMaxRows          int             `toml:"batch_max_rows"`
+	UpsertKeyTags         []string        `toml:"upsert_key_tags"`
+	TableUpdateTemplate   string          `toml:"table_update_template"`
 	Log                   telegraf.Logger `toml:"-"`
 
-	db     *gosql.DB
-	tables map[string]bool
+	db      *gosql.DB
+	tables  map[string]bool
+	columns map[string]map[string]bool
 }
 
 func (*SQL) SampleConfig() string {
 	return sampleConfig
 }
 
+func (p *SQL) Init() error {
+	switch p.BatchMode {
+	case "", "transaction":
+	case "multi_row":
+		if p.Driver == "clickhouse" {
+			return errors.New("batch mode \"multi_row\" is not supported for clickhouse")
+		}
+		if p.BatchMaxRows < 1 {
+			return errors.New("'batch_max_rows' must be greater than zero")
+		}
+	default:
+		return fmt.Errorf("invalid batch mode %q", p.BatchMode)
+	}
+
+	if len(p.UpsertKeyTags) > 0 {
+		switch p.Driver {
+		case "pgx", "sqlite", "mysql", "mssql", "snowflake":
+		default:
+			return fmt.Errorf("upserts are not supported for driver %q", p.Driver)
+		}
+		if p.Convert.Key == "" {
+			return errors.New("'convert.key' required for upserts")
+		}
+	}
+
+	return nil
+}
+
 func (p *SQL) Connect() error {
 	db, err := gosql.Open(p.Driver, p.DataSourceName)
 	if err != nil {
@@ -84,6 +120,7 @@ func (p *SQL) Connect() error {
 
 	p.db = db
 	p.tables = make(map[string]bool)
+	p.columns = make(map[string]map[string]bool)
 
 	return nil
 }
@@ -144,6 +181,15 @@ func (p *SQL) deriveDatatype(value interface{}) string {
 	return datatype
 }
 
+// tagDatatype returns the column type of the tag, key columns of upserts use
+// the key type as many databases do not allow text columns in primary keys
+func (p *SQL) tagDatatype(key string) string {
+	if slices.Contains(p.UpsertKeyTags, key) {
+		return p.Convert.Key
+	}
+	return p.Convert.Text
+}
+
 func (p *SQL) generateCreateTable(metric telegraf.Metric) string {
 	columns := make([]string, 0, len(metric.TagList())+len(metric.FieldList())+1)
 
@@ -152,7 +198,7 @@ func (p *SQL) generateCreateTable(metric telegraf.Metric) string {
 	}
 
 	for _, tag := range metric.TagList() {
-		columns = append(columns, fmt.Sprintf("%s %s", quoteIdent(tag.Key), p.Convert.Text))
+		columns = append(columns, fmt.Sprintf("%s %s", quoteIdent(tag.Key), p.tagDatatype(tag.Key)))
 	}
 
 	var datatype string
@@ -161,6 +207,15 @@ func (p *SQL) generateCreateTable(metric telegraf.Metric) string {
 		columns = append(columns, fmt.Sprintf("%s %s", quoteIdent(field.Key), datatype))
 	}
 
+	// Upserts require a unique constraint on the key columns
+	if len(p.UpsertKeyTags) > 0 {
+		keys := make([]string, 0, len(p.UpsertKeyTags))
+		for _, key := range p.UpsertKeyTags {
+			keys = append(keys, quoteIdent(key))
+		}
+		columns = append(columns, fmt.Sprintf("PRIMARY KEY(%s)", strings.Join(keys, ",")))
+	}
+
 	query := p.TableTemplate
 	query = strings.ReplaceAll(query, "{TABLE}", quoteIdent(metric.Name()))
 	query = strings.ReplaceAll(query, "{TABLELITERAL}", quoteStr(metric.Name()))
@@ -169,28 +224,39 @@ func (p *SQL) generateCreateTable(metric telegraf.Metric) string {
 	return query
 }
 
-func (p *SQL) generateInsert(tablename string, columns []string) string {
-	placeholders := make([]string, 0, len(columns))
-	quotedColumns := make([]string, 0, len(columns))
-	for _, column := range columns {
-		quotedColumns = append(quotedColumns, quoteIdent(column))
-	}
+// placeholders returns the placeholders for a row of values starting at the
+// given parameter index
+func (p *SQL) placeholders(offset, n int) string {
+	placeholders := make([]string, 0, n)
 	if p.Driver == "pgx" {
 		// Postgres uses $1 $2 $3 as placeholders
-		for i := 0; i < len(columns); i++ {
-			placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		for i := 0; i < n; i++ {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", offset+i+1))
 		}
 	} else {
 		// Everything else uses ? ? ? as placeholders
-		for i := 0; i < len(columns); i++ {
+		for i := 0; i < n; i++ {
 			placeholders = append(placeholders, "?")
 		}
 	}
+	return strings.Join(placeholders, ",")
+}
 
-	return fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s)",
+func (p *SQL) generateInsert(tablename string, columns []string, rows int) string {
+	quotedColumns := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quotedColumns = append(quotedColumns, quoteIdent(column))
+	}
+
+	values := make([]string, 0, rows)
+	for i := 0; i < rows; i++ {
+		values = append(values, "("+p.placeholders(i*len(columns), len(columns))+")")
+	}
+
+	return fmt.Sprintf("INSERT INTO %s(%s) VALUES%s",
 		quoteIdent(tablename),
 		strings.Join(quotedColumns, ","),
-		strings.Join(placeholders, ","))
+		strings.Join(values, ","))
 }
 
 func (p *SQL) tableExists(tableName string) bool {
@@ -200,22 +266,133 @@ func (p *SQL) tableExists(tableName string) bool {
 	return err == nil
 }
 
-func (p *SQL) Write(metrics []telegraf.Metric) error {
-	var err error
+// updateTable adds columns for the tags and fields of the metrics not
+// existing in the table
+func (p *SQL) updateTable(tablename string, metrics []telegraf.Metric) error {
+	known, found := p.columns[tablename]
+	if !found {
+		var err error
+		if known, err = p.tableColumns(tablename); err != nil {
+			return fmt.Errorf("querying columns of table %q failed: %w", tablename, err)
+		}
+		p.columns[tablename] = known
+	}
 
 	for _, metric := range metrics {
-		tablename := metric.Name()
-
-		// create table if needed
-		if !p.tables[tablename] && !p.tableExists(tablename) {
-			createStmt := p.generateCreateTable(metric)
-			_, err := p.db.Exec(createStmt)
-			if err != nil {
+		for _, tag := range metric.TagList() {
+			if err := p.addColumn(tablename, known, tag.Key, p.tagDatatype(tag.Key)); err != nil {
+				return err
+			}
+		}
+		for _, field := range metric.FieldList() {
+			if err := p.addColumn(tablename, known, field.Key, p.deriveDatatype(field.Value)); err != nil {
 				return err
 			}
 		}
-		p.tables[tablename] = true
+	}
+	return nil
+}
+
+func (p *SQL) addColumn(tablename string, known map[string]bool, column, datatype string) error {
+	// Most databases compare unquoted column names case-insensitively
+	name := strings.ToLower(column)
+	if known[name] {
+		return nil
+	}
+
+	stmt := strings.ReplaceAll(p.TableUpdateTemplate, "{TABLE}", quoteIdent(tablename))
+	stmt = strings.ReplaceAll(stmt, "{TABLELITERAL}", quoteStr(tablename))
+	stmt = strings.ReplaceAll(stmt, "{COLUMN}", fmt.Sprintf("%s %s", quoteIdent(column), datatype))
+	if _, err := p.db.Exec(stmt); err != nil {
+		return fmt.Errorf("adding column %q to table %q failed: %w", column, tablename, err)
+	}
+	known[name] = true
+
+	return nil
+}
+
+// tableColumns returns the lower-case names of the columns of the table
+func (p *SQL) tableColumns(tablename string) (map[string]bool, error) {
+	rows, err := p.db.Query("SELECT * FROM " + quoteIdent(tablename) + " WHERE 1=0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]bool, len(names))
+	for _, name := range names {
+		columns[strings.ToLower(name)] = true
+	}
+	return columns, rows.Err()
+}
+
+func (p *SQL) Write(metrics []telegraf.Metric) error {
+	// Group the metrics per table keeping the order of the tables
+	var tablenames []string
+	tables := make(map[string][]telegraf.Metric)
+	for _, metric := range metrics {
+		tablename := metric.Name()
+		if _, found := tables[tablename]; !found {
+			tablenames = append(tablenames, tablename)
+		}
+		tables[tablename] = append(tables[tablename], metric)
+	}
 
+	for _, tablename := range tablenames {
+		if err := p.writeTable(tablename, tables[tablename]); err != nil {
+			// The table might have been changed externally so reload the
+			// columns on the next write
+			delete(p.columns, tablename)
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *SQL) writeTable(tablename string, metrics []telegraf.Metric) error {
+	// create table if needed
+	if !p.tables[tablename] && !p.tableExists(tablename) {
+		createStmt := p.generateCreateTable(metrics[0])
+		_, err := p.db.Exec(createStmt)
+		if err != nil {
+			return err
+		}
+	}
+	p.tables[tablename] = true
+
+	if p.TableUpdateTemplate != "" {
+		if err := p.updateTable(tablename, metrics); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range p.batches(tablename, metrics) {
+		if err := p.writeBatch(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batch contains the rows for a table with the same set of columns
+type batch struct {
+	table   string
+	columns []string
+	rows    [][]interface{}
+
+	// index of the row of each upsert key to only keep the latest row
+	keys map[string]int
+}
+
+// batches groups the metrics of a table by their set of columns
+func (p *SQL) batches(tablename string, metrics []telegraf.Metric) []*batch {
+	var batches []*batch
+	index := make(map[string]*batch)
+	for _, metric := range metrics {
 		var columns []string
 		var values []interface{}
 
@@ -224,49 +401,141 @@ func (p *SQL) Write(metrics []telegraf.Metric) error {
 			values = append(values, metric.Time())
 		}
 
-		for column, value := range metric.Tags() {
-			columns = append(columns, column)
-			values = append(values, value)
+		for _, tag := range metric.TagList() {
+			columns = append(columns, tag.Key)
+			values = append(values, tag.Value)
 		}
 
-		for column, value := range metric.Fields() {
-			columns = append(columns, column)
-			values = append(values, value)
+		for _, field := range metric.FieldList() {
+			columns = append(columns, field.Key)
+			values = append(values, field.Value)
 		}
 
-		sql := p.generateInsert(tablename, columns)
-
-		switch p.Driver {
-		case "clickhouse":
-			// ClickHouse needs to batch inserts with prepared statements
-			tx, err := p.db.Begin()
-			if err != nil {
-				return fmt.Errorf("begin failed: %w", err)
+		var key string
+		if len(p.UpsertKeyTags) > 0 {
+			var ok bool
+			if key, ok = p.upsertKey(metric); !ok {
+				p.Log.Warnf("Skipping metric %q missing upsert key tags", metric.Name())
+				continue
 			}
-			stmt, err := tx.Prepare(sql)
-			if err != nil {
-				return fmt.Errorf("prepare failed: %w", err)
-			}
-			defer stmt.Close() //nolint:revive,gocritic // done on purpose, closing will be executed properly
+		}
 
-			_, err = stmt.Exec(values...)
-			if err != nil {
-				return fmt.Errorf("execution failed: %w", err)
-			}
-			err = tx.Commit()
-			if err != nil {
-				return fmt.Errorf("commit failed: %w", err)
-			}
-		default:
-			_, err = p.db.Exec(sql, values...)
-			if err != nil {
-				return fmt.Errorf("execution failed: %w", err)
+		id := strings.Join(columns, "\x00")
+		b, found := index[id]
+		if !found {
+			b = &batch{table: tablename, columns: columns, keys: make(map[string]int)}
+			index[id] = b
+			batches = append(batches, b)
+		}
+
+		// Upserting the same key twice within a statement is an error for
+		// most databases, so only keep the latest row
+		if len(p.UpsertKeyTags) > 0 {
+			if i, found := b.keys[key]; found {
+				b.rows[i] = values
+				continue
 			}
+			b.keys[key] = len(b.rows)
+		}
+		b.rows = append(b.rows, values)
+	}
+	return batches
+}
+
+// writeBatch writes the rows of a batch within a transaction
+func (p *SQL) writeBatch(b *batch) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin failed: %w", err)
+	}
+
+	if p.BatchMode == "multi_row" {
+		err = p.execMultiRow(tx, b)
+	} else {
+		err = p.execPrepared(tx, b)
+	}
+	if err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			p.Log.Errorf("Rollback failed: %v", rerr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// execPrepared executes a prepared statement for each row
+func (p *SQL) execPrepared(tx *gosql.Tx, b *batch) error {
+	stmt, err := tx.Prepare(p.generateStatement(b.table, b.columns, 1))
+	if err != nil {
+		return fmt.Errorf("prepare failed: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, values := range b.rows {
+		if _, err := stmt.Exec(values...); err != nil {
+			return fmt.Errorf("execution failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// execMultiRow executes statements inserting multiple rows at once
+func (p *SQL) execMultiRow(tx *gosql.Tx, b *batch) error {
+	size := p.BatchMaxRows
+	if size < 1 {
+		size = 1
+	}
+	// Respect the maximum number of parameters per statement of the driver
+	if limit := maxParameters(p.Driver) / len(b.columns); limit > 0 && limit < size {
+		size = limit
+	}
+
+	for start := 0; start < len(b.rows); start += size {
+		end := start + size
+		if end > len(b.rows) {
+			end = len(b.rows)
+		}
+
+		values := make([]interface{}, 0, (end-start)*len(b.columns))
+		for _, row := range b.rows[start:end] {
+			values = append(values, row...)
+		}
+
+		if _, err := tx.Exec(p.generateStatement(b.table, b.columns, end-start), values...); err != nil {
+			return fmt.Errorf("execution failed: %w", err)
 		}
 	}
 	return nil
 }
 
+// generateStatement generates the insert or upsert statement for the given
+// number of rows
+func (p *SQL) generateStatement(tablename string, columns []string, rows int) string {
+	if len(p.UpsertKeyTags) > 0 {
+		return p.generateUpsert(tablename, columns, rows)
+	}
+	return p.generateInsert(tablename, columns, rows)
+}
+
+// maxParameters returns the maximum number of parameters per statement or
+// zero if unknown
+func maxParameters(driver string) int {
+	switch driver {
+	case "mssql":
+		// The limit is 2100 but the driver might use some parameters
+		return 2000
+	case "sqlite":
+		return 32766
+	case "mysql", "pgx":
+		return 65535
+	}
+	return 0
+}
+
 func init() {
 	outputs.Add("sql", func() telegraf.Output { return newSQL() })
 }
@@ -280,6 +549,7 @@ func newSQL() *SQL {
 			Integer:         "INT",
 			Real:            "DOUBLE",
 			Text:            "TEXT",
+			Key:             "VARCHAR(255)",
 			Timestamp:       "TIMESTAMP",
 			Defaultvalue:    "TEXT",
 			Unsigned:        "UNSIGNED",
@@ -292,5 +562,7 @@ func newSQL() *SQL {
 		// except max idle connections which is 2. See
 		// https://pkg.go.dev/database/sql#DB.SetMaxIdleConns
 		ConnectionMaxIdle: 2,
+		BatchMode:         "transaction",
+		BatchMaxRows:      1000,
 	}
 }
@@ -37,6 +37,121 @@ func TestSqlInsertStatementIntegration(t *testing.T) {
 	}
 }
 
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(p *SQL)
+		expected string
+	}{
+		{
+			name:     "invalid batch mode",
+			modify:   func(p *SQL) { p.BatchMode = "foo" },
+			expected: `invalid batch mode "foo"`,
+		},
+		{
+			name: "multi-row clickhouse",
+			modify: func(p *SQL) {
+				p.Driver = "clickhouse"
+				p.BatchMode = "multi_row"
+			},
+			expected: "not supported for clickhouse",
+		},
+		{
+			name: "upsert clickhouse",
+			modify: func(p *SQL) {
+				p.Driver = "clickhouse"
+				p.UpsertKeyTags = []string{"host"}
+			},
+			expected: `upserts are not supported for driver "clickhouse"`,
+		},
+		{
+			name: "upsert without key type",
+			modify: func(p *SQL) {
+				p.UpsertKeyTags = []string{"host"}
+				p.Convert.Key = ""
+			},
+			expected: "'convert.key' required for upserts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newSQL()
+			p.Driver = "sqlite"
+			tt.modify(p)
+			require.ErrorContains(t, p.Init(), tt.expected)
+		})
+	}
+}
+
+func TestGenerateStatement(t *testing.T) {
+	columns := []string{"timestamp", "host", "value"}
+	tests := []struct {
+		driver   string
+		upsert   bool
+		expected string
+	}{
+		{
+			driver:   "pgx",
+			expected: `INSERT INTO "test"("timestamp","host","value") VALUES($1,$2,$3),($4,$5,$6)`,
+		},
+		{
+			driver:   "mysql",
+			expected: `INSERT INTO "test"("timestamp","host","value") VALUES(?,?,?),(?,?,?)`,
+		},
+		{
+			driver: "pgx",
+			upsert: true,
+			expected: `INSERT INTO "test"("timestamp","host","value") VALUES($1,$2,$3),($4,$5,$6) ` +
+				`ON CONFLICT("host") DO UPDATE SET "timestamp"=excluded."timestamp","value"=excluded."value"`,
+		},
+		{
+			driver: "mysql",
+			upsert: true,
+			expected: `INSERT INTO "test"("timestamp","host","value") VALUES(?,?,?),(?,?,?) ` +
+				`ON DUPLICATE KEY UPDATE "timestamp"=VALUES("timestamp"),"value"=VALUES("value")`,
+		},
+		{
+			driver: "mssql",
+			upsert: true,
+			expected: `MERGE INTO "test" AS dst USING (SELECT ? AS "timestamp",? AS "host",? AS "value" UNION ALL SELECT ?,?,?) AS src ` +
+				`ON dst."host"=src."host" WHEN MATCHED THEN UPDATE SET "timestamp"=src."timestamp","value"=src."value" ` +
+				`WHEN NOT MATCHED THEN INSERT("timestamp","host","value") VALUES(src."timestamp",src."host",src."value");`,
+		},
+	}
+
+	for _, tt := range tests {
+		name := tt.driver
+		if tt.upsert {
+			name += " upsert"
+		}
+		t.Run(name, func(t *testing.T) {
+			p := newSQL()
+			p.Driver = tt.driver
+			if tt.upsert {
+				p.UpsertKeyTags = []string{"host"}
+			}
+			require.NoError(t, p.Init())
+			require.Equal(t, tt.expected, p.generateStatement("test", columns, 2))
+		})
+	}
+}
+
+func TestGenerateCreateTableUpsert(t *testing.T) {
+	p := newSQL()
+	p.Driver = "mysql"
+	p.UpsertKeyTags = []string{"host"}
+	require.NoError(t, p.Init())
+
+	m := metric.New("test",
+		map[string]string{"host": "a", "region": "b"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0),
+	)
+	expected := `CREATE TABLE "test"("timestamp" TIMESTAMP,"host" VARCHAR(255),"region" TEXT,"value" DOUBLE,PRIMARY KEY("host"))`
+	require.Equal(t, expected, p.generateCreateTable(m))
+}
+
 func pwgen(n int) string {
 	charset := []byte("abcdedfghijklmnopqrstABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 
@@ -238,6 +353,67 @@ func TestMysqlIntegration(t *testing.T) {
 	}
 }
 
+func TestMysqlUpsertIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	initdb, err := filepath.Abs("testdata/mariadb/initdb/script.sql")
+	require.NoError(t, err)
+
+	// initdb/script.sql creates this database
+	const dbname = "foo"
+	const username = "root"
+	password := pwgen(32)
+
+	servicePort := "3306"
+	container := testutil.Container{
+		Image: "mariadb",
+		Env: map[string]string{
+			"MARIADB_ROOT_PASSWORD": password,
+		},
+		Files: map[string]string{
+			"/docker-entrypoint-initdb.d/script.sql": initdb,
+		},
+		ExposedPorts: []string{servicePort},
+		WaitingFor: wait.ForAll(
+			wait.ForListeningPort(nat.Port(servicePort)),
+			wait.ForLog("mariadbd: ready for connections.").WithOccurrence(2),
+		),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	p := newSQL()
+	p.Log = testutil.Logger{}
+	p.Driver = "mysql"
+	p.DataSourceName = fmt.Sprintf("%v:%v@tcp(%v:%v)/%v",
+		username, password, container.Address, container.Ports[servicePort], dbname,
+	)
+	p.InitSQL = "SET sql_mode='ANSI_QUOTES';"
+	p.UpsertKeyTags = []string{"host"}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	// The first write creates the table with a primary key on the key tag,
+	// the second one updates the existing row
+	for _, value := range []int64{1, 2} {
+		m := metric.New("upsert",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": value},
+			ts,
+		)
+		require.NoError(t, p.Write([]telegraf.Metric{m}))
+	}
+
+	var count, value int64
+	row := p.db.QueryRow(`SELECT COUNT(*), MAX("value") FROM "upsert"`)
+	require.NoError(t, row.Scan(&count, &value))
+	require.Equal(t, int64(1), count)
+	require.Equal(t, int64(2), value)
+}
+
 func TestPostgresIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -11,6 +11,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -128,3 +130,128 @@ func TestSqlite(t *testing.T) {
 	require.Equal(t, "string2", k)
 	require.False(t, rows4.Next())
 }
+
+func TestSqliteBatchModes(t *testing.T) {
+	for _, mode := range []string{"transaction", "multi_row"} {
+		t.Run(mode, func(t *testing.T) {
+			address := filepath.Join(t.TempDir(), "db")
+			p := newSQL()
+			p.Log = testutil.Logger{}
+			p.Driver = "sqlite"
+			p.DataSourceName = address
+			p.BatchMode = mode
+			p.BatchMaxRows = 3
+			require.NoError(t, p.Init())
+
+			metrics := make([]telegraf.Metric, 0, 10)
+			for i := int64(0); i < 10; i++ {
+				// Alternate the set of fields to write multiple batches
+				fields := map[string]interface{}{"value": i}
+				if i%2 == 0 {
+					fields["even"] = true
+				}
+				metrics = append(metrics, metric.New("test", map[string]string{"host": "a"}, fields, ts))
+			}
+
+			require.NoError(t, p.Connect())
+			defer p.Close()
+			// The first metric defines the table so all columns exist
+			require.NoError(t, p.Write(metrics))
+
+			db, err := gosql.Open("sqlite", address)
+			require.NoError(t, err)
+			defer db.Close()
+
+			var count, sum int
+			require.NoError(t, db.QueryRow("select count(*), sum(value) from test").Scan(&count, &sum))
+			require.Equal(t, 10, count)
+			require.Equal(t, 45, sum)
+
+			var even int
+			require.NoError(t, db.QueryRow("select count(*) from test where even").Scan(&even))
+			require.Equal(t, 5, even)
+		})
+	}
+}
+
+func TestSqliteUpsert(t *testing.T) {
+	for _, mode := range []string{"transaction", "multi_row"} {
+		t.Run(mode, func(t *testing.T) {
+			address := filepath.Join(t.TempDir(), "db")
+			p := newSQL()
+			p.Log = testutil.Logger{}
+			p.Driver = "sqlite"
+			p.DataSourceName = address
+			p.BatchMode = mode
+			p.UpsertKeyTags = []string{"host"}
+			require.NoError(t, p.Init())
+
+			require.NoError(t, p.Connect())
+			defer p.Close()
+
+			// Duplicate keys within a write are reduced to the latest metric
+			require.NoError(t, p.Write([]telegraf.Metric{
+				metric.New("state", map[string]string{"host": "a"}, map[string]interface{}{"up": int64(1)}, ts),
+				metric.New("state", map[string]string{"host": "b"}, map[string]interface{}{"up": int64(1)}, ts),
+				metric.New("state", map[string]string{"host": "a"}, map[string]interface{}{"up": int64(2)}, ts),
+				metric.New("state", map[string]string{}, map[string]interface{}{"up": int64(3)}, ts),
+			}))
+			require.NoError(t, p.Write([]telegraf.Metric{
+				metric.New("state", map[string]string{"host": "b"}, map[string]interface{}{"up": int64(0)}, ts),
+			}))
+
+			db, err := gosql.Open("sqlite", address)
+			require.NoError(t, err)
+			defer db.Close()
+
+			rows, err := db.Query("select host, up from state order by host")
+			require.NoError(t, err)
+			defer rows.Close()
+
+			actual := make(map[string]int64)
+			for rows.Next() {
+				var host string
+				var up int64
+				require.NoError(t, rows.Scan(&host, &up))
+				actual[host] = up
+			}
+			require.NoError(t, rows.Err())
+			require.Equal(t, map[string]int64{"a": 2, "b": 0}, actual)
+		})
+	}
+}
+
+func TestSqliteAddColumns(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "db")
+	p := newSQL()
+	p.Log = testutil.Logger{}
+	p.Driver = "sqlite"
+	p.DataSourceName = address
+	p.TableUpdateTemplate = "ALTER TABLE {TABLE} ADD COLUMN {COLUMN}"
+	require.NoError(t, p.Init())
+
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("test", map[string]string{"host": "a"}, map[string]interface{}{"value": int64(1)}, ts),
+		metric.New("test", map[string]string{"host": "a", "region": "eu"}, map[string]interface{}{"value": int64(2), "extra": 1.5}, ts),
+	}))
+
+	db, err := gosql.Open("sqlite", address)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var sql string
+	require.NoError(t, db.QueryRow("select sql from sqlite_master where name = 'test'").Scan(&sql))
+	require.Equal(t,
+		`CREATE TABLE "test"("timestamp" TIMESTAMP,"host" TEXT,"value" INT, "region" TEXT, "extra" DOUBLE)`,
+		sql,
+	)
+
+	var region string
+	var extra float64
+	require.NoError(t, db.QueryRow("select region, extra from test where value = 2").Scan(&region, &extra))
+	require.Equal(t, "eu", region)
+	require.InDelta(t, 1.5, extra, 1e-9)
+}
@@ -0,0 +1,121 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// upsertKey returns the values of the upsert key tags of the metric and
+// whether all key tags exist
+func (p *SQL) upsertKey(metric telegraf.Metric) (string, bool) {
+	var b strings.Builder
+	for _, key := range p.UpsertKeyTags {
+		v, found := metric.GetTag(key)
+		if !found {
+			return "", false
+		}
+		b.WriteString(v)
+		b.WriteByte(0)
+	}
+	return b.String(), true
+}
+
+// generateUpsert generates a statement inserting the given number of rows or
+// updating the existing rows with the same upsert key using the syntax of
+// the driver
+func (p *SQL) generateUpsert(tablename string, columns []string, rows int) string {
+	isKey := make(map[string]bool, len(p.UpsertKeyTags))
+	keys := make([]string, 0, len(p.UpsertKeyTags))
+	for _, key := range p.UpsertKeyTags {
+		isKey[key] = true
+		keys = append(keys, quoteIdent(key))
+	}
+	var values []string
+	for _, column := range columns {
+		if !isKey[column] {
+			values = append(values, quoteIdent(column))
+		}
+	}
+
+	switch p.Driver {
+	case "mysql":
+		updates := make([]string, 0, len(values))
+		for _, column := range values {
+			updates = append(updates, fmt.Sprintf("%s=VALUES(%s)", column, column))
+		}
+		if len(updates) == 0 {
+			// Nothing to update, but the clause requires an assignment
+			updates = append(updates, fmt.Sprintf("%s=%s", keys[0], keys[0]))
+		}
+		return p.generateInsert(tablename, columns, rows) + " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ",")
+	case "mssql", "snowflake":
+		return p.generateMerge(tablename, columns, keys, values, rows)
+	}
+
+	// Postgres and SQLite
+	if len(values) == 0 {
+		return p.generateInsert(tablename, columns, rows) + fmt.Sprintf(" ON CONFLICT(%s) DO NOTHING", strings.Join(keys, ","))
+	}
+	updates := make([]string, 0, len(values))
+	for _, column := range values {
+		updates = append(updates, fmt.Sprintf("%s=excluded.%s", column, column))
+	}
+	return p.generateInsert(tablename, columns, rows) +
+		fmt.Sprintf(" ON CONFLICT(%s) DO UPDATE SET %s", strings.Join(keys, ","), strings.Join(updates, ","))
+}
+
+// generateMerge generates a MERGE statement for databases not supporting
+// upserts as part of an INSERT statement
+func (p *SQL) generateMerge(tablename string, columns, keys, values []string, rows int) string {
+	quotedColumns := make([]string, 0, len(columns))
+	sourceColumns := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quotedColumns = append(quotedColumns, quoteIdent(column))
+		sourceColumns = append(sourceColumns, "src."+quoteIdent(column))
+	}
+
+	// The source rows are a union of selects of the parameters
+	selects := make([]string, 0, rows)
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			selects = append(selects, "SELECT "+p.placeholders(i*len(columns), len(columns)))
+			continue
+		}
+		aliased := make([]string, 0, len(columns))
+		for _, column := range quotedColumns {
+			aliased = append(aliased, "? AS "+column)
+		}
+		selects = append(selects, "SELECT "+strings.Join(aliased, ","))
+	}
+
+	conditions := make([]string, 0, len(keys))
+	for _, key := range keys {
+		conditions = append(conditions, fmt.Sprintf("dst.%s=src.%s", key, key))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "MERGE INTO %s AS dst USING (%s) AS src ON %s",
+		quoteIdent(tablename),
+		strings.Join(selects, " UNION ALL "),
+		strings.Join(conditions, " AND "),
+	)
+	if len(values) > 0 {
+		updates := make([]string, 0, len(values))
+		for _, column := range values {
+			updates = append(updates, fmt.Sprintf("%s=src.%s", column, column))
+		}
+		fmt.Fprintf(&b, " WHEN MATCHED THEN UPDATE SET %s", strings.Join(updates, ","))
+	}
+	fmt.Fprintf(&b, " WHEN NOT MATCHED THEN INSERT(%s) VALUES(%s)",
+		strings.Join(quotedColumns, ","),
+		strings.Join(sourceColumns, ","),
+	)
+
+	// SQL Server requires MERGE statements to be terminated
+	if p.Driver == "mssql" {
+		b.WriteString(";")
+	}
+	return b.String()
+}
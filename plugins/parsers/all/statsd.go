@@ -0,0 +1,5 @@
+//go:build !custom || parsers || parsers.statsd
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/parsers/statsd" // register plugin
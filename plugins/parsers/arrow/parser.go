@@ -0,0 +1,209 @@
+package arrow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/arrow"
+)
+
+type Parser struct {
+	MeasurementColumn string   `toml:"arrow_measurement_column"`
+	TagColumns        []string `toml:"arrow_tag_columns"`
+	TimestampColumn   string   `toml:"arrow_timestamp_column"`
+
+	defaultTags map[string]string
+	metricName  string
+	mem         memory.Allocator
+}
+
+func (p *Parser) Init() error {
+	if p.TimestampColumn == "" {
+		p.TimestampColumn = "time"
+	}
+	p.mem = memory.NewGoAllocator()
+
+	return nil
+}
+
+// Parse decodes one or more concatenated Arrow IPC streams into metrics.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	now := time.Now()
+	reader := bytes.NewReader(buf)
+
+	var metrics []telegraf.Metric
+	for reader.Len() > 0 {
+		m, err := p.parseStream(reader, now)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m...)
+	}
+
+	return metrics, nil
+}
+
+func (p *Parser) parseStream(r *bytes.Reader, now time.Time) ([]telegraf.Metric, error) {
+	reader, err := ipc.NewReader(r, ipc.WithAllocator(p.mem))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create arrow reader: %w", err)
+	}
+	defer reader.Release()
+
+	schema := reader.Schema()
+	name := p.metricName
+	if v, ok := schema.Metadata().GetValue(serializer.MetadataMeasurement); ok && v != "" {
+		name = v
+	}
+
+	roles := make([]string, schema.NumFields())
+	for i, col := range schema.Fields() {
+		roles[i] = p.role(col)
+	}
+
+	var metrics []telegraf.Metric
+	for reader.Next() {
+		record := reader.Record()
+		for row := range int(record.NumRows()) {
+			m := metric.New(name, p.defaultTags, nil, now)
+			for i, col := range record.Columns() {
+				if col.IsNull(row) {
+					continue
+				}
+				value, err := columnValue(col, row)
+				if err != nil {
+					return nil, fmt.Errorf("column %q: %w", schema.Field(i).Name, err)
+				}
+				if err := p.apply(m, schema.Field(i).Name, roles[i], value); err != nil {
+					return nil, fmt.Errorf("column %q: %w", schema.Field(i).Name, err)
+				}
+			}
+
+			// Metrics without fields are invalid, e.g. for rows with all
+			// field columns being null
+			if len(m.FieldList()) == 0 {
+				continue
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return nil, fmt.Errorf("reading record failed: %w", err)
+	}
+
+	return metrics, nil
+}
+
+func (p *Parser) role(col arrow.Field) string {
+	switch {
+	case p.MeasurementColumn != "" && col.Name == p.MeasurementColumn:
+		return "measurement"
+	case col.Name == p.TimestampColumn:
+		return serializer.RoleTimestamp
+	case slices.Contains(p.TagColumns, col.Name):
+		return serializer.RoleTag
+	}
+	if role, ok := col.Metadata.GetValue(serializer.MetadataRole); ok {
+		return role
+	}
+	return serializer.RoleField
+}
+
+func (p *Parser) apply(m telegraf.Metric, key, role string, value interface{}) error {
+	switch role {
+	case "measurement":
+		name, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("measurement must be a string but is %T", value)
+		}
+		m.SetName(name)
+	case serializer.RoleTimestamp:
+		switch v := value.(type) {
+		case time.Time:
+			m.SetTime(v)
+		case int64:
+			m.SetTime(time.Unix(0, v))
+		default:
+			return fmt.Errorf("unsupported timestamp type %T", value)
+		}
+	case serializer.RoleTag:
+		m.AddTag(key, fmt.Sprintf("%v", value))
+	default:
+		m.AddField(key, value)
+	}
+	return nil
+}
+
+func columnValue(col arrow.Array, row int) (interface{}, error) {
+	switch c := col.(type) {
+	case *array.Boolean:
+		return c.Value(row), nil
+	case *array.Int8:
+		return int64(c.Value(row)), nil
+	case *array.Int16:
+		return int64(c.Value(row)), nil
+	case *array.Int32:
+		return int64(c.Value(row)), nil
+	case *array.Int64:
+		return c.Value(row), nil
+	case *array.Uint8:
+		return uint64(c.Value(row)), nil
+	case *array.Uint16:
+		return uint64(c.Value(row)), nil
+	case *array.Uint32:
+		return uint64(c.Value(row)), nil
+	case *array.Uint64:
+		return c.Value(row), nil
+	case *array.Float32:
+		return float64(c.Value(row)), nil
+	case *array.Float64:
+		return c.Value(row), nil
+	case *array.String:
+		return c.Value(row), nil
+	case *array.LargeString:
+		return c.Value(row), nil
+	case *array.Timestamp:
+		unit := c.DataType().(*arrow.TimestampType).Unit
+		return c.Value(row).ToTime(unit), nil
+	}
+	return nil, fmt.Errorf("unsupported data type %s", col.DataType())
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, nil
+	}
+	if len(metrics) > 1 {
+		return nil, errors.New("line contains multiple metrics")
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func init() {
+	parsers.Add("arrow",
+		func(defaultMetricName string) telegraf.Parser {
+			return &Parser{metricName: defaultMetricName}
+		},
+	)
+}
@@ -0,0 +1,184 @@
+package arrow
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/arrow"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestRoundTrip(t *testing.T) {
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5, "count": int64(3)},
+			time.Unix(1700000000, 123),
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 23.0, "ok": true},
+			time.Unix(1700000001, 0),
+		),
+		metric.New(
+			"mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"used": uint64(1024), "state": "ok"},
+			time.Unix(1700000000, 0),
+		),
+	}
+
+	s := &serializer.Serializer{Compression: "zstd", Log: testutil.Logger{}}
+	require.NoError(t, s.Init())
+	buf, err := s.SerializeBatch(expected)
+	require.NoError(t, err)
+
+	p := &Parser{metricName: "arrow"}
+	require.NoError(t, p.Init())
+	actual, err := p.Parse(buf)
+	require.NoError(t, err)
+
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestDefaultTags(t *testing.T) {
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "source": "test"},
+			map[string]interface{}{"usage": 42.5},
+			time.Unix(1700000000, 0),
+		),
+	}
+
+	s := &serializer.Serializer{Log: testutil.Logger{}}
+	require.NoError(t, s.Init())
+	buf, err := s.Serialize(metric.New(
+		"cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 42.5},
+		time.Unix(1700000000, 0),
+	))
+	require.NoError(t, err)
+
+	p := &Parser{metricName: "arrow"}
+	require.NoError(t, p.Init())
+	p.SetDefaultTags(map[string]string{"source": "test"})
+	actual, err := p.Parse(buf)
+	require.NoError(t, err)
+
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestForeignStream(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "ts", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "site", Type: arrow.BinaryTypes.String},
+		{Name: "temperature", Type: arrow.PrimitiveTypes.Float32},
+		{Name: "errors", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+	}, nil)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.StringBuilder).AppendValues([]string{"sensor", "sensor"}, nil)
+	builder.Field(1).(*array.Int64Builder).AppendValues([]int64{1000, 2000}, nil)
+	builder.Field(2).(*array.StringBuilder).AppendValues([]string{"north", "south"}, nil)
+	builder.Field(3).(*array.Float32Builder).AppendValues([]float32{21.5, 19.25}, nil)
+	builder.Field(4).(*array.Int32Builder).AppendValues([]int32{0, 5}, []bool{false, true})
+	record := builder.NewRecord()
+	defer record.Release()
+
+	var buf bytes.Buffer
+	writer := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"sensor",
+			map[string]string{"site": "north"},
+			map[string]interface{}{"temperature": float64(21.5)},
+			time.Unix(0, 1000),
+		),
+		metric.New(
+			"sensor",
+			map[string]string{"site": "south"},
+			map[string]interface{}{"temperature": float64(19.25), "errors": int64(5)},
+			time.Unix(0, 2000),
+		),
+	}
+
+	p := &Parser{
+		MeasurementColumn: "name",
+		TagColumns:        []string{"site"},
+		TimestampColumn:   "ts",
+		metricName:        "arrow",
+	}
+	require.NoError(t, p.Init())
+	actual, err := p.Parse(buf.Bytes())
+	require.NoError(t, err)
+
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestNullFieldsRow(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "ts", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "site", Type: arrow.BinaryTypes.String},
+		{Name: "temperature", Type: arrow.PrimitiveTypes.Float32, Nullable: true},
+		{Name: "errors", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+	}, nil)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Int64Builder).AppendValues([]int64{1000, 2000}, nil)
+	builder.Field(1).(*array.StringBuilder).AppendValues([]string{"north", "south"}, nil)
+	builder.Field(2).(*array.Float32Builder).AppendValues([]float32{21.5, 0}, []bool{true, false})
+	builder.Field(3).(*array.Int32Builder).AppendValues([]int32{0, 0}, []bool{false, false})
+	record := builder.NewRecord()
+	defer record.Release()
+
+	var buf bytes.Buffer
+	writer := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"arrow",
+			map[string]string{"site": "north"},
+			map[string]interface{}{"temperature": float64(21.5)},
+			time.Unix(0, 1000),
+		),
+	}
+
+	p := &Parser{
+		TagColumns:      []string{"site"},
+		TimestampColumn: "ts",
+		metricName:      "arrow",
+	}
+	require.NoError(t, p.Init())
+	actual, err := p.Parse(buf.Bytes())
+	require.NoError(t, err)
+
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestInvalidData(t *testing.T) {
+	p := &Parser{metricName: "arrow"}
+	require.NoError(t, p.Init())
+	_, err := p.Parse([]byte("this is not arrow"))
+	require.ErrorContains(t, err, "unable to create arrow reader")
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
+	common_binary "github.com/influxdata/telegraf/plugins/common/binary"
 )
 
 type BinaryPart struct {
@@ -28,9 +29,10 @@ type Filter struct {
 }
 
 type Config struct {
-	MetricName string  `toml:"metric_name"`
-	Filter     *Filter `toml:"filter"`
-	Entries    []Entry `toml:"entries"`
+	MetricName string                `toml:"metric_name"`
+	Filter     *Filter               `toml:"filter"`
+	Entries    []Entry               `toml:"entries"`
+	Layout     *common_binary.Layout `toml:"layout"`
 }
 
 func (c *Config) preprocess(defaultName string) error {
@@ -66,6 +68,26 @@ func (c *Config) preprocess(defaultName string) error {
 		}
 	}
 
+	// Preprocess the layout if any
+	if c.Layout != nil {
+		if len(c.Entries) > 0 {
+			return errors.New("entries and layout cannot be used together")
+		}
+		if err := c.Layout.Init(); err != nil {
+			return fmt.Errorf("layout invalid: %w", err)
+		}
+		if !c.Layout.HasAssignment("measurement") && c.MetricName == "" {
+			if defaultName == "" {
+				return errors.New("no metric name given")
+			}
+			c.MetricName = defaultName
+		}
+		if !c.Layout.HasAssignment("field") {
+			return errors.New("no field defined")
+		}
+		return nil
+	}
+
 	// Preprocess entries part
 	var hasField, hasMeasurement bool
 	defined := make(map[string]bool)
@@ -138,6 +160,14 @@ func (c *Config) matches(in []byte) bool {
 }
 
 func (c *Config) collect(in []byte, order binary.ByteOrder, defaultTime time.Time) (telegraf.Metric, error) {
+	if c.Layout != nil {
+		m := metric.New(c.MetricName, nil, nil, defaultTime)
+		if err := c.Layout.Decode(in, order, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
 	t := defaultTime
 	name := c.MetricName
 	tags := make(map[string]string)
@@ -58,10 +58,38 @@ func (e *ParseError) Error() string {
 	return fmt.Sprintf("metric parse error: %s at %d:%d: %q", e.msg, e.LineNumber, e.Column, buffer)
 }
 
+// PartialParseError is returned by the parser in lenient mode if some of the
+// lines could not be parsed. It contains the errors of all invalid lines in
+// the order of occurrence.
+type PartialParseError struct {
+	Errors []*ParseError
+}
+
+func (e *PartialParseError) Error() string {
+	switch len(e.Errors) {
+	case 0:
+		return "partial parse error"
+	case 1:
+		return e.Errors[0].Error()
+	case 2:
+		return e.Errors[0].Error() + " (and 1 other parse error)"
+	}
+	return fmt.Sprintf("%s (and %d other parse errors)", e.Errors[0].Error(), len(e.Errors)-1)
+}
+
+func (e *PartialParseError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 // Parser is an InfluxDB Line Protocol parser that implements the
 // parsers.Parser interface.
 type Parser struct {
 	InfluxTimestampPrecision config.Duration   `toml:"influx_timestamp_precision"`
+	InfluxParseMode          string            `toml:"influx_parse_mode"`
 	DefaultTags              map[string]string `toml:"-"`
 	// If set to "series" a series machine will be initialized, defaults to regular machine
 	Type string `toml:"-"`
@@ -85,6 +113,7 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 	metrics := make([]telegraf.Metric, 0)
 	p.machine.SetData(input)
 
+	var errs []*ParseError
 	for {
 		err := p.machine.Next()
 		if errors.Is(err, EOF) {
@@ -92,7 +121,7 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 		}
 
 		if err != nil {
-			return nil, &ParseError{
+			perr := &ParseError{
 				Offset:     p.machine.Position(),
 				LineOffset: p.machine.LineOffset(),
 				LineNumber: p.machine.LineNumber(),
@@ -100,6 +129,12 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 				msg:        err.Error(),
 				buf:        string(input),
 			}
+			if p.InfluxParseMode != "lenient" {
+				return nil, perr
+			}
+			// Skip the invalid line and continue with the next one
+			errs = append(errs, perr)
+			continue
 		}
 
 		metric := p.handler.Metric()
@@ -111,6 +146,9 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 	}
 
 	p.applyDefaultTags(metrics)
+	if len(errs) > 0 {
+		return metrics, &PartialParseError{Errors: errs}
+	}
 	return metrics, nil
 }
 
@@ -150,6 +188,14 @@ func (p *Parser) applyDefaultTagsSingle(metric telegraf.Metric) {
 }
 
 func (p *Parser) Init() error {
+	switch p.InfluxParseMode {
+	case "":
+		p.InfluxParseMode = "strict"
+	case "strict", "lenient":
+	default:
+		return fmt.Errorf("invalid parse mode %q", p.InfluxParseMode)
+	}
+
 	p.handler = NewMetricHandler()
 	if p.Type == "series" {
 		p.machine = NewSeriesMachine(p.handler)
@@ -878,6 +878,45 @@ func TestParserErrorString(t *testing.T) {
 	}
 }
 
+func TestParserLenient(t *testing.T) {
+	input := []byte("cpu value=42\ncpu value=invalid\ncpu value=43\nmem free=\ncpu value=44")
+
+	parser := Parser{InfluxParseMode: "lenient"}
+	require.NoError(t, parser.Init())
+	parser.SetTimeFunc(DefaultTime)
+
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(42, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 43.0}, time.Unix(42, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 44.0}, time.Unix(42, 0)),
+	}
+
+	actual, err := parser.Parse(input)
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	var perr *PartialParseError
+	require.ErrorAs(t, err, &perr)
+	require.Len(t, perr.Errors, 2)
+	require.Equal(t, 2, perr.Errors[0].LineNumber)
+	require.Equal(t, 11, perr.Errors[0].Column)
+	require.Equal(t, 4, perr.Errors[1].LineNumber)
+	require.Equal(t, 10, perr.Errors[1].Column)
+	require.Equal(t, `metric parse error: expected field at 2:11: "cpu value=invalid" (and 1 other parse error)`, err.Error())
+
+	// Strict mode must still reject the whole input
+	parser = Parser{}
+	require.NoError(t, parser.Init())
+	actual, err = parser.Parse(input)
+	require.Empty(t, actual)
+	var single *ParseError
+	require.ErrorAs(t, err, &single)
+}
+
+func TestParserInvalidParseMode(t *testing.T) {
+	parser := Parser{InfluxParseMode: "foo"}
+	require.ErrorContains(t, parser.Init(), `invalid parse mode "foo"`)
+}
+
 func TestStreamParserErrorString(t *testing.T) {
 	var ptests = []struct {
 		name  string
@@ -0,0 +1,350 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/templating"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+const defaultFieldName = "value"
+
+var (
+	sanitizeWhitespace = regexp.MustCompile(`\s+`)
+	sanitizeChars      = regexp.MustCompile(`[^a-zA-Z_\-0-9\.;=]`)
+)
+
+// MetricTypes maps the statsd type identifiers to the value of the
+// `metric_type` tag.
+var MetricTypes = map[string]string{
+	"c":  "counter",
+	"g":  "gauge",
+	"s":  "set",
+	"ms": "timing",
+	"h":  "histogram",
+	"d":  "distribution",
+}
+
+// Parser parses statsd lines, including the DataDog extensions, into one
+// metric per value without any aggregation.
+type Parser struct {
+	DataDogExtensions       bool            `toml:"statsd_datadog_extensions"`
+	DataDogKeepContainerTag bool            `toml:"statsd_datadog_keep_container_tag"`
+	MetricSeparator         string          `toml:"statsd_metric_separator"`
+	Templates               []string        `toml:"statsd_templates"`
+	ConvertNames            bool            `toml:"statsd_convert_names"`
+	SanitizeNamesMethod     string          `toml:"statsd_sanitize_name_method"`
+	Log                     telegraf.Logger `toml:"-"`
+
+	defaultTags    map[string]string
+	templateEngine *templating.Engine
+}
+
+func (p *Parser) Init() error {
+	if p.MetricSeparator == "" {
+		p.MetricSeparator = "_"
+	}
+
+	switch p.SanitizeNamesMethod {
+	case "", "upstream":
+	default:
+		return fmt.Errorf("invalid sanitize name method %q", p.SanitizeNamesMethod)
+	}
+
+	defaultTemplate, err := templating.NewDefaultTemplateWithPattern("measurement*")
+	if err != nil {
+		return fmt.Errorf("creating template failed: %w", err)
+	}
+
+	p.templateEngine, err = templating.NewEngine(p.MetricSeparator, defaultTemplate, p.Templates)
+	if err != nil {
+		return fmt.Errorf("creating template engine failed: %w", err)
+	}
+
+	return nil
+}
+
+// Parse converts the given statsd lines to metrics. Invalid lines are
+// skipped and reported in the returned error, all valid metrics are returned.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	now := time.Now()
+
+	var metrics []telegraf.Metric
+	var errs []error
+	for _, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case p.DataDogExtensions && (strings.HasPrefix(line, "_e") || strings.HasPrefix(line, "_sc")):
+			p.Log.Debugf("Ignoring DataDog event or service-check: %s", line)
+			continue
+		}
+
+		m, err := p.parseLine(line, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		metrics = append(metrics, m...)
+	}
+
+	return metrics, errors.Join(errs...)
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, nil
+	}
+	if len(metrics) > 1 {
+		return nil, errors.New("line contains multiple metrics")
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseLine(line string, now time.Time) ([]telegraf.Metric, error) {
+	lineTags := make(map[string]string)
+	if p.DataDogExtensions {
+		// DataDog tags look like
+		//   users.online:1|c|@0.5|#country:china,environment:production
+		//   users.online:1|c|#sometagwithnovalue
+		// so split on the pipe, remove and parse the tag and container
+		// segments and rebuild the line without those
+		segments := strings.Split(line, "|")
+		remaining := make([]string, 0, len(segments))
+		for _, segment := range segments {
+			switch {
+			case strings.HasPrefix(segment, "#"):
+				parseDataDogTags(lineTags, segment[1:])
+			case strings.HasPrefix(segment, "c:"):
+				if p.DataDogKeepContainerTag {
+					lineTags["container"] = segment[2:]
+				}
+			default:
+				remaining = append(remaining, segment)
+			}
+		}
+		line = strings.Join(remaining, "|")
+	}
+
+	bits := strings.Split(line, ":")
+	if len(bits) < 2 {
+		return nil, fmt.Errorf("splitting ':', unable to parse metric %q", line)
+	}
+	bucket, bits := bits[0], bits[1:]
+	name, field, tags, err := p.parseName(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("parsing bucket %q failed: %w", bucket, err)
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(bits))
+	for _, bit := range bits {
+		parts := strings.Split(bit, "|")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("splitting '|', unable to parse metric %q", line)
+		}
+
+		mtype := parts[1]
+		typeName, found := MetricTypes[mtype]
+		if !found {
+			return nil, fmt.Errorf("metric type %q unsupported", mtype)
+		}
+
+		samplerate := 1.0
+		if len(parts) > 2 {
+			sr := parts[2]
+			if !strings.HasPrefix(sr, "@") || len(sr) < 2 {
+				return nil, fmt.Errorf("invalid sample rate %q", sr)
+			}
+			samplerate, err = strconv.ParseFloat(sr[1:], 64)
+			if err != nil || samplerate <= 0 {
+				return nil, fmt.Errorf("invalid sample rate %q", sr)
+			}
+		}
+
+		raw := parts[0]
+		if (strings.HasPrefix(raw, "-") || strings.HasPrefix(raw, "+")) && mtype != "g" && mtype != "c" {
+			return nil, fmt.Errorf("+- values are only supported for gauges & counters, unable to parse metric %q", line)
+		}
+
+		var value interface{}
+		var vtype telegraf.ValueType
+		switch mtype {
+		case "c":
+			v, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				f, ferr := strconv.ParseFloat(raw, 64)
+				if ferr != nil {
+					return nil, fmt.Errorf("parsing counter value %q failed: %w", raw, err)
+				}
+				v = int64(f)
+			}
+			// Scale the counter by the sample rate
+			value = int64(float64(v) / samplerate)
+			vtype = telegraf.Counter
+		case "g":
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing gauge value %q failed: %w", raw, err)
+			}
+			// Signed values change the current value of the gauge, so mark
+			// them to be distinguishable from absolute values
+			if strings.HasPrefix(raw, "-") || strings.HasPrefix(raw, "+") {
+				typeName = "gauge_delta"
+			}
+			value = v
+			vtype = telegraf.Gauge
+		case "s":
+			value = raw
+			vtype = telegraf.Untyped
+		default:
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s value %q failed: %w", typeName, raw, err)
+			}
+			value = v
+			vtype = telegraf.Histogram
+		}
+
+		mtags := make(map[string]string, len(p.defaultTags)+len(tags)+len(lineTags)+1)
+		maps.Copy(mtags, p.defaultTags)
+		maps.Copy(mtags, tags)
+		maps.Copy(mtags, lineTags)
+		mtags["metric_type"] = typeName
+
+		metrics = append(metrics, metric.New(name, mtags, map[string]interface{}{field: value}, now, vtype))
+	}
+
+	return metrics, nil
+}
+
+// parseName extracts the measurement name, the field name and the tags from
+// the given bucket using the configured templates.
+func (p *Parser) parseName(bucket string) (name, field string, tags map[string]string, err error) {
+	tags = make(map[string]string)
+
+	parts := strings.Split(bucket, ",")
+	for _, btag := range parts[1:] {
+		k, v := parseKeyValue(btag)
+		if k != "" {
+			tags[k] = v
+		}
+	}
+
+	name = parts[0]
+	if p.SanitizeNamesMethod == "upstream" {
+		name = sanitizeWhitespace.ReplaceAllString(name, "_")
+		name = strings.ReplaceAll(name, "/", "-")
+		name = sanitizeChars.ReplaceAllString(name, "")
+	}
+
+	measurement, ttags, field, err := p.templateEngine.Apply(name)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if measurement != "" {
+		name = measurement
+	}
+	maps.Copy(tags, ttags)
+
+	if p.ConvertNames {
+		name = strings.ReplaceAll(name, ".", "_")
+		name = strings.ReplaceAll(name, "-", "__")
+	}
+	if field == "" {
+		field = defaultFieldName
+	}
+
+	return name, field, tags, nil
+}
+
+// parseKeyValue extracts the key and value from a string like "key=value".
+// If no key is given, only the value is returned.
+func parseKeyValue(keyValue string) (key, val string) {
+	split := strings.Split(keyValue, "=")
+	// Must be exactly 2 to get anything meaningful out of them
+	if len(split) == 2 {
+		key = split[0]
+		val = split[1]
+	} else if len(split) == 1 {
+		val = split[0]
+	} else if len(split) > 2 {
+		// fix: https://github.com/influxdata/telegraf/issues/10113
+		// fix: value has "=" parse error
+		// uri=/service/endpoint?sampleParam={paramValue} parse value key="uri", val="/service/endpoint?sampleParam\={paramValue}"
+		key = split[0]
+		val = strings.Join(split[1:], "=")
+	}
+
+	return key, val
+}
+
+// parseDataDogTags adds the tags contained in the given DataDog tag-list
+// (without the leading '#') to tags. Tags without value are set to "true".
+func parseDataDogTags(tags map[string]string, message string) {
+	if len(message) == 0 {
+		return
+	}
+
+	start, i := 0, 0
+	var k string
+	var inVal bool // check if we are parsing the value part of the tag
+	for i = range message {
+		if message[i] == ',' {
+			if k == "" {
+				k = message[start:i]
+				tags[k] = "true" // this is because influx doesn't support empty tags
+				start = i + 1
+				continue
+			}
+			v := message[start:i]
+			if v == "" {
+				v = "true"
+			}
+			tags[k] = v
+			start = i + 1
+			k, inVal = "", false // reset state vars
+		} else if message[i] == ':' && !inVal {
+			k = message[start:i]
+			start = i + 1
+			inVal = true
+		}
+	}
+	if k == "" && start < i+1 {
+		tags[message[start:i+1]] = "true"
+	}
+	// grab the last value
+	if k != "" {
+		if start < i+1 {
+			tags[k] = message[start : i+1]
+			return
+		}
+		tags[k] = "true"
+	}
+}
+
+func init() {
+	parsers.Add("statsd",
+		func(string) telegraf.Parser {
+			return &Parser{}
+		},
+	)
+}
@@ -0,0 +1,218 @@
+package statsd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		parser   *Parser
+		input    string
+		expected []telegraf.Metric
+	}{
+		{
+			name:   "counter with sample rate",
+			parser: &Parser{},
+			input:  "users.online:1|c|@0.5",
+			expected: []telegraf.Metric{
+				metric.New(
+					"users_online",
+					map[string]string{"metric_type": "counter"},
+					map[string]interface{}{"value": int64(2)},
+					time.Unix(0, 0),
+					telegraf.Counter,
+				),
+			},
+		},
+		{
+			name:   "multiple values",
+			parser: &Parser{},
+			input:  "load:0.5|g:320|ms\nunique:foo|s",
+			expected: []telegraf.Metric{
+				metric.New(
+					"load",
+					map[string]string{"metric_type": "gauge"},
+					map[string]interface{}{"value": 0.5},
+					time.Unix(0, 0),
+					telegraf.Gauge,
+				),
+				metric.New(
+					"load",
+					map[string]string{"metric_type": "timing"},
+					map[string]interface{}{"value": 320.0},
+					time.Unix(0, 0),
+					telegraf.Histogram,
+				),
+				metric.New(
+					"unique",
+					map[string]string{"metric_type": "set"},
+					map[string]interface{}{"value": "foo"},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name:   "influx-style tags",
+			parser: &Parser{},
+			input:  "cpu.load,host=a,region=us:1.5|g",
+			expected: []telegraf.Metric{
+				metric.New(
+					"cpu_load",
+					map[string]string{"metric_type": "gauge", "host": "a", "region": "us"},
+					map[string]interface{}{"value": 1.5},
+					time.Unix(0, 0),
+					telegraf.Gauge,
+				),
+			},
+		},
+		{
+			name:   "datadog tags",
+			parser: &Parser{DataDogExtensions: true, DataDogKeepContainerTag: true},
+			input:  "request.latency:12|d|#env:prod,canary|c:abc123",
+			expected: []telegraf.Metric{
+				metric.New(
+					"request_latency",
+					map[string]string{
+						"metric_type": "distribution",
+						"env":         "prod",
+						"canary":      "true",
+						"container":   "abc123",
+					},
+					map[string]interface{}{"value": 12.0},
+					time.Unix(0, 0),
+					telegraf.Histogram,
+				),
+			},
+		},
+		{
+			name:   "templates",
+			parser: &Parser{Templates: []string{"measurement.field.host"}},
+			input:  "cpu.idle.server01:42|g",
+			expected: []telegraf.Metric{
+				metric.New(
+					"cpu",
+					map[string]string{"metric_type": "gauge", "host": "server01"},
+					map[string]interface{}{"idle": 42.0},
+					time.Unix(0, 0),
+					telegraf.Gauge,
+				),
+			},
+		},
+		{
+			name:   "datadog events are ignored",
+			parser: &Parser{DataDogExtensions: true},
+			input:  "_e{5,4}:title|text\nfoo:1|c",
+			expected: []telegraf.Metric{
+				metric.New(
+					"foo",
+					map[string]string{"metric_type": "counter"},
+					map[string]interface{}{"value": int64(1)},
+					time.Unix(0, 0),
+					telegraf.Counter,
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.parser.Log = testutil.Logger{}
+			require.NoError(t, tt.parser.Init())
+
+			actual, err := tt.parser.Parse([]byte(tt.input))
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, tt.expected, actual, testutil.IgnoreTime())
+		})
+	}
+}
+
+func TestParseSignedGauge(t *testing.T) {
+	parser := &Parser{Log: testutil.Logger{}}
+	require.NoError(t, parser.Init())
+
+	actual, err := parser.Parse([]byte("level:5|g\nlevel:+2|g\nlevel:-3|g"))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"level",
+			map[string]string{"metric_type": "gauge"},
+			map[string]interface{}{"value": 5.0},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		metric.New(
+			"level",
+			map[string]string{"metric_type": "gauge_delta"},
+			map[string]interface{}{"value": 2.0},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+		metric.New(
+			"level",
+			map[string]string{"metric_type": "gauge_delta"},
+			map[string]interface{}{"value": -3.0},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser := &Parser{Log: testutil.Logger{}}
+	require.NoError(t, parser.Init())
+
+	actual, err := parser.Parse([]byte("foo\nbar:1|x\nbaz:+1|ms\ngood:1|g"))
+	require.ErrorContains(t, err, `splitting ':', unable to parse metric "foo"`)
+	require.ErrorContains(t, err, `metric type "x" unsupported`)
+	require.ErrorContains(t, err, "+- values are only supported for gauges & counters")
+	require.Len(t, actual, 1)
+	require.Equal(t, "good", actual[0].Name())
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	parser := &Parser{Log: testutil.Logger{}}
+	require.NoError(t, parser.Init())
+	parser.SetDefaultTags(map[string]string{"source": "relay", "host": "default"})
+
+	actual, err := parser.ParseLine("foo,host=a:1|g")
+	require.NoError(t, err)
+
+	expected := metric.New(
+		"foo",
+		map[string]string{"metric_type": "gauge", "host": "a", "source": "relay"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0),
+		telegraf.Gauge,
+	)
+	testutil.RequireMetricEqual(t, expected, actual, testutil.IgnoreTime())
+}
+
+func TestParseKeyValue(t *testing.T) {
+	tests := []struct {
+		input string
+		key   string
+		value string
+	}{
+		{"", "", ""},
+		{"baz", "", "baz"},
+		{"only value", "", "only value"},
+		{"foo=bar", "foo", "bar"},
+		{"url=/api/querystring?key1=val1&key2=value", "url", "/api/querystring?key1=val1&key2=value"},
+	}
+
+	for _, tt := range tests {
+		key, value := parseKeyValue(tt.input)
+		require.Equal(t, tt.key, key, tt.input)
+		require.Equal(t, tt.value, value, tt.input)
+	}
+}
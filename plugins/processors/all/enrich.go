@@ -0,0 +1,5 @@
+//go:build !custom || processors || processors.enrich
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/processors/enrich" // register plugin
@@ -0,0 +1,284 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package cardinality
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Cardinality struct {
+	Limit            int             `toml:"limit"`
+	Action           string          `toml:"action"`
+	OverflowValue    string          `toml:"overflow_value"`
+	Keep             []string        `toml:"keep"`
+	Expiry           config.Duration `toml:"expiry"`
+	AlertMeasurement string          `toml:"alert_measurement"`
+	AlertInterval    config.Duration `toml:"alert_interval"`
+	TopKeys          int             `toml:"top_keys"`
+	Log              telegraf.Logger `toml:"-"`
+
+	keep         map[string]bool
+	measurements map[string]*measurementState
+	lastCleanup  time.Time
+}
+
+// measurementState keeps the known series of a measurement, the series
+// created by stripping or aggregating tags and the distinct values seen per
+// tag key. All are bounded by the configured limit.
+type measurementState struct {
+	series    map[uint64]time.Time
+	overflow  map[uint64]time.Time
+	values    map[string]map[string]time.Time
+	rejected  int64
+	lastAlert time.Time
+}
+
+func (*Cardinality) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Cardinality) Init() error {
+	if c.Limit < 1 {
+		return errors.New("limit must be greater than zero")
+	}
+
+	switch c.Action {
+	case "":
+		c.Action = "drop"
+	case "drop", "strip":
+	case "aggregate":
+		if c.OverflowValue == "" {
+			return errors.New("overflow_value must not be empty for action \"aggregate\"")
+		}
+	default:
+		return fmt.Errorf("invalid action %q", c.Action)
+	}
+
+	if c.TopKeys < 1 {
+		return errors.New("top_keys must be greater than zero")
+	}
+
+	c.keep = make(map[string]bool, len(c.Keep))
+	for _, k := range c.Keep {
+		c.keep[k] = true
+	}
+	c.measurements = make(map[string]*measurementState)
+
+	return nil
+}
+
+func (c *Cardinality) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := time.Now()
+	c.cleanup(now)
+
+	var exceeded []string
+	out := in[:0]
+	for _, m := range in {
+		name := m.Name()
+		state, found := c.measurements[name]
+		if !found {
+			state = &measurementState{
+				series:   make(map[uint64]time.Time),
+				overflow: make(map[uint64]time.Time),
+				values:   make(map[string]map[string]time.Time),
+			}
+			c.measurements[name] = state
+		}
+		c.trackValues(state, m, now)
+
+		id := m.HashID()
+		if _, known := state.series[id]; known || len(state.series) < c.Limit {
+			state.series[id] = now
+			out = append(out, m)
+			continue
+		}
+
+		// The metric would create a new series exceeding the limit
+		if state.rejected == 0 {
+			exceeded = append(exceeded, name)
+		}
+		state.rejected++
+
+		switch c.Action {
+		case "drop":
+			m.Drop()
+			continue
+		case "strip":
+			if key := c.topKey(state, m); key != "" {
+				m.RemoveTag(key)
+			}
+		case "aggregate":
+			if key := c.topKey(state, m); key != "" {
+				m.AddTag(key, c.OverflowValue)
+			}
+		}
+
+		// The series created by the modification are bounded by the limit as
+		// well, e.g. if other tags still have a high cardinality
+		id = m.HashID()
+		if _, known := state.series[id]; known {
+			state.series[id] = now
+			out = append(out, m)
+			continue
+		}
+		if _, known := state.overflow[id]; !known && len(state.overflow) >= c.Limit {
+			m.Drop()
+			continue
+		}
+		state.overflow[id] = now
+		out = append(out, m)
+	}
+
+	// Emit alerts for all measurements over the limit, including the ones
+	// exceeding the limit in previous calls
+	for name, state := range c.measurements {
+		if state.rejected > 0 && !slices.Contains(exceeded, name) {
+			exceeded = append(exceeded, name)
+		}
+	}
+	for _, name := range exceeded {
+		state := c.measurements[name]
+		if now.Sub(state.lastAlert) < time.Duration(c.AlertInterval) {
+			continue
+		}
+		out = append(out, c.alert(name, state, now))
+		state.rejected = 0
+		state.lastAlert = now
+	}
+
+	return out
+}
+
+// trackValues records the distinct values of each tag key of the metric.
+// Value tracking stops once the number of values reaches the limit.
+func (c *Cardinality) trackValues(state *measurementState, m telegraf.Metric, now time.Time) {
+	for _, tag := range m.TagList() {
+		values, found := state.values[tag.Key]
+		if !found {
+			values = make(map[string]time.Time)
+			state.values[tag.Key] = values
+		}
+		if _, found := values[tag.Value]; found || len(values) < c.Limit {
+			values[tag.Value] = now
+		}
+	}
+}
+
+// topKey returns the tag of the metric with the most distinct values that is
+// not in the keep list.
+func (c *Cardinality) topKey(state *measurementState, m telegraf.Metric) string {
+	var key string
+	var count int
+	for _, tag := range m.TagList() {
+		if c.keep[tag.Key] {
+			continue
+		}
+		if n := len(state.values[tag.Key]); n > count {
+			key, count = tag.Key, n
+		}
+	}
+	return key
+}
+
+// topKeys returns the tag keys of the measurement with the most distinct
+// values in descending order.
+func (c *Cardinality) topKeys(state *measurementState) []string {
+	keys := make([]string, 0, len(state.values))
+	for k := range state.values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ni, nj := len(state.values[keys[i]]), len(state.values[keys[j]])
+		if ni != nj {
+			return ni > nj
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > c.TopKeys {
+		keys = keys[:c.TopKeys]
+	}
+	return keys
+}
+
+func (c *Cardinality) alert(name string, state *measurementState, now time.Time) telegraf.Metric {
+	top := c.topKeys(state)
+	c.Log.Warnf("Measurement %q exceeded the limit of %d series, %d metrics affected; top contributing tags: %s",
+		name, c.Limit, state.rejected, strings.Join(top, ", "))
+
+	return metric.New(
+		c.AlertMeasurement,
+		map[string]string{"measurement": name},
+		map[string]interface{}{
+			"limit":    int64(c.Limit),
+			"series":   int64(len(state.series)),
+			"rejected": state.rejected,
+			"top_keys": strings.Join(top, ","),
+		},
+		now,
+	)
+}
+
+// Remove expired series and tag values
+func (c *Cardinality) cleanup(now time.Time) {
+	expiry := time.Duration(c.Expiry)
+	if expiry <= 0 {
+		return
+	}
+	// No need to cleanup too often, use a tenth of the expiry time
+	if now.Sub(c.lastCleanup) < expiry/10 {
+		return
+	}
+	c.lastCleanup = now
+
+	for name, state := range c.measurements {
+		for id, seen := range state.series {
+			if now.Sub(seen) >= expiry {
+				delete(state.series, id)
+			}
+		}
+		for id, seen := range state.overflow {
+			if now.Sub(seen) >= expiry {
+				delete(state.overflow, id)
+			}
+		}
+		for key, values := range state.values {
+			for v, seen := range values {
+				if now.Sub(seen) >= expiry {
+					delete(values, v)
+				}
+			}
+			if len(values) == 0 {
+				delete(state.values, key)
+			}
+		}
+		if len(state.series) == 0 && len(state.overflow) == 0 && state.rejected == 0 {
+			delete(c.measurements, name)
+		}
+	}
+}
+
+func init() {
+	processors.Add("cardinality", func() telegraf.Processor {
+		return &Cardinality{
+			Action:           "drop",
+			OverflowValue:    "other",
+			Expiry:           config.Duration(time.Hour),
+			AlertMeasurement: "cardinality_limit",
+			AlertInterval:    config.Duration(time.Minute),
+			TopKeys:          3,
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package cardinality
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newCardinality(limit int, action string) *Cardinality {
+	return &Cardinality{
+		Limit:            limit,
+		Action:           action,
+		OverflowValue:    "other",
+		Expiry:           config.Duration(time.Hour),
+		AlertMeasurement: "cardinality_limit",
+		AlertInterval:    config.Duration(time.Minute),
+		TopKeys:          3,
+		Log:              testutil.Logger{},
+	}
+}
+
+func requests(n int) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, n)
+	for i := 0; i < n; i++ {
+		metrics = append(metrics, metric.New(
+			"http",
+			map[string]string{"host": "a", "request_id": strconv.Itoa(i)},
+			map[string]interface{}{"latency": int64(i)},
+			time.Unix(0, 0),
+		))
+	}
+	return metrics
+}
+
+func TestInitFail(t *testing.T) {
+	plugin := newCardinality(0, "drop")
+	require.ErrorContains(t, plugin.Init(), "limit must be greater than zero")
+
+	plugin = newCardinality(10, "foo")
+	require.ErrorContains(t, plugin.Init(), `invalid action "foo"`)
+
+	plugin = newCardinality(10, "drop")
+	plugin.TopKeys = 0
+	require.ErrorContains(t, plugin.Init(), "top_keys must be greater than zero")
+
+	plugin = newCardinality(10, "aggregate")
+	plugin.OverflowValue = ""
+	require.ErrorContains(t, plugin.Init(), "overflow_value must not be empty")
+}
+
+func TestActions(t *testing.T) {
+	alert := metric.New(
+		"cardinality_limit",
+		map[string]string{"measurement": "http"},
+		map[string]interface{}{
+			"limit":    int64(2),
+			"series":   int64(2),
+			"rejected": int64(2),
+			"top_keys": "request_id,host",
+		},
+		time.Unix(0, 0),
+	)
+
+	tests := []struct {
+		name     string
+		action   string
+		keep     []string
+		expected []telegraf.Metric
+	}{
+		{
+			name:   "drop",
+			action: "drop",
+			expected: []telegraf.Metric{
+				requests(4)[0],
+				requests(4)[1],
+				alert,
+			},
+		},
+		{
+			name:   "strip",
+			action: "strip",
+			expected: []telegraf.Metric{
+				requests(4)[0],
+				requests(4)[1],
+				metric.New("http", map[string]string{"host": "a"}, map[string]interface{}{"latency": int64(2)}, time.Unix(0, 0)),
+				metric.New("http", map[string]string{"host": "a"}, map[string]interface{}{"latency": int64(3)}, time.Unix(0, 0)),
+				alert,
+			},
+		},
+		{
+			name:   "strip with keep",
+			action: "strip",
+			keep:   []string{"request_id"},
+			expected: []telegraf.Metric{
+				requests(4)[0],
+				requests(4)[1],
+				metric.New("http", map[string]string{"request_id": "2"}, map[string]interface{}{"latency": int64(2)}, time.Unix(0, 0)),
+				metric.New("http", map[string]string{"request_id": "3"}, map[string]interface{}{"latency": int64(3)}, time.Unix(0, 0)),
+				alert,
+			},
+		},
+		{
+			name:   "aggregate",
+			action: "aggregate",
+			expected: []telegraf.Metric{
+				requests(4)[0],
+				requests(4)[1],
+				metric.New("http",
+					map[string]string{"host": "a", "request_id": "other"},
+					map[string]interface{}{"latency": int64(2)},
+					time.Unix(0, 0),
+				),
+				metric.New("http",
+					map[string]string{"host": "a", "request_id": "other"},
+					map[string]interface{}{"latency": int64(3)},
+					time.Unix(0, 0),
+				),
+				alert,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newCardinality(2, tt.action)
+			plugin.Keep = tt.keep
+			require.NoError(t, plugin.Init())
+
+			actual := plugin.Apply(requests(4)...)
+			testutil.RequireMetricsEqual(t, tt.expected, actual, testutil.IgnoreTime())
+		})
+	}
+}
+
+func TestOverflowSeriesLimit(t *testing.T) {
+	plugin := newCardinality(1, "strip")
+	require.NoError(t, plugin.Init())
+
+	// Stripping the request ID still leaves a high cardinality tag creating
+	// new series, those are bounded by the limit as well
+	input := make([]telegraf.Metric, 0, 3)
+	for i := 0; i < 3; i++ {
+		input = append(input, metric.New(
+			"http",
+			map[string]string{"request_id": strconv.Itoa(i), "session": strconv.Itoa(i)},
+			map[string]interface{}{"latency": int64(i)},
+			time.Unix(0, 0),
+		))
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("http", map[string]string{"request_id": "0", "session": "0"}, map[string]interface{}{"latency": int64(0)}, time.Unix(0, 0)),
+		metric.New("http", map[string]string{"session": "1"}, map[string]interface{}{"latency": int64(1)}, time.Unix(0, 0)),
+	}
+	actual := plugin.Apply(input...)
+	require.Len(t, actual, 3)
+	testutil.RequireMetricsEqual(t, expected, actual[:2], testutil.IgnoreTime())
+}
+
+func TestKnownSeriesPass(t *testing.T) {
+	plugin := newCardinality(2, "drop")
+	require.NoError(t, plugin.Init())
+
+	// Fill up the limit and exceed it once
+	actual := plugin.Apply(requests(3)...)
+	require.Len(t, actual, 3)
+	require.Equal(t, "cardinality_limit", actual[2].Name())
+
+	// Known series must still pass and no further alert is emitted within the
+	// alert interval
+	actual = plugin.Apply(requests(3)...)
+	testutil.RequireMetricsEqual(t, requests(2), actual)
+}
+
+func TestAlertInterval(t *testing.T) {
+	plugin := newCardinality(1, "drop")
+	plugin.AlertInterval = 0
+	require.NoError(t, plugin.Init())
+
+	for i := 0; i < 3; i++ {
+		actual := plugin.Apply(requests(2)...)
+		require.Len(t, actual, 2)
+		require.Equal(t, "cardinality_limit", actual[1].Name())
+		rejected, found := actual[1].GetField("rejected")
+		require.True(t, found)
+		require.Equal(t, int64(1), rejected)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	plugin := newCardinality(1, "drop")
+	plugin.Expiry = config.Duration(time.Nanosecond)
+	require.NoError(t, plugin.Init())
+
+	input := requests(2)
+	actual := plugin.Apply(input[0])
+	testutil.RequireMetricsEqual(t, input[:1], actual)
+
+	// The first series expired so the second one fits into the limit
+	time.Sleep(time.Millisecond)
+	actual = plugin.Apply(input[1])
+	testutil.RequireMetricsEqual(t, input[1:], actual)
+}
+
+func TestTrackingDrop(t *testing.T) {
+	var delivered int
+	notify := func(telegraf.DeliveryInfo) {
+		delivered++
+	}
+
+	plugin := newCardinality(1, "drop")
+	require.NoError(t, plugin.Init())
+
+	input := make([]telegraf.Metric, 0, 2)
+	for _, m := range requests(2) {
+		tm, _ := metric.WithTracking(m, notify)
+		input = append(input, tm)
+	}
+
+	actual := plugin.Apply(input...)
+	require.Len(t, actual, 2)
+	for _, m := range actual {
+		m.Accept()
+	}
+	require.Equal(t, 2, delivered)
+}
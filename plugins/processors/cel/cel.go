@@ -0,0 +1,273 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package cel
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type CEL struct {
+	Drop        string            `toml:"drop"`
+	Name        string            `toml:"name"`
+	Fields      []operation       `toml:"fields"`
+	Tags        []operation       `toml:"tags"`
+	FieldRename map[string]string `toml:"field_rename"`
+	TagRename   map[string]string `toml:"tag_rename"`
+	Log         telegraf.Logger   `toml:"-"`
+
+	drop       cel.Program
+	dropWarned bool
+	name       cel.Program
+	nameWarned bool
+}
+
+// operation sets the field or tag with the given key to the result of the
+// expression
+type operation struct {
+	Key        string `toml:"key"`
+	Expression string `toml:"expression"`
+
+	program cel.Program
+	warned  bool
+}
+
+func (*CEL) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CEL) Init() error {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("tags", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("fields", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("time", cel.TimestampType),
+		cel.Function(
+			"now",
+			cel.Overload("now", nil, cel.TimestampType),
+			cel.SingletonFunctionBinding(func(_ ...ref.Val) ref.Val { return types.Timestamp{Time: time.Now()} }),
+		),
+		ext.Encoders(),
+		ext.Math(),
+		ext.Strings(),
+	)
+	if err != nil {
+		return fmt.Errorf("creating environment failed: %w", err)
+	}
+
+	if c.Drop != "" {
+		if c.drop, err = compile(env, c.Drop, cel.BoolType); err != nil {
+			return fmt.Errorf("compiling 'drop' failed: %w", err)
+		}
+	}
+	if c.Name != "" {
+		if c.name, err = compile(env, c.Name, cel.StringType); err != nil {
+			return fmt.Errorf("compiling 'name' failed: %w", err)
+		}
+	}
+	for i := range c.Fields {
+		if c.Fields[i].Key == "" {
+			return fmt.Errorf("field operation %d: missing 'key'", i+1)
+		}
+		if c.Fields[i].program, err = compile(env, c.Fields[i].Expression, nil); err != nil {
+			return fmt.Errorf("compiling expression of field %q failed: %w", c.Fields[i].Key, err)
+		}
+	}
+	for i := range c.Tags {
+		if c.Tags[i].Key == "" {
+			return fmt.Errorf("tag operation %d: missing 'key'", i+1)
+		}
+		if c.Tags[i].program, err = compile(env, c.Tags[i].Expression, nil); err != nil {
+			return fmt.Errorf("compiling expression of tag %q failed: %w", c.Tags[i].Key, err)
+		}
+	}
+
+	// Renames are applied in random order, so chained renames or renaming
+	// multiple keys to the same name would lead to random results
+	if err := checkRenames(c.FieldRename); err != nil {
+		return fmt.Errorf("invalid field rename: %w", err)
+	}
+	if err := checkRenames(c.TagRename); err != nil {
+		return fmt.Errorf("invalid tag rename: %w", err)
+	}
+
+	return nil
+}
+
+func (c *CEL) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		if c.apply(m) {
+			out = append(out, m)
+		} else {
+			m.Drop()
+		}
+	}
+	return out
+}
+
+// apply transforms the metric and returns whether to keep it
+func (c *CEL) apply(m telegraf.Metric) bool {
+	// The activation is updated with the results of the operations, so
+	// subsequent expressions see the modified metric
+	tags := m.Tags()
+	fields := m.Fields()
+	activation := map[string]interface{}{
+		"name":   m.Name(),
+		"tags":   tags,
+		"fields": fields,
+		"time":   m.Time(),
+	}
+
+	if c.drop != nil {
+		result, _, err := c.drop.Eval(activation)
+		if err != nil {
+			c.evalFailed(&c.dropWarned, "'drop'", m, err)
+		} else if drop, ok := result.Value().(bool); ok && drop {
+			return false
+		}
+	}
+
+	for i := range c.Fields {
+		op := &c.Fields[i]
+		result, _, err := op.program.Eval(activation)
+		if err != nil {
+			c.evalFailed(&op.warned, fmt.Sprintf("field %q", op.Key), m, err)
+			continue
+		}
+		v, err := fieldValue(result)
+		if err != nil {
+			c.Log.Errorf("Invalid value for field %q: %v", op.Key, err)
+			continue
+		}
+		m.AddField(op.Key, v)
+		fields[op.Key] = v
+	}
+
+	for i := range c.Tags {
+		op := &c.Tags[i]
+		result, _, err := op.program.Eval(activation)
+		if err != nil {
+			c.evalFailed(&op.warned, fmt.Sprintf("tag %q", op.Key), m, err)
+			continue
+		}
+		v, err := tagValue(result)
+		if err != nil {
+			c.Log.Errorf("Invalid value for tag %q: %v", op.Key, err)
+			continue
+		}
+		m.AddTag(op.Key, v)
+		tags[op.Key] = v
+	}
+
+	if c.name != nil {
+		result, _, err := c.name.Eval(activation)
+		if err != nil {
+			c.evalFailed(&c.nameWarned, "'name'", m, err)
+		} else if name, ok := result.Value().(string); ok && name != "" {
+			m.SetName(name)
+		}
+	}
+
+	for oldKey, newKey := range c.FieldRename {
+		if v, found := m.GetField(oldKey); found {
+			m.RemoveField(oldKey)
+			m.AddField(newKey, v)
+		}
+	}
+	for oldKey, newKey := range c.TagRename {
+		if v, found := m.GetTag(oldKey); found {
+			m.RemoveTag(oldKey)
+			m.AddTag(newKey, v)
+		}
+	}
+
+	return true
+}
+
+// evalFailed logs the first evaluation failure of an expression as warning
+// and subsequent ones at debug level to not flood the log
+func (c *CEL) evalFailed(warned *bool, what string, m telegraf.Metric, err error) {
+	if *warned {
+		c.Log.Debugf("Evaluating %s for metric %q failed: %v", what, m.Name(), err)
+		return
+	}
+	*warned = true
+	c.Log.Warnf("Evaluating %s for metric %q failed: %v; further failures of this expression are logged at debug level",
+		what, m.Name(), err)
+}
+
+// checkRenames rejects renames to keys also being renamed and renames of
+// multiple keys to the same key
+func checkRenames(renames map[string]string) error {
+	targets := make(map[string]string, len(renames))
+	for oldKey, newKey := range renames {
+		if _, found := renames[newKey]; found && newKey != oldKey {
+			return fmt.Errorf("%q is renamed to %q which is renamed itself", oldKey, newKey)
+		}
+		if other, found := targets[newKey]; found {
+			if other > oldKey {
+				other, oldKey = oldKey, other
+			}
+			return fmt.Errorf("both %q and %q are renamed to %q", other, oldKey, newKey)
+		}
+		targets[newKey] = oldKey
+	}
+	return nil
+}
+
+// compile checks and compiles the expression optionally enforcing the
+// output type
+func compile(env *cel.Env, expression string, outputType *cel.Type) (cel.Program, error) {
+	if expression == "" {
+		return nil, errors.New("empty expression")
+	}
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if outputType != nil && !ast.OutputType().IsExactType(outputType) && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression must return %s but returns %s", outputType, ast.OutputType())
+	}
+	return env.Program(ast, cel.EvalOptions(cel.OptOptimize))
+}
+
+// fieldValue converts the result of an expression into a field value
+func fieldValue(v ref.Val) (interface{}, error) {
+	switch r := v.Value().(type) {
+	case int64, uint64, float64, bool, string:
+		return r, nil
+	case time.Duration:
+		return r.Nanoseconds(), nil
+	case time.Time:
+		return r.UnixNano(), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", v.Type().TypeName())
+}
+
+// tagValue converts the result of an expression into a tag value
+func tagValue(v ref.Val) (string, error) {
+	s, ok := v.ConvertToType(types.StringType).Value().(string)
+	if !ok {
+		return "", fmt.Errorf("cannot convert %s to string", v.Type().TypeName())
+	}
+	return s, nil
+}
+
+func init() {
+	processors.Add("cel", func() telegraf.Processor {
+		return &CEL{}
+	})
+}
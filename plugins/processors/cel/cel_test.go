@@ -0,0 +1,212 @@
+package cel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
+	"github.com/influxdata/telegraf/plugins/processors/starlark"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *CEL
+		expected string
+	}{
+		{
+			name:     "invalid syntax",
+			plugin:   &CEL{Fields: []operation{{Key: "x", Expression: "fields.bytes *"}}},
+			expected: `compiling expression of field "x" failed`,
+		},
+		{
+			name:     "missing key",
+			plugin:   &CEL{Tags: []operation{{Expression: "'foo'"}}},
+			expected: "tag operation 1: missing 'key'",
+		},
+		{
+			name:     "non-boolean drop",
+			plugin:   &CEL{Drop: "name"},
+			expected: "expression must return bool",
+		},
+		{
+			name:     "non-string name",
+			plugin:   &CEL{Name: "1 + 2"},
+			expected: "expression must return string",
+		},
+		{
+			name:     "unknown variable",
+			plugin:   &CEL{Drop: "foo == 1"},
+			expected: "undeclared reference to 'foo'",
+		},
+		{
+			name:     "chained field rename",
+			plugin:   &CEL{FieldRename: map[string]string{"a": "b", "b": "c"}},
+			expected: `invalid field rename: "a" is renamed to "b" which is renamed itself`,
+		},
+		{
+			name:     "tag rename to same key",
+			plugin:   &CEL{TagRename: map[string]string{"a": "c", "b": "c"}},
+			expected: `invalid tag rename: both "a" and "b" are renamed to "c"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	plugin := &CEL{
+		Drop: "has(fields.dropme)",
+		Name: "name + '_stats'",
+		Fields: []operation{
+			{Key: "bits", Expression: "fields.bytes * 8"},
+			{Key: "kbits", Expression: "double(fields.bits) / 1000.0"},
+			{Key: "large", Expression: "fields.bytes > 1000"},
+			{Key: "missing", Expression: "fields.unknown * 2"},
+		},
+		Tags: []operation{
+			{Key: "short_host", Expression: "tags.host.split('.')[0]"},
+			{Key: "size", Expression: "fields.large ? 'large' : 'small'"},
+			{Key: "code", Expression: "fields.status"},
+		},
+		FieldRename: map[string]string{"bytes": "bytes_total"},
+		TagRename:   map[string]string{"host": "hostname"},
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	input := []telegraf.Metric{
+		metric.New(
+			"net",
+			map[string]string{"host": "server01.example.com"},
+			map[string]interface{}{"bytes": int64(1500), "status": int64(200)},
+			now,
+		),
+		metric.New(
+			"net",
+			map[string]string{"host": "server02.example.com"},
+			map[string]interface{}{"bytes": int64(10), "dropme": true},
+			now,
+		),
+	}
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"net_stats",
+			map[string]string{
+				"hostname":   "server01.example.com",
+				"short_host": "server01",
+				"size":       "large",
+				"code":       "200",
+			},
+			map[string]interface{}{
+				"bytes_total": int64(1500),
+				"status":      int64(200),
+				"bits":        int64(12000),
+				"kbits":       float64(12),
+				"large":       true,
+			},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, plugin.Apply(input...))
+}
+
+func TestEvaluationFailureLogged(t *testing.T) {
+	logger := &testutil.CaptureLogger{}
+	plugin := &CEL{
+		Fields: []operation{{Key: "bits", Expression: "fields.bytes * 8"}},
+		Log:    logger,
+	}
+	require.NoError(t, plugin.Init())
+
+	// Multiplying a float with an integer fails in CEL
+	input := metric.New("net", map[string]string{}, map[string]interface{}{"bytes": 1.5}, time.Unix(0, 0))
+	plugin.Apply(input, input.Copy())
+
+	// Only the first failure of the expression is logged as warning
+	warnings := logger.Warnings()
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], `Evaluating field "bits" for metric "net" failed: no such overload`)
+}
+
+func TestTracking(t *testing.T) {
+	var delivered []telegraf.DeliveryInfo
+	notify := func(di telegraf.DeliveryInfo) {
+		delivered = append(delivered, di)
+	}
+
+	plugin := &CEL{
+		Drop: "fields.value < 0",
+		Log:  testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	input := make([]telegraf.Metric, 0, 2)
+	for _, v := range []int64{-1, 1} {
+		m := metric.New("test", map[string]string{}, map[string]interface{}{"value": v}, time.Unix(0, 0))
+		tm, _ := metric.WithTracking(m, notify)
+		input = append(input, tm)
+	}
+
+	actual := plugin.Apply(input...)
+	require.Len(t, actual, 1)
+	for _, m := range actual {
+		m.Accept()
+	}
+	require.Len(t, delivered, 2)
+}
+
+func newBenchmarkInput() telegraf.Metric {
+	return metric.New(
+		"net",
+		map[string]string{"host": "server01.example.com"},
+		map[string]interface{}{"bytes": int64(1500)},
+		time.Now(),
+	)
+}
+
+func BenchmarkCEL(b *testing.B) {
+	plugin := &CEL{
+		Fields: []operation{{Key: "bits", Expression: "fields.bytes * 8"}},
+		Tags:   []operation{{Key: "short_host", Expression: "tags.host.split('.')[0]"}},
+		Log:    testutil.Logger{},
+	}
+	require.NoError(b, plugin.Init())
+
+	for n := 0; n < b.N; n++ {
+		plugin.Apply(newBenchmarkInput())
+	}
+}
+
+func BenchmarkStarlark(b *testing.B) {
+	plugin := &starlark.Starlark{
+		Common: common.Common{
+			StarlarkLoadFunc: common.LoadFunc,
+			Log:              testutil.Logger{},
+			Source: `
+def apply(metric):
+    metric.fields["bits"] = metric.fields["bytes"] * 8
+    metric.tags["short_host"] = metric.tags["host"].split(".")[0]
+    return metric
+`,
+		},
+	}
+	require.NoError(b, plugin.Init())
+
+	for n := 0; n < b.N; n++ {
+		var acc testutil.Accumulator
+		require.NoError(b, plugin.Add(newBenchmarkInput(), &acc))
+	}
+}
@@ -0,0 +1,53 @@
+package enrich
+
+import (
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+type cacheEntry struct {
+	values  map[string]interface{}
+	expires time.Time
+}
+
+// cache keeps the looked-up entries per key including the keys not found
+// in the source. Expired entries are kept until evicted to allow using them
+// while refreshing.
+type cache struct {
+	entries     *lru.Cache[string, *cacheEntry]
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+func newCache(size int, ttl, negativeTTL time.Duration) (*cache, error) {
+	entries, err := lru.New[string, *cacheEntry](size)
+	if err != nil {
+		return nil, err
+	}
+	return &cache{entries: entries, ttl: ttl, negativeTTL: negativeTTL}, nil
+}
+
+// get returns the cached values of the key, whether the entry is expired and
+// whether the key was found in the cache at all
+func (c *cache) get(key string, now time.Time) (values map[string]interface{}, expired, found bool) {
+	entry, found := c.entries.Get(key)
+	if !found {
+		return nil, false, false
+	}
+	return entry.values, !now.Before(entry.expires), true
+}
+
+// set caches the values of the key, nil values denote a key not found in
+// the source
+func (c *cache) set(key string, values map[string]interface{}, now time.Time) {
+	ttl := c.ttl
+	if values == nil {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 {
+		c.entries.Remove(key)
+		return
+	}
+	c.entries.Add(key, &cacheEntry{values: values, expires: now.Add(ttl)})
+}
@@ -0,0 +1,283 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package enrich
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/parallel"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+const (
+	defaultMaxOrderedQueueSize = 10_000
+	defaultMaxParallelLookups  = 10
+	defaultTimeout             = 5 * time.Second
+	defaultCacheTTL            = 5 * time.Minute
+	defaultNegativeCacheTTL    = time.Minute
+	defaultCacheSize           = 10_000
+)
+
+// source is a backend to look up the entries for a key. Lookups of
+// non-existing keys must return nil entries without an error.
+type source interface {
+	start(log telegraf.Logger) error
+	lookup(ctx context.Context, key string) (map[string]interface{}, error)
+	stop()
+}
+
+type Enrich struct {
+	KeyTemplate        string          `toml:"key"`
+	Source             string          `toml:"source"`
+	Fields             []string        `toml:"fields"`
+	Include            []string        `toml:"include"`
+	CacheTTL           config.Duration `toml:"cache_ttl"`
+	NegativeCacheTTL   config.Duration `toml:"negative_cache_ttl"`
+	CacheSize          int             `toml:"cache_size"`
+	AsyncRefresh       bool            `toml:"async_refresh"`
+	Ordered            bool            `toml:"ordered"`
+	MaxParallelLookups int             `toml:"max_parallel_lookups"`
+	Timeout            config.Duration `toml:"timeout"`
+	SQL                *SQLSource      `toml:"sql"`
+	HTTP               *HTTPSource     `toml:"http"`
+	Redis              *RedisSource    `toml:"redis"`
+	Log                telegraf.Logger `toml:"-"`
+
+	tmpl     *template.Template
+	src      source
+	cache    *cache
+	fields   map[string]bool
+	include  map[string]bool
+	parallel parallel.Parallel
+
+	inflight     map[string]*call
+	inflightLock sync.Mutex
+	refreshes    sync.WaitGroup
+}
+
+// call is a lookup in progress, concurrent lookups of the same key wait for
+// the result of the first one
+type call struct {
+	done   chan struct{}
+	values map[string]interface{}
+	err    error
+}
+
+func (*Enrich) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Enrich) Init() error {
+	if e.KeyTemplate == "" {
+		return errors.New("missing 'key'")
+	}
+	tmpl, err := template.New("key").Parse(e.KeyTemplate)
+	if err != nil {
+		return fmt.Errorf("creating template failed: %w", err)
+	}
+	e.tmpl = tmpl
+
+	switch e.Source {
+	case "sql":
+		if e.SQL == nil {
+			return errors.New("missing 'sql' settings")
+		}
+		if err := e.SQL.init(); err != nil {
+			return fmt.Errorf("initializing SQL source failed: %w", err)
+		}
+		e.src = e.SQL
+	case "http":
+		if e.HTTP == nil {
+			return errors.New("missing 'http' settings")
+		}
+		if err := e.HTTP.init(); err != nil {
+			return fmt.Errorf("initializing HTTP source failed: %w", err)
+		}
+		e.src = e.HTTP
+	case "redis":
+		if e.Redis == nil {
+			return errors.New("missing 'redis' settings")
+		}
+		if err := e.Redis.init(); err != nil {
+			return fmt.Errorf("initializing Redis source failed: %w", err)
+		}
+		e.src = e.Redis
+	case "":
+		return errors.New("missing 'source'")
+	default:
+		return fmt.Errorf("invalid source %q", e.Source)
+	}
+
+	if e.CacheSize < 1 {
+		return errors.New("cache_size must be greater than zero")
+	}
+	if e.MaxParallelLookups < 1 {
+		return errors.New("max_parallel_lookups must be greater than zero")
+	}
+
+	e.fields = make(map[string]bool, len(e.Fields))
+	for _, f := range e.Fields {
+		e.fields[f] = true
+	}
+	e.include = make(map[string]bool, len(e.Include))
+	for _, f := range e.Include {
+		e.include[f] = true
+	}
+
+	c, err := newCache(e.CacheSize, time.Duration(e.CacheTTL), time.Duration(e.NegativeCacheTTL))
+	if err != nil {
+		return fmt.Errorf("creating cache failed: %w", err)
+	}
+	e.cache = c
+	e.inflight = make(map[string]*call)
+
+	return nil
+}
+
+func (e *Enrich) Start(acc telegraf.Accumulator) error {
+	if err := e.src.start(e.Log); err != nil {
+		return fmt.Errorf("starting %s source failed: %w", e.Source, err)
+	}
+
+	if e.Ordered {
+		e.parallel = parallel.NewOrdered(acc, e.asyncAdd, defaultMaxOrderedQueueSize, e.MaxParallelLookups)
+	} else {
+		e.parallel = parallel.NewUnordered(acc, e.asyncAdd, e.MaxParallelLookups)
+	}
+
+	return nil
+}
+
+func (e *Enrich) Add(metric telegraf.Metric, _ telegraf.Accumulator) error {
+	e.parallel.Enqueue(metric)
+	return nil
+}
+
+func (e *Enrich) Stop() {
+	if e.parallel != nil {
+		e.parallel.Stop()
+	}
+	e.refreshes.Wait()
+	e.src.stop()
+}
+
+func (e *Enrich) asyncAdd(metric telegraf.Metric) []telegraf.Metric {
+	m := metric
+	if wm, ok := metric.(telegraf.UnwrappableMetric); ok {
+		m = wm.Unwrap()
+	}
+
+	var buf bytes.Buffer
+	if err := e.tmpl.Execute(&buf, m); err != nil {
+		e.Log.Errorf("Generating key failed: %v", err)
+		e.Log.Debugf("metric was %v", m)
+		return []telegraf.Metric{metric}
+	}
+	key := buf.String()
+	if key == "" {
+		return []telegraf.Metric{metric}
+	}
+
+	values, expired, found := e.cache.get(key, time.Now())
+	switch {
+	case found && !expired:
+		// Use the cached entry
+	case found && e.AsyncRefresh:
+		// Use the expired entry and refresh it in the background
+		e.refresh(key)
+	default:
+		fetched, err := e.fetch(key)
+		if err != nil {
+			e.Log.Errorf("Looking up key %q failed: %v", key, err)
+			// Fall back to the expired entry if any
+			if !found {
+				return []telegraf.Metric{metric}
+			}
+		} else {
+			values = fetched
+		}
+	}
+
+	for k, v := range values {
+		if v == nil || (len(e.include) > 0 && !e.include[k]) {
+			continue
+		}
+		if e.fields[k] {
+			m.AddField(k, v)
+		} else {
+			m.AddTag(k, fmt.Sprint(v))
+		}
+	}
+
+	return []telegraf.Metric{metric}
+}
+
+// fetch looks up the key in the source and updates the cache. Concurrent
+// lookups of the same key are merged into a single request.
+func (e *Enrich) fetch(key string) (map[string]interface{}, error) {
+	e.inflightLock.Lock()
+	if c, found := e.inflight[key]; found {
+		e.inflightLock.Unlock()
+		<-c.done
+		return c.values, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	e.inflight[key] = c
+	e.inflightLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.Timeout))
+	c.values, c.err = e.src.lookup(ctx, key)
+	cancel()
+	if c.err == nil {
+		e.cache.set(key, c.values, time.Now())
+	}
+
+	e.inflightLock.Lock()
+	delete(e.inflight, key)
+	e.inflightLock.Unlock()
+	close(c.done)
+
+	return c.values, c.err
+}
+
+// refresh updates the cache entry of the key in the background if no
+// lookup for the key is in progress
+func (e *Enrich) refresh(key string) {
+	e.inflightLock.Lock()
+	_, found := e.inflight[key]
+	e.inflightLock.Unlock()
+	if found {
+		return
+	}
+
+	e.refreshes.Add(1)
+	go func() {
+		defer e.refreshes.Done()
+		if _, err := e.fetch(key); err != nil {
+			e.Log.Errorf("Refreshing key %q failed: %v", key, err)
+		}
+	}()
+}
+
+func init() {
+	processors.AddStreaming("enrich", func() telegraf.StreamingProcessor {
+		return &Enrich{
+			CacheTTL:           config.Duration(defaultCacheTTL),
+			NegativeCacheTTL:   config.Duration(defaultNegativeCacheTTL),
+			CacheSize:          defaultCacheSize,
+			MaxParallelLookups: defaultMaxParallelLookups,
+			Timeout:            config.Duration(defaultTimeout),
+		}
+	})
+}
@@ -0,0 +1,343 @@
+package enrich
+
+import (
+	"context"
+	gosql "database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/docker/go-connections/nat"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	common_redis "github.com/influxdata/telegraf/plugins/common/redis"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newEnrich() *Enrich {
+	return &Enrich{
+		KeyTemplate:        `{{.Tag "host"}}`,
+		CacheTTL:           config.Duration(defaultCacheTTL),
+		NegativeCacheTTL:   config.Duration(defaultNegativeCacheTTL),
+		CacheSize:          defaultCacheSize,
+		MaxParallelLookups: defaultMaxParallelLookups,
+		Timeout:            config.Duration(defaultTimeout),
+		Log:                testutil.Logger{},
+	}
+}
+
+func hosts(names ...string) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, len(names))
+	for _, name := range names {
+		metrics = append(metrics, metric.New(
+			"cpu",
+			map[string]string{"host": name},
+			map[string]interface{}{"usage": 42.0},
+			time.Unix(0, 0),
+		))
+	}
+	return metrics
+}
+
+func process(t *testing.T, plugin *Enrich, input ...telegraf.Metric) []telegraf.Metric {
+	t.Helper()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	plugin.Stop()
+
+	return acc.GetTelegrafMetrics()
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(e *Enrich)
+		expected string
+	}{
+		{
+			name:     "missing key",
+			modify:   func(e *Enrich) { e.KeyTemplate = "" },
+			expected: "missing 'key'",
+		},
+		{
+			name:     "missing source",
+			modify:   func(*Enrich) {},
+			expected: "missing 'source'",
+		},
+		{
+			name:     "invalid source",
+			modify:   func(e *Enrich) { e.Source = "foo" },
+			expected: `invalid source "foo"`,
+		},
+		{
+			name:     "missing settings",
+			modify:   func(e *Enrich) { e.Source = "sql" },
+			expected: "missing 'sql' settings",
+		},
+		{
+			name: "missing placeholder",
+			modify: func(e *Enrich) {
+				e.Source = "http"
+				e.HTTP = &HTTPSource{URL: "http://localhost/hosts"}
+			},
+			expected: "must contain the '{key}' placeholder",
+		},
+		{
+			name: "missing query",
+			modify: func(e *Enrich) {
+				e.Source = "sql"
+				e.SQL = &SQLSource{Driver: "sqlite", DSN: config.NewSecret([]byte("test.db"))}
+			},
+			expected: "missing 'query'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newEnrich()
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/hosts/server01" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"owner": "team-a", "tier": 1, "site": "berlin", "weight": 0.5, "nested": {"a": 1}}`)
+	}))
+	defer server.Close()
+
+	plugin := newEnrich()
+	plugin.Source = "http"
+	plugin.HTTP = &HTTPSource{URL: server.URL + "/hosts/{key}"}
+	plugin.Fields = []string{"weight"}
+	plugin.Ordered = true
+	require.NoError(t, plugin.Init())
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "owner": "team-a", "tier": "1", "site": "berlin"},
+			map[string]interface{}{"usage": 42.0, "weight": 0.5},
+			time.Unix(0, 0),
+		),
+		hosts("unknown")[0],
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "owner": "team-a", "tier": "1", "site": "berlin"},
+			map[string]interface{}{"usage": 42.0, "weight": 0.5},
+			time.Unix(0, 0),
+		),
+		hosts("unknown")[0],
+	}
+
+	// Process sequentially to make sure the cache is used
+	var actual []telegraf.Metric
+	for _, m := range hosts("server01", "unknown", "server01", "unknown") {
+		actual = append(actual, process(t, plugin, m)...)
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	// Both the found and the not-found key must be cached
+	require.Equal(t, int64(2), requests.Load())
+}
+
+func TestInclude(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"owner": "team-a", "tier": 1, "site": "berlin"}`)
+	}))
+	defer server.Close()
+
+	plugin := newEnrich()
+	plugin.Source = "http"
+	plugin.HTTP = &HTTPSource{URL: server.URL + "/hosts/{key}"}
+	plugin.Include = []string{"owner"}
+	require.NoError(t, plugin.Init())
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "owner": "team-a"},
+			map[string]interface{}{"usage": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, process(t, plugin, hosts("server01")...))
+}
+
+func TestAsyncRefresh(t *testing.T) {
+	var version atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `{"version": "%d"}`, version.Add(1))
+	}))
+	defer server.Close()
+
+	plugin := newEnrich()
+	plugin.Source = "http"
+	plugin.HTTP = &HTTPSource{URL: server.URL + "/hosts/{key}"}
+	plugin.CacheTTL = config.Duration(time.Nanosecond)
+	plugin.AsyncRefresh = true
+	require.NoError(t, plugin.Init())
+
+	// The first lookup is synchronous
+	actual := process(t, plugin, hosts("server01")...)
+	require.Len(t, actual, 1)
+	require.Equal(t, "1", actual[0].Tags()["version"])
+
+	// The expired entry is used while being refreshed in the background
+	actual = process(t, plugin, hosts("server01")...)
+	require.Len(t, actual, 1)
+	require.Equal(t, "1", actual[0].Tags()["version"])
+
+	// Stopping the plugin waits for the refresh so the new value is used
+	actual = process(t, plugin, hosts("server01")...)
+	require.Len(t, actual, 1)
+	require.Equal(t, "2", actual[0].Tags()["version"])
+}
+
+func TestLookupErrorKeepsMetric(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	plugin := newEnrich()
+	plugin.Source = "http"
+	plugin.HTTP = &HTTPSource{URL: server.URL + "/hosts/{key}"}
+	require.NoError(t, plugin.Init())
+
+	testutil.RequireMetricsEqual(t, hosts("server01"), process(t, plugin, hosts("server01")...))
+}
+
+func TestSQL(t *testing.T) {
+	dbfile := filepath.Join(t.TempDir(), "cmdb.db")
+	db, err := gosql.Open("sqlite", dbfile)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE hosts (name TEXT, owner TEXT, tier INTEGER, site TEXT)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO hosts VALUES ('server01', 'team-a', 1, 'berlin'), ('server02', 'team-b', 2, 'paris')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	plugin := newEnrich()
+	plugin.Source = "sql"
+	plugin.SQL = &SQLSource{
+		Driver: "sqlite",
+		DSN:    config.NewSecret([]byte(dbfile)),
+		Query:  "SELECT owner, tier, site FROM hosts WHERE name = ?",
+	}
+	plugin.Fields = []string{"tier"}
+	plugin.Ordered = true
+	require.NoError(t, plugin.Init())
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "owner": "team-a", "site": "berlin"},
+			map[string]interface{}{"usage": 42.0, "tier": int64(1)},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server02", "owner": "team-b", "site": "paris"},
+			map[string]interface{}{"usage": 42.0, "tier": int64(2)},
+			time.Unix(0, 0),
+		),
+		hosts("unknown")[0],
+	}
+	actual := process(t, plugin, hosts("server01", "server02", "unknown")...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireUserAuth("telegraf", "secret")
+	server.HSet("cmdb:server01", "owner", "team-a", "site", "berlin")
+
+	plugin := newEnrich()
+	plugin.Source = "redis"
+	plugin.Redis = &RedisSource{
+		KeyPrefix: "cmdb:",
+		ClientConfig: common_redis.ClientConfig{
+			Address:  server.Addr(),
+			Username: config.NewSecret([]byte("telegraf")),
+			Password: config.NewSecret([]byte("secret")),
+		},
+	}
+	plugin.Ordered = true
+	require.NoError(t, plugin.Init())
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "owner": "team-a", "site": "berlin"},
+			map[string]interface{}{"usage": 42.0},
+			time.Unix(0, 0),
+		),
+		hosts("unknown")[0],
+	}
+	actual := process(t, plugin, hosts("server01", "unknown")...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestRedisIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	servicePort := "6379"
+	container := testutil.Container{
+		Image:        "redis:7-alpine",
+		ExposedPorts: []string{servicePort},
+		WaitingFor:   wait.ForListeningPort(nat.Port(servicePort)),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+	address := fmt.Sprintf("%s:%s", container.Address, container.Ports[servicePort])
+
+	client := redis.NewClient(&redis.Options{Addr: address})
+	require.NoError(t, client.HSet(context.Background(), "cmdb:server01", "owner", "team-a", "site", "berlin").Err())
+	require.NoError(t, client.Close())
+
+	plugin := newEnrich()
+	plugin.Source = "redis"
+	plugin.Redis = &RedisSource{
+		KeyPrefix:    "cmdb:",
+		ClientConfig: common_redis.ClientConfig{Address: address},
+	}
+	plugin.Ordered = true
+	require.NoError(t, plugin.Init())
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "owner": "team-a", "site": "berlin"},
+			map[string]interface{}{"usage": 42.0},
+			time.Unix(0, 0),
+		),
+		hosts("unknown")[0],
+	}
+	actual := process(t, plugin, hosts("server01", "unknown")...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
@@ -0,0 +1,105 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	common_http "github.com/influxdata/telegraf/plugins/common/http"
+)
+
+// HTTPSource looks up the entries by requesting a flat JSON object from an
+// HTTP endpoint
+type HTTPSource struct {
+	URL     string            `toml:"url"`
+	Headers map[string]string `toml:"headers"`
+	common_http.HTTPClientConfig
+
+	client *http.Client
+}
+
+func (s *HTTPSource) init() error {
+	if s.URL == "" {
+		return errors.New("missing 'url'")
+	}
+	if !strings.Contains(s.URL, "{key}") {
+		return errors.New("'url' must contain the '{key}' placeholder")
+	}
+	return nil
+}
+
+func (s *HTTPSource) start(log telegraf.Logger) error {
+	client, err := s.HTTPClientConfig.CreateClient(context.Background(), log)
+	if err != nil {
+		return fmt.Errorf("creating client failed: %w", err)
+	}
+	s.client = client
+
+	return nil
+}
+
+func (s *HTTPSource) lookup(ctx context.Context, key string) (map[string]interface{}, error) {
+	address := strings.ReplaceAll(s.URL, "{key}", url.PathEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request failed: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range s.Headers {
+		if strings.EqualFold(k, "host") {
+			req.Host = v
+		} else {
+			req.Header.Set(k, v)
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return nil, fmt.Errorf("received status %d (%s): %s", resp.StatusCode, http.StatusText(resp.StatusCode), body)
+	}
+
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding response failed: %w", err)
+	}
+
+	// Only use scalar values and convert numbers to integers if possible
+	entries := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		switch value := v.(type) {
+		case json.Number:
+			if i, err := value.Int64(); err == nil {
+				entries[k] = i
+			} else if f, err := value.Float64(); err == nil {
+				entries[k] = f
+			}
+		case string, bool:
+			entries[k] = value
+		}
+	}
+
+	return entries, nil
+}
+
+func (s *HTTPSource) stop() {
+	if s.client != nil {
+		s.client.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,63 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	common_redis "github.com/influxdata/telegraf/plugins/common/redis"
+)
+
+// RedisSource looks up the entries stored in a Redis hash
+type RedisSource struct {
+	KeyPrefix string `toml:"key_prefix"`
+	common_redis.ClientConfig
+
+	client *redis.Client
+}
+
+func (s *RedisSource) init() error {
+	if s.Address == "" {
+		return errors.New("missing 'address'")
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = config.Duration(defaultTimeout)
+	}
+	return nil
+}
+
+func (s *RedisSource) start(telegraf.Logger) error {
+	client, err := s.ClientConfig.NewClient()
+	if err != nil {
+		return fmt.Errorf("creating client failed: %w", err)
+	}
+	s.client = client
+
+	return nil
+}
+
+func (s *RedisSource) lookup(ctx context.Context, key string) (map[string]interface{}, error) {
+	result, err := s.client.HGetAll(ctx, s.KeyPrefix+key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	entries := make(map[string]interface{}, len(result))
+	for k, v := range result {
+		entries[k] = v
+	}
+	return entries, nil
+}
+
+func (s *RedisSource) stop() {
+	if s.client != nil {
+		s.client.Close()
+	}
+}
@@ -0,0 +1,91 @@
+package enrich
+
+import (
+	"context"
+	gosql "database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+)
+
+// SQLSource looks up the entries using a query returning a single row
+type SQLSource struct {
+	Driver string        `toml:"driver"`
+	DSN    config.Secret `toml:"dsn"`
+	Query  string        `toml:"query"`
+
+	db *gosql.DB
+}
+
+func (s *SQLSource) init() error {
+	if s.Driver == "" {
+		return errors.New("missing 'driver'")
+	}
+	if s.DSN.Empty() {
+		return errors.New("missing 'dsn'")
+	}
+	if s.Query == "" {
+		return errors.New("missing 'query'")
+	}
+	return nil
+}
+
+func (s *SQLSource) start(telegraf.Logger) error {
+	dsn, err := s.DSN.Get()
+	if err != nil {
+		return fmt.Errorf("getting DSN failed: %w", err)
+	}
+	defer dsn.Destroy()
+
+	db, err := gosql.Open(s.Driver, dsn.String())
+	if err != nil {
+		return err
+	}
+	s.db = db
+
+	return nil
+}
+
+func (s *SQLSource) lookup(ctx context.Context, key string) (map[string]interface{}, error) {
+	rows, err := s.db.QueryContext(ctx, s.Query, key)
+	if err != nil {
+		return nil, fmt.Errorf("executing query failed: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("getting columns failed: %w", err)
+	}
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, fmt.Errorf("scanning row failed: %w", err)
+	}
+
+	entries := make(map[string]interface{}, len(columns))
+	for i, name := range columns {
+		if v, ok := values[i].([]byte); ok {
+			entries[name] = string(v)
+		} else {
+			entries[name] = values[i]
+		}
+	}
+
+	return entries, rows.Err()
+}
+
+func (s *SQLSource) stop() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
@@ -0,0 +1,331 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package join
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Join struct {
+	KeyTags     []string        `toml:"key_tags"`
+	Tolerance   config.Duration `toml:"tolerance"`
+	Timeout     config.Duration `toml:"timeout"`
+	Mode        string          `toml:"mode"`
+	Measurement string          `toml:"measurement"`
+	MaxPending  int             `toml:"max_pending"`
+	Left        side            `toml:"left"`
+	Right       side            `toml:"right"`
+	Ratios      []ratio         `toml:"ratio"`
+	Log         telegraf.Logger `toml:"-"`
+
+	acc    telegraf.Accumulator
+	left   *pendingSet
+	right  *pendingSet
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	sync.Mutex
+}
+
+// side selects the metrics of one side of the join and defines how to
+// rename their fields in the joined metric
+type side struct {
+	Measurements []string          `toml:"measurements"`
+	FieldPrefix  string            `toml:"field_prefix"`
+	FieldRename  map[string]string `toml:"field_rename"`
+
+	filter filter.Filter
+}
+
+// ratio is a field computed by dividing two fields of the joined metric
+type ratio struct {
+	Field       string `toml:"field"`
+	Numerator   string `toml:"numerator"`
+	Denominator string `toml:"denominator"`
+}
+
+func (*Join) SampleConfig() string {
+	return sampleConfig
+}
+
+func (j *Join) Init() error {
+	if len(j.KeyTags) == 0 {
+		return errors.New("no key tags specified")
+	}
+
+	switch j.Mode {
+	case "":
+		j.Mode = "inner"
+	case "inner", "left":
+	default:
+		return fmt.Errorf("invalid mode %q", j.Mode)
+	}
+
+	if j.Tolerance < 0 {
+		return errors.New("'tolerance' must not be negative")
+	}
+	if j.Timeout <= 0 {
+		return errors.New("'timeout' must be greater than zero")
+	}
+	if j.MaxPending < 1 {
+		return errors.New("'max_pending' must be greater than zero")
+	}
+
+	if len(j.Left.Measurements) == 0 || len(j.Right.Measurements) == 0 {
+		return errors.New("measurements for both sides required")
+	}
+	var err error
+	if j.Left.filter, err = filter.Compile(j.Left.Measurements); err != nil {
+		return fmt.Errorf("creating left filter failed: %w", err)
+	}
+	if j.Right.filter, err = filter.Compile(j.Right.Measurements); err != nil {
+		return fmt.Errorf("creating right filter failed: %w", err)
+	}
+
+	for i, r := range j.Ratios {
+		if r.Field == "" || r.Numerator == "" || r.Denominator == "" {
+			return fmt.Errorf("ratio %d requires 'field', 'numerator' and 'denominator'", i+1)
+		}
+	}
+
+	j.left = newPendingSet()
+	j.right = newPendingSet()
+
+	return nil
+}
+
+func (j *Join) Start(acc telegraf.Accumulator) error {
+	j.acc = acc
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(expiryInterval(time.Duration(j.Timeout)))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.expire(time.Now())
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (j *Join) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	isLeft := j.Left.filter.Match(m.Name())
+	isRight := !isLeft && j.Right.filter.Match(m.Name())
+	if !isLeft && !isRight {
+		acc.AddMetric(m)
+		return nil
+	}
+
+	key, ok := j.key(m)
+	if !ok {
+		acc.AddMetric(m)
+		return nil
+	}
+
+	j.Lock()
+	defer j.Unlock()
+
+	now := time.Now()
+	own, other := j.left, j.right
+	if isRight {
+		own, other = j.right, j.left
+	}
+
+	if match := other.take(key, m.Time(), time.Duration(j.Tolerance)); match != nil {
+		if isLeft {
+			acc.AddMetric(j.join(m, match))
+		} else {
+			acc.AddMetric(j.join(match, m))
+		}
+		return nil
+	}
+
+	own.add(key, m, now)
+	if own.len() > j.MaxPending {
+		j.expireEntry(own, own.oldest())
+	}
+
+	return nil
+}
+
+func (j *Join) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+
+	// Flush all pending metrics
+	j.Lock()
+	defer j.Unlock()
+	for _, set := range []*pendingSet{j.left, j.right} {
+		for set.len() > 0 {
+			j.expireEntry(set, set.oldest())
+		}
+	}
+}
+
+// expire handles the pending metrics that were not matched within the
+// timeout
+func (j *Join) expire(now time.Time) {
+	j.Lock()
+	defer j.Unlock()
+
+	threshold := now.Add(-time.Duration(j.Timeout))
+	for _, set := range []*pendingSet{j.left, j.right} {
+		for set.len() > 0 {
+			e := set.oldest()
+			if e.added.After(threshold) {
+				break
+			}
+			j.expireEntry(set, e)
+		}
+	}
+}
+
+// expireEntry removes the unmatched entry and emits or drops its metric
+// depending on the join mode
+func (j *Join) expireEntry(set *pendingSet, e *entry) {
+	set.remove(e)
+	if set == j.left && j.Mode == "left" {
+		j.acc.AddMetric(j.join(e.metric, nil))
+		return
+	}
+	e.metric.Drop()
+}
+
+// key returns the values of the key tags of the metric and whether all key
+// tags exist
+func (j *Join) key(m telegraf.Metric) (string, bool) {
+	var b strings.Builder
+	for _, k := range j.KeyTags {
+		v, found := m.GetTag(k)
+		if !found {
+			return "", false
+		}
+		b.WriteString(v)
+		b.WriteByte(0)
+	}
+	return b.String(), true
+}
+
+// join merges the right metric into the left one. The right metric may be
+// nil for unmatched left metrics in left-join mode.
+func (j *Join) join(left, right telegraf.Metric) telegraf.Metric {
+	if j.Measurement != "" {
+		left.SetName(j.Measurement)
+	}
+	j.Left.renameFields(left)
+
+	if right != nil {
+		for _, tag := range right.TagList() {
+			if !left.HasTag(tag.Key) {
+				left.AddTag(tag.Key, tag.Value)
+			}
+		}
+		for _, field := range right.FieldList() {
+			left.AddField(j.Right.fieldName(field.Key), field.Value)
+		}
+		right.Drop()
+	}
+
+	for _, r := range j.Ratios {
+		num, ok := numeric(left, r.Numerator)
+		if !ok {
+			continue
+		}
+		den, ok := numeric(left, r.Denominator)
+		if !ok || den == 0 {
+			continue
+		}
+		left.AddField(r.Field, num/den)
+	}
+
+	return left
+}
+
+func (s *side) fieldName(key string) string {
+	if name, found := s.FieldRename[key]; found {
+		return name
+	}
+	return s.FieldPrefix + key
+}
+
+func (s *side) renameFields(m telegraf.Metric) {
+	if s.FieldPrefix == "" && len(s.FieldRename) == 0 {
+		return
+	}
+
+	fields := m.FieldList()
+	renamed := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		renamed[s.fieldName(field.Key)] = field.Value
+	}
+	for key := range m.Fields() {
+		m.RemoveField(key)
+	}
+	for key, value := range renamed {
+		m.AddField(key, value)
+	}
+}
+
+func numeric(m telegraf.Metric, key string) (float64, bool) {
+	v, found := m.GetField(key)
+	if !found {
+		return 0, false
+	}
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// expiryInterval returns the interval for checking for expired metrics
+func expiryInterval(timeout time.Duration) time.Duration {
+	interval := timeout / 4
+	if interval > time.Second {
+		interval = time.Second
+	}
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return interval
+}
+
+func init() {
+	processors.AddStreaming("join", func() telegraf.StreamingProcessor {
+		return &Join{
+			Tolerance:  config.Duration(time.Second),
+			Timeout:    config.Duration(10 * time.Second),
+			Mode:       "inner",
+			MaxPending: 10000,
+		}
+	})
+}
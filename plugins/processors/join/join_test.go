@@ -0,0 +1,282 @@
+package join
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newJoin() *Join {
+	return &Join{
+		KeyTags:    []string{"service"},
+		Tolerance:  config.Duration(time.Second),
+		Timeout:    config.Duration(time.Hour),
+		Mode:       "inner",
+		MaxPending: 100,
+		Left: side{
+			Measurements: []string{"procstat"},
+			FieldRename:  map[string]string{"cpu_usage": "cpu"},
+		},
+		Right: side{
+			Measurements: []string{"http_response"},
+			FieldPrefix:  "http_",
+			FieldRename:  map[string]string{"response_time": "latency"},
+		},
+		Log: testutil.Logger{},
+	}
+}
+
+func procstat(service string, cpu float64, ts time.Time) telegraf.Metric {
+	return metric.New(
+		"procstat",
+		map[string]string{"service": service, "host": "server01"},
+		map[string]interface{}{"cpu_usage": cpu},
+		ts,
+	)
+}
+
+func httpResponse(service string, latency float64, ts time.Time) telegraf.Metric {
+	return metric.New(
+		"http_response",
+		map[string]string{"service": service, "method": "GET"},
+		map[string]interface{}{"response_time": latency, "status_code": int64(200)},
+		ts,
+	)
+}
+
+func process(t *testing.T, plugin *Join, input ...telegraf.Metric) []telegraf.Metric {
+	t.Helper()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	plugin.Stop()
+
+	return acc.GetTelegrafMetrics()
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(j *Join)
+		expected string
+	}{
+		{
+			name:     "no key tags",
+			modify:   func(j *Join) { j.KeyTags = nil },
+			expected: "no key tags specified",
+		},
+		{
+			name:     "invalid mode",
+			modify:   func(j *Join) { j.Mode = "outer" },
+			expected: `invalid mode "outer"`,
+		},
+		{
+			name:     "missing side",
+			modify:   func(j *Join) { j.Right.Measurements = nil },
+			expected: "measurements for both sides required",
+		},
+		{
+			name:     "invalid ratio",
+			modify:   func(j *Join) { j.Ratios = []ratio{{Field: "x"}} },
+			expected: "ratio 1 requires",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newJoin()
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestInnerJoin(t *testing.T) {
+	plugin := newJoin()
+	plugin.Measurement = "service"
+	plugin.Ratios = []ratio{{Field: "cpu_per_latency", Numerator: "cpu", Denominator: "latency"}}
+	require.NoError(t, plugin.Init())
+
+	now := time.Unix(1700000000, 0)
+	input := []telegraf.Metric{
+		procstat("web", 20, now),
+		httpResponse("web", 0.5, now.Add(500*time.Millisecond)),
+		// Outside of the tolerance
+		procstat("db", 10, now),
+		httpResponse("db", 0.1, now.Add(2*time.Second)),
+		// Not part of the join
+		metric.New("cpu", map[string]string{"service": "web"}, map[string]interface{}{"usage": 1.0}, now),
+		// Missing key tag
+		metric.New("procstat", map[string]string{}, map[string]interface{}{"cpu_usage": 1.0}, now),
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"service": "web"}, map[string]interface{}{"usage": 1.0}, now),
+		metric.New("procstat", map[string]string{}, map[string]interface{}{"cpu_usage": 1.0}, now),
+		metric.New(
+			"service",
+			map[string]string{"service": "web", "host": "server01", "method": "GET"},
+			map[string]interface{}{
+				"cpu":              20.0,
+				"latency":          0.5,
+				"http_status_code": int64(200),
+				"cpu_per_latency":  40.0,
+			},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, process(t, plugin, input...), testutil.SortMetrics())
+}
+
+func TestLeftJoin(t *testing.T) {
+	plugin := newJoin()
+	plugin.Mode = "left"
+	require.NoError(t, plugin.Init())
+
+	now := time.Unix(1700000000, 0)
+	input := []telegraf.Metric{
+		// The right side may arrive first
+		httpResponse("web", 0.5, now),
+		procstat("web", 20, now),
+		// Unmatched metrics of both sides
+		procstat("db", 10, now),
+		httpResponse("cache", 0.1, now),
+	}
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"procstat",
+			map[string]string{"service": "web", "host": "server01", "method": "GET"},
+			map[string]interface{}{"cpu": 20.0, "latency": 0.5, "http_status_code": int64(200)},
+			now,
+		),
+		metric.New(
+			"procstat",
+			map[string]string{"service": "db", "host": "server01"},
+			map[string]interface{}{"cpu": 10.0},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, process(t, plugin, input...))
+}
+
+func TestClosestMatch(t *testing.T) {
+	plugin := newJoin()
+	plugin.Tolerance = config.Duration(5 * time.Second)
+	require.NoError(t, plugin.Init())
+
+	now := time.Unix(1700000000, 0)
+	input := []telegraf.Metric{
+		httpResponse("web", 0.1, now.Add(-3*time.Second)),
+		httpResponse("web", 0.2, now.Add(time.Second)),
+		httpResponse("web", 0.3, now.Add(4*time.Second)),
+		procstat("web", 20, now),
+	}
+
+	actual := process(t, plugin, input...)
+	require.Len(t, actual, 1)
+	require.Equal(t, 0.2, actual[0].Fields()["latency"])
+}
+
+func TestExpiry(t *testing.T) {
+	plugin := newJoin()
+	plugin.Mode = "left"
+	plugin.Timeout = config.Duration(time.Minute)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	now := time.Unix(1700000000, 0)
+	require.NoError(t, plugin.Add(procstat("web", 20, now), &acc))
+	require.NoError(t, plugin.Add(httpResponse("db", 0.1, now), &acc))
+
+	plugin.expire(time.Now())
+	require.Empty(t, acc.GetTelegrafMetrics())
+
+	plugin.expire(time.Now().Add(2 * time.Minute))
+	expected := []telegraf.Metric{
+		metric.New(
+			"procstat",
+			map[string]string{"service": "web", "host": "server01"},
+			map[string]interface{}{"cpu": 20.0},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.Zero(t, plugin.left.len())
+	require.Zero(t, plugin.right.len())
+
+	// A late counterpart is not joined anymore
+	require.NoError(t, plugin.Add(httpResponse("web", 0.5, now), &acc))
+	require.Equal(t, 1, plugin.right.len())
+}
+
+func TestMaxPending(t *testing.T) {
+	plugin := newJoin()
+	plugin.Mode = "left"
+	plugin.MaxPending = 2
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	now := time.Unix(1700000000, 0)
+	for _, service := range []string{"a", "b", "c"} {
+		require.NoError(t, plugin.Add(procstat(service, 1, now), &acc))
+	}
+
+	// The oldest metric is expired to stay within the limit
+	actual := acc.GetTelegrafMetrics()
+	require.Len(t, actual, 1)
+	require.Equal(t, "a", actual[0].Tags()["service"])
+	require.Equal(t, 2, plugin.left.len())
+}
+
+func TestTracking(t *testing.T) {
+	var mu sync.Mutex
+	delivered := make([]telegraf.DeliveryInfo, 0, 3)
+	notify := func(di telegraf.DeliveryInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, di)
+	}
+
+	now := time.Unix(1700000000, 0)
+	input := make([]telegraf.Metric, 0, 3)
+	for _, m := range []telegraf.Metric{
+		procstat("web", 20, now),
+		httpResponse("web", 0.5, now),
+		httpResponse("db", 0.5, now),
+	} {
+		tm, _ := metric.WithTracking(m, notify)
+		input = append(input, tm)
+	}
+
+	plugin := newJoin()
+	require.NoError(t, plugin.Init())
+
+	actual := process(t, plugin, input...)
+	require.Len(t, actual, 1)
+	for _, m := range actual {
+		m.Accept()
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(delivered) == len(input)
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,82 @@
+package join
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// entry is a metric waiting for its counterpart
+type entry struct {
+	key    string
+	metric telegraf.Metric
+	added  time.Time
+	elem   *list.Element
+}
+
+// pendingSet holds the unmatched metrics of one side by key and in the order
+// of their arrival to be able to expire the oldest ones first
+type pendingSet struct {
+	byKey map[string][]*entry
+	order *list.List
+}
+
+func newPendingSet() *pendingSet {
+	return &pendingSet{
+		byKey: make(map[string][]*entry),
+		order: list.New(),
+	}
+}
+
+func (s *pendingSet) len() int {
+	return s.order.Len()
+}
+
+func (s *pendingSet) add(key string, m telegraf.Metric, now time.Time) {
+	e := &entry{key: key, metric: m, added: now}
+	e.elem = s.order.PushBack(e)
+	s.byKey[key] = append(s.byKey[key], e)
+}
+
+func (s *pendingSet) oldest() *entry {
+	return s.order.Front().Value.(*entry)
+}
+
+func (s *pendingSet) remove(e *entry) {
+	s.order.Remove(e.elem)
+
+	entries := s.byKey[e.key]
+	for i, candidate := range entries {
+		if candidate == e {
+			entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	if len(entries) == 0 {
+		delete(s.byKey, e.key)
+	} else {
+		s.byKey[e.key] = entries
+	}
+}
+
+// take removes and returns the metric with the given key closest to the
+// given time within the tolerance, nil if there is none
+func (s *pendingSet) take(key string, ts time.Time, tolerance time.Duration) telegraf.Metric {
+	var best *entry
+	var bestDiff time.Duration
+	for _, e := range s.byKey[key] {
+		diff := e.metric.Time().Sub(ts).Abs()
+		if diff > tolerance {
+			continue
+		}
+		if best == nil || diff < bestDiff {
+			best, bestDiff = e, diff
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	s.remove(best)
+	return best.metric
+}
@@ -0,0 +1,131 @@
+package k8s_attributes
+
+import (
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podEntry holds the tags to add for a pod and the identifiers the pod is
+// indexed with
+type podEntry struct {
+	tags         map[string]string
+	ip           string
+	containerIDs []string
+}
+
+// podIndex allows to find the tags of a pod by its IP, UID or container ID
+type podIndex struct {
+	byUID         map[string]*podEntry
+	byIP          map[string]*podEntry
+	byContainerID map[string]*podEntry
+	sync.RWMutex
+}
+
+func newPodIndex() *podIndex {
+	return &podIndex{
+		byUID:         make(map[string]*podEntry),
+		byIP:          make(map[string]*podEntry),
+		byContainerID: make(map[string]*podEntry),
+	}
+}
+
+func (idx *podIndex) add(pod *corev1.Pod, tags map[string]string) {
+	entry := &podEntry{tags: tags}
+
+	// Pods in the host network share the node's IP and cannot be identified
+	// by IP. The IP of completed pods is released and might be reused by a
+	// running pod, so only index pods not terminated yet.
+	terminated := pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+	if !pod.Spec.HostNetwork && !terminated {
+		entry.ip = pod.Status.PodIP
+	}
+
+	statuses := make([]corev1.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if id := trimContainerID(status.ContainerID); id != "" {
+			entry.containerIDs = append(entry.containerIDs, id)
+		}
+	}
+
+	idx.Lock()
+	defer idx.Unlock()
+
+	idx.removeLocked(pod.UID)
+	idx.byUID[string(pod.UID)] = entry
+	if entry.ip != "" {
+		idx.byIP[entry.ip] = entry
+	}
+	for _, id := range entry.containerIDs {
+		idx.byContainerID[id] = entry
+	}
+}
+
+func (idx *podIndex) remove(uid types.UID) {
+	idx.Lock()
+	defer idx.Unlock()
+
+	idx.removeLocked(uid)
+}
+
+func (idx *podIndex) removeLocked(uid types.UID) {
+	entry, found := idx.byUID[string(uid)]
+	if !found {
+		return
+	}
+	delete(idx.byUID, string(uid))
+	// Only remove the IP if it was not reused by another pod in the meantime
+	if entry.ip != "" && idx.byIP[entry.ip] == entry {
+		delete(idx.byIP, entry.ip)
+	}
+	for _, id := range entry.containerIDs {
+		if idx.byContainerID[id] == entry {
+			delete(idx.byContainerID, id)
+		}
+	}
+}
+
+func (idx *podIndex) getByUID(uid string) (map[string]string, bool) {
+	idx.RLock()
+	defer idx.RUnlock()
+
+	entry, found := idx.byUID[uid]
+	if !found {
+		return nil, false
+	}
+	return entry.tags, true
+}
+
+func (idx *podIndex) getByIP(ip string) (map[string]string, bool) {
+	idx.RLock()
+	defer idx.RUnlock()
+
+	entry, found := idx.byIP[ip]
+	if !found {
+		return nil, false
+	}
+	return entry.tags, true
+}
+
+func (idx *podIndex) getByContainerID(id string) (map[string]string, bool) {
+	idx.RLock()
+	defer idx.RUnlock()
+
+	entry, found := idx.byContainerID[trimContainerID(id)]
+	if !found {
+		return nil, false
+	}
+	return entry.tags, true
+}
+
+// trimContainerID removes the runtime prefix like "containerd://" from the ID
+func trimContainerID(id string) string {
+	if i := strings.Index(id, "://"); i >= 0 {
+		return id[i+3:]
+	}
+	return id
+}
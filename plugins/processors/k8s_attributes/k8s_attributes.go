@@ -0,0 +1,313 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package k8s_attributes
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+var availableMetadata = []string{
+	"namespace",
+	"pod_name",
+	"pod_uid",
+	"node_name",
+	"host_ip",
+	"deployment",
+	"replicaset",
+	"statefulset",
+	"daemonset",
+	"job",
+}
+
+type K8sAttributes struct {
+	KubeConfig       string          `toml:"kubeconfig"`
+	Namespace        string          `toml:"namespace"`
+	NodeName         string          `toml:"node_name"`
+	ContainerIDTags  []string        `toml:"container_id_tags"`
+	UIDTags          []string        `toml:"uid_tags"`
+	IPTags           []string        `toml:"ip_tags"`
+	Metadata         []string        `toml:"metadata"`
+	Labels           []string        `toml:"labels"`
+	Annotations      []string        `toml:"annotations"`
+	LabelPrefix      string          `toml:"label_prefix"`
+	AnnotationPrefix string          `toml:"annotation_prefix"`
+	ResyncInterval   config.Duration `toml:"resync_interval"`
+	StartupTimeout   config.Duration `toml:"startup_timeout"`
+	Log              telegraf.Logger `toml:"-"`
+
+	client           kubernetes.Interface
+	metadata         map[string]bool
+	labelFilter      filter.Filter
+	annotationFilter filter.Filter
+	index            *podIndex
+	cancel           context.CancelFunc
+	factory          informers.SharedInformerFactory
+}
+
+func (*K8sAttributes) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *K8sAttributes) Init() error {
+	if len(k.ContainerIDTags) == 0 && len(k.UIDTags) == 0 && len(k.IPTags) == 0 {
+		return errors.New("no tags for identifying pods specified")
+	}
+
+	k.metadata = make(map[string]bool, len(k.Metadata))
+	for _, m := range k.Metadata {
+		if !isAvailableMetadata(m) {
+			return fmt.Errorf("invalid metadata %q", m)
+		}
+		k.metadata[m] = true
+	}
+
+	var err error
+	if k.labelFilter, err = filter.Compile(k.Labels); err != nil {
+		return fmt.Errorf("creating label filter failed: %w", err)
+	}
+	if k.annotationFilter, err = filter.Compile(k.Annotations); err != nil {
+		return fmt.Errorf("creating annotation filter failed: %w", err)
+	}
+
+	k.index = newPodIndex()
+
+	return nil
+}
+
+func (k *K8sAttributes) Start(telegraf.Accumulator) error {
+	if k.client == nil {
+		client, err := newClient(k.KubeConfig)
+		if err != nil {
+			return err
+		}
+		k.client = client
+	}
+
+	var options []informers.SharedInformerOption
+	if k.Namespace != "" {
+		options = append(options, informers.WithNamespace(k.Namespace))
+	}
+	if k.NodeName != "" {
+		selector := fields.OneTermEqualSelector("spec.nodeName", k.NodeName).String()
+		options = append(options, informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.FieldSelector = selector
+		}))
+	}
+	k.factory = informers.NewSharedInformerFactoryWithOptions(k.client, time.Duration(k.ResyncInterval), options...)
+
+	informer := k.factory.Core().V1().Pods().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				k.index.add(pod, k.podTags(pod))
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				k.index.add(pod, k.podTags(pod))
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*corev1.Pod); ok {
+				k.index.remove(pod.UID)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("adding event handler failed: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	k.cancel = cancel
+	k.factory.Start(ctx.Done())
+
+	// Wait for the initial list of pods to be able to enrich the first metrics
+	syncCtx, syncCancel := context.WithTimeout(ctx, time.Duration(k.StartupTimeout))
+	defer syncCancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+		k.Log.Warn("Timeout waiting for the initial pod list, metrics might not be enriched until synced")
+	}
+
+	return nil
+}
+
+func (k *K8sAttributes) Add(metric telegraf.Metric, acc telegraf.Accumulator) error {
+	if tags, found := k.lookup(metric); found {
+		for key, value := range tags {
+			if !metric.HasTag(key) {
+				metric.AddTag(key, value)
+			}
+		}
+	}
+	acc.AddMetric(metric)
+
+	return nil
+}
+
+func (k *K8sAttributes) Stop() {
+	if k.cancel != nil {
+		k.cancel()
+	}
+	if k.factory != nil {
+		k.factory.Shutdown()
+	}
+}
+
+func (k *K8sAttributes) lookup(metric telegraf.Metric) (map[string]string, bool) {
+	for _, key := range k.ContainerIDTags {
+		if id, ok := identifier(metric, key); ok {
+			if tags, found := k.index.getByContainerID(id); found {
+				return tags, true
+			}
+		}
+	}
+	for _, key := range k.UIDTags {
+		if uid, ok := identifier(metric, key); ok {
+			if tags, found := k.index.getByUID(uid); found {
+				return tags, true
+			}
+		}
+	}
+	for _, key := range k.IPTags {
+		if ip, ok := identifier(metric, key); ok {
+			if tags, found := k.index.getByIP(ip); found {
+				return tags, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// podTags collects the configured metadata, labels and annotations of the pod
+func (k *K8sAttributes) podTags(pod *corev1.Pod) map[string]string {
+	tags := make(map[string]string)
+	add := func(key, value string) {
+		if k.metadata[key] && value != "" {
+			tags[key] = value
+		}
+	}
+
+	add("namespace", pod.Namespace)
+	add("pod_name", pod.Name)
+	add("pod_uid", string(pod.UID))
+	add("node_name", pod.Spec.NodeName)
+	add("host_ip", pod.Status.HostIP)
+
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+		switch owner.Kind {
+		case "ReplicaSet":
+			add("replicaset", owner.Name)
+			// Deployments create replica-sets named after the deployment with
+			// the pod-template hash as suffix
+			if hash, found := pod.Labels["pod-template-hash"]; found {
+				if name, found := strings.CutSuffix(owner.Name, "-"+hash); found {
+					add("deployment", name)
+				}
+			}
+		case "StatefulSet":
+			add("statefulset", owner.Name)
+		case "DaemonSet":
+			add("daemonset", owner.Name)
+		case "Job":
+			add("job", owner.Name)
+		}
+	}
+
+	if k.labelFilter != nil {
+		for key, value := range pod.Labels {
+			if k.labelFilter.Match(key) {
+				tags[k.LabelPrefix+key] = value
+			}
+		}
+	}
+	if k.annotationFilter != nil {
+		for key, value := range pod.Annotations {
+			if k.annotationFilter.Match(key) {
+				tags[k.AnnotationPrefix+key] = value
+			}
+		}
+	}
+
+	return tags
+}
+
+// identifier returns the value of the tag or string field with the given key
+func identifier(metric telegraf.Metric, key string) (string, bool) {
+	if v, found := metric.GetTag(key); found && v != "" {
+		return v, true
+	}
+	if v, found := metric.GetField(key); found {
+		if s, ok := v.(string); ok && s != "" {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+func isAvailableMetadata(name string) bool {
+	for _, m := range availableMetadata {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+func newClient(kubeconfig string) (kubernetes.Interface, error) {
+	var cfg *rest.Config
+	var err error
+	if kubeconfig == "" {
+		cfg, err = rest.InClusterConfig()
+	} else {
+		cfg, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading kubernetes config failed: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating kubernetes client failed: %w", err)
+	}
+	return client, nil
+}
+
+func init() {
+	processors.AddStreaming("k8s_attributes", func() telegraf.StreamingProcessor {
+		return &K8sAttributes{
+			ContainerIDTags: []string{"container_id"},
+			UIDTags:         []string{"pod_uid"},
+			IPTags:          []string{"pod_ip"},
+			Metadata:        []string{"namespace", "pod_name", "node_name"},
+			ResyncInterval:  config.Duration(5 * time.Minute),
+			StartupTimeout:  config.Duration(30 * time.Second),
+		}
+	})
+}
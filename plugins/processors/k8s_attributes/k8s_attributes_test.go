@@ -0,0 +1,246 @@
+package k8s_attributes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newK8sAttributes() *K8sAttributes {
+	return &K8sAttributes{
+		ContainerIDTags: []string{"container_id"},
+		UIDTags:         []string{"pod_uid"},
+		IPTags:          []string{"pod_ip"},
+		Metadata:        []string{"namespace", "pod_name", "node_name"},
+		StartupTimeout:  config.Duration(5 * time.Second),
+		Log:             testutil.Logger{},
+	}
+}
+
+func newPod() *corev1.Pod {
+	controller := true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web-7d4b9c8f6d-x2x9z",
+			Namespace: "default",
+			UID:       "b2c4f3a0-1111-2222-3333-444455556666",
+			Labels: map[string]string{
+				"app":               "web",
+				"team":              "frontend",
+				"pod-template-hash": "7d4b9c8f6d",
+			},
+			Annotations: map[string]string{
+				"example.com/owner": "alice",
+				"example.com/other": "ignored",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ReplicaSet", Name: "web-7d4b9c8f6d", Controller: &controller},
+			},
+		},
+		Spec: corev1.PodSpec{NodeName: "node01"},
+		Status: corev1.PodStatus{
+			PodIP:  "10.1.2.3",
+			HostIP: "192.168.0.10",
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "web", ContainerID: "containerd://abcdef0123456789"},
+			},
+		},
+	}
+}
+
+func cpu(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	if fields == nil {
+		fields = map[string]interface{}{"usage": 42.0}
+	}
+	return metric.New("cpu", tags, fields, time.Unix(0, 0))
+}
+
+func TestInitFail(t *testing.T) {
+	plugin := newK8sAttributes()
+	plugin.Metadata = []string{"foo"}
+	require.ErrorContains(t, plugin.Init(), `invalid metadata "foo"`)
+
+	plugin = newK8sAttributes()
+	plugin.ContainerIDTags = nil
+	plugin.UIDTags = nil
+	plugin.IPTags = nil
+	require.ErrorContains(t, plugin.Init(), "no tags for identifying pods specified")
+}
+
+func TestLookup(t *testing.T) {
+	plugin := newK8sAttributes()
+	plugin.Metadata = []string{"namespace", "pod_name", "deployment", "replicaset"}
+	plugin.Labels = []string{"app"}
+	plugin.LabelPrefix = "label_"
+	plugin.Annotations = []string{"example.com/owner"}
+	plugin.client = fake.NewSimpleClientset(newPod())
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	podTags := map[string]string{
+		"namespace":         "default",
+		"pod_name":          "web-7d4b9c8f6d-x2x9z",
+		"deployment":        "web",
+		"replicaset":        "web-7d4b9c8f6d",
+		"label_app":         "web",
+		"example.com/owner": "alice",
+	}
+	withTags := func(tags map[string]string) map[string]string {
+		for k, v := range podTags {
+			tags[k] = v
+		}
+		return tags
+	}
+
+	input := []telegraf.Metric{
+		cpu(map[string]string{"container_id": "abcdef0123456789"}, nil),
+		cpu(map[string]string{"pod_uid": "b2c4f3a0-1111-2222-3333-444455556666"}, nil),
+		cpu(map[string]string{"pod_ip": "10.1.2.3"}, nil),
+		cpu(nil, map[string]interface{}{"usage": 42.0, "container_id": "abcdef0123456789"}),
+		cpu(map[string]string{"pod_ip": "10.9.9.9"}, nil),
+		cpu(map[string]string{"pod_ip": "10.1.2.3", "namespace": "existing"}, nil),
+	}
+	expected := []telegraf.Metric{
+		cpu(withTags(map[string]string{"container_id": "abcdef0123456789"}), nil),
+		cpu(withTags(map[string]string{"pod_uid": "b2c4f3a0-1111-2222-3333-444455556666"}), nil),
+		cpu(withTags(map[string]string{"pod_ip": "10.1.2.3"}), nil),
+		cpu(withTags(map[string]string{}), map[string]interface{}{"usage": 42.0, "container_id": "abcdef0123456789"}),
+		cpu(map[string]string{"pod_ip": "10.9.9.9"}, nil),
+		cpu(withTags(map[string]string{"pod_ip": "10.1.2.3"}), nil),
+	}
+	// Existing tags must not be overwritten
+	expected[5].AddTag("namespace", "existing")
+
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestPodUpdateAndDelete(t *testing.T) {
+	pod := newPod()
+	client := fake.NewSimpleClientset(pod)
+
+	plugin := newK8sAttributes()
+	plugin.Metadata = []string{"pod_name"}
+	plugin.Labels = []string{"team"}
+	plugin.client = client
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	tags, found := plugin.index.getByIP("10.1.2.3")
+	require.True(t, found)
+	require.Equal(t, map[string]string{"pod_name": "web-7d4b9c8f6d-x2x9z", "team": "frontend"}, tags)
+
+	// Update the pod's labels and IP
+	updated := pod.DeepCopy()
+	updated.Labels["team"] = "backend"
+	updated.Status.PodIP = "10.1.2.4"
+	_, err := client.CoreV1().Pods("default").Update(context.Background(), updated, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		tags, found := plugin.index.getByIP("10.1.2.4")
+		return found && tags["team"] == "backend"
+	}, 5*time.Second, 10*time.Millisecond)
+	_, found = plugin.index.getByIP("10.1.2.3")
+	require.False(t, found)
+
+	// Delete the pod
+	err = client.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, found := plugin.index.getByUID(string(pod.UID))
+		return !found
+	}, 5*time.Second, 10*time.Millisecond)
+	_, found = plugin.index.getByContainerID("abcdef0123456789")
+	require.False(t, found)
+}
+
+func TestHostNetworkPodNotIndexedByIP(t *testing.T) {
+	pod := newPod()
+	pod.Spec.HostNetwork = true
+	pod.Status.PodIP = "192.168.0.10"
+
+	plugin := newK8sAttributes()
+	plugin.client = fake.NewSimpleClientset(pod)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	_, found := plugin.index.getByIP("192.168.0.10")
+	require.False(t, found)
+	_, found = plugin.index.getByContainerID("containerd://abcdef0123456789")
+	require.True(t, found)
+}
+
+func TestCompletedPodIPReuse(t *testing.T) {
+	completed := newPod()
+	completed.Status.Phase = corev1.PodRunning
+
+	running := newPod()
+	running.Name = "web-7d4b9c8f6d-a1b2c"
+	running.UID = "c3d5e7f9-1111-2222-3333-444455556666"
+	running.Status.Phase = corev1.PodRunning
+	running.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{Name: "web", ContainerID: "containerd://0123456789abcdef"},
+	}
+
+	client := fake.NewSimpleClientset(completed)
+
+	plugin := newK8sAttributes()
+	plugin.Metadata = []string{"pod_name"}
+	plugin.client = client
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// The first pod completes and its IP is assigned to a new running pod
+	updated := completed.DeepCopy()
+	updated.Status.Phase = corev1.PodSucceeded
+	_, err := client.CoreV1().Pods("default").Update(context.Background(), updated, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	_, err = client.CoreV1().Pods("default").Create(context.Background(), running, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		tags, found := plugin.index.getByIP("10.1.2.3")
+		return found && tags["pod_name"] == running.Name
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Further updates of the completed pod do not take over the IP
+	updated = updated.DeepCopy()
+	updated.Labels["team"] = "backend"
+	_, err = client.CoreV1().Pods("default").Update(context.Background(), updated, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, found := plugin.index.getByUID(string(updated.UID))
+		return found
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Never(t, func() bool {
+		tags, found := plugin.index.getByIP("10.1.2.3")
+		return !found || tags["pod_name"] != running.Name
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
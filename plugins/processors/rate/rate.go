@@ -0,0 +1,295 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package rate
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Rate struct {
+	Fields      []string        `toml:"fields"`
+	Mode        string          `toml:"mode"`
+	RateUnit    config.Duration `toml:"rate_unit"`
+	Suffix      string          `toml:"suffix"`
+	CounterBits int             `toml:"counter_bits"`
+	ResetMode   string          `toml:"reset_mode"`
+	TTL         config.Duration `toml:"ttl"`
+	Log         telegraf.Logger `toml:"-"`
+
+	filter      filter.Filter
+	maxValue    uint64
+	series      map[uint64]map[string]*counter
+	lastCleanup time.Time
+}
+
+// counter is the last observed value of a counter field. Non-negative
+// integer values are kept as raw value to avoid precision loss for large
+// counters, all other values are kept as float.
+type counter struct {
+	Raw       uint64  `json:"raw,omitempty"`
+	Float     float64 `json:"float,omitempty"`
+	IsFloat   bool    `json:"is_float,omitempty"`
+	Timestamp int64   `json:"timestamp"`
+	Updated   int64   `json:"updated"`
+}
+
+func (*Rate) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rate) Init() error {
+	switch r.Mode {
+	case "":
+		r.Mode = "rate"
+	case "rate", "delta":
+	default:
+		return fmt.Errorf("invalid mode %q", r.Mode)
+	}
+
+	switch r.CounterBits {
+	case 0:
+	case 32:
+		r.maxValue = math.MaxUint32
+	case 64:
+		r.maxValue = math.MaxUint64
+	default:
+		return fmt.Errorf("invalid counter bits %d", r.CounterBits)
+	}
+
+	switch r.ResetMode {
+	case "":
+		r.ResetMode = "skip"
+	case "skip", "value":
+	default:
+		return fmt.Errorf("invalid reset mode %q", r.ResetMode)
+	}
+
+	if r.RateUnit <= 0 {
+		return errors.New("rate_unit must be positive")
+	}
+
+	if len(r.Fields) == 0 {
+		r.Fields = []string{"*"}
+	}
+	f, err := filter.Compile(r.Fields)
+	if err != nil {
+		return fmt.Errorf("creating field filter failed: %w", err)
+	}
+	r.filter = f
+	r.series = make(map[uint64]map[string]*counter)
+
+	return nil
+}
+
+func (r *Rate) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := time.Now()
+	r.cleanup(now)
+
+	out := in[:0]
+	for _, m := range in {
+		id := m.HashID()
+		fields, found := r.series[id]
+		if !found {
+			fields = make(map[string]*counter)
+			r.series[id] = fields
+		}
+
+		// Copy the field list as we modify the fields while iterating
+		for _, field := range slices.Clone(m.FieldList()) {
+			if !r.filter.Match(field.Key) {
+				continue
+			}
+			current, ok := newCounter(field.Value, m.Time(), now)
+			if !ok {
+				continue
+			}
+
+			previous := fields[field.Key]
+			fields[field.Key] = current
+
+			// The first observation of a counter does not yield a result
+			var result interface{}
+			ok = previous != nil
+			if ok {
+				result, ok = r.calculate(previous, current, field.Value)
+			}
+
+			if r.Suffix == "" {
+				if ok {
+					m.AddField(field.Key, result)
+				} else {
+					m.RemoveField(field.Key)
+				}
+			} else if ok {
+				m.AddField(field.Key+r.Suffix, result)
+			}
+		}
+
+		// Drop metrics where all fields were removed
+		if len(m.FieldList()) == 0 {
+			m.Drop()
+			continue
+		}
+		out = append(out, m)
+	}
+
+	return out
+}
+
+func (r *Rate) GetState() interface{} {
+	return r.series
+}
+
+func (r *Rate) SetState(state interface{}) error {
+	series, ok := state.(map[uint64]map[string]*counter)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+	for id, fields := range series {
+		r.series[id] = fields
+	}
+	// Remove the state that went stale while we were not running
+	r.lastCleanup = time.Time{}
+	r.cleanup(time.Now())
+
+	return nil
+}
+
+// calculate returns the delta or rate between the previous and the current
+// value, the original value is used to determine the type of deltas.
+func (r *Rate) calculate(previous, current *counter, original interface{}) (interface{}, bool) {
+	var delta float64
+	var raw uint64
+	switch {
+	case previous.IsFloat || current.IsFloat:
+		prev, cur := previous.value(), current.value()
+		if cur < prev {
+			if r.ResetMode == "skip" {
+				return nil, false
+			}
+			delta = cur
+		} else {
+			delta = cur - prev
+		}
+	case current.Raw >= previous.Raw:
+		raw = current.Raw - previous.Raw
+		delta = float64(raw)
+	default:
+		if wrapped, ok := r.wraparound(previous.Raw, current.Raw); ok {
+			raw = wrapped
+		} else if r.ResetMode == "skip" {
+			r.Log.Debugf("Counter reset detected from %d to %d", previous.Raw, current.Raw)
+			return nil, false
+		} else {
+			raw = current.Raw
+		}
+		delta = float64(raw)
+	}
+
+	if r.Mode == "rate" {
+		elapsed := current.Timestamp - previous.Timestamp
+		if elapsed <= 0 {
+			return nil, false
+		}
+		return delta * float64(r.RateUnit) / float64(elapsed), true
+	}
+
+	// Keep the type of the original field for deltas
+	switch original.(type) {
+	case int64:
+		if previous.IsFloat || current.IsFloat {
+			return int64(delta), true
+		}
+		return int64(raw), true
+	case uint64:
+		return raw, true
+	}
+	return delta, true
+}
+
+// wraparound checks if the decrease from previous to current can be explained
+// by the counter wrapping around at its maximum value. Wraparound is assumed if
+// the change is less than half of the counter range, otherwise the decrease is
+// considered a counter reset.
+func (r *Rate) wraparound(previous, current uint64) (uint64, bool) {
+	if r.maxValue == 0 || previous > r.maxValue || current > r.maxValue {
+		return 0, false
+	}
+	delta := (r.maxValue - previous) + current + 1
+	return delta, delta <= r.maxValue/2
+}
+
+// Remove the state of series not updated within the TTL
+func (r *Rate) cleanup(now time.Time) {
+	ttl := time.Duration(r.TTL)
+	if ttl <= 0 {
+		return
+	}
+	// No need to cleanup too often, use a tenth of the TTL
+	if now.Sub(r.lastCleanup) < ttl/10 {
+		return
+	}
+	r.lastCleanup = now
+
+	threshold := now.Add(-ttl).UnixNano()
+	for id, fields := range r.series {
+		for key, c := range fields {
+			if c.Updated < threshold {
+				delete(fields, key)
+			}
+		}
+		if len(fields) == 0 {
+			delete(r.series, id)
+		}
+	}
+}
+
+func newCounter(v interface{}, ts, now time.Time) (*counter, bool) {
+	c := &counter{Timestamp: ts.UnixNano(), Updated: now.UnixNano()}
+	switch value := v.(type) {
+	case int64:
+		if value < 0 {
+			c.Float, c.IsFloat = float64(value), true
+		} else {
+			c.Raw = uint64(value)
+		}
+	case uint64:
+		c.Raw = value
+	case float64:
+		c.Float, c.IsFloat = value, true
+	default:
+		return nil, false
+	}
+	return c, true
+}
+
+func (c *counter) value() float64 {
+	if c.IsFloat {
+		return c.Float
+	}
+	return float64(c.Raw)
+}
+
+func init() {
+	processors.Add("rate", func() telegraf.Processor {
+		return &Rate{
+			Fields:    []string{"*"},
+			Mode:      "rate",
+			RateUnit:  config.Duration(time.Second),
+			ResetMode: "skip",
+			TTL:       config.Duration(10 * time.Minute),
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package rate
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newRate() *Rate {
+	return &Rate{
+		Fields:    []string{"*"},
+		Mode:      "rate",
+		RateUnit:  config.Duration(time.Second),
+		ResetMode: "skip",
+		TTL:       config.Duration(10 * time.Minute),
+		Log:       testutil.Logger{},
+	}
+}
+
+func counters(values ...interface{}) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, len(values))
+	for i, v := range values {
+		metrics = append(metrics, metric.New(
+			"net",
+			map[string]string{"interface": "eth0"},
+			map[string]interface{}{"bytes_recv": v, "state": "up"},
+			time.Unix(int64(10*i), 0),
+		))
+	}
+	return metrics
+}
+
+func expected(values ...interface{}) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, len(values))
+	for i, v := range values {
+		fields := map[string]interface{}{"state": "up"}
+		if v != nil {
+			fields["bytes_recv"] = v
+		}
+		metrics = append(metrics, metric.New(
+			"net",
+			map[string]string{"interface": "eth0"},
+			fields,
+			time.Unix(int64(10*i), 0),
+		))
+	}
+	return metrics
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(r *Rate)
+		expected string
+	}{
+		{
+			name:     "invalid mode",
+			modify:   func(r *Rate) { r.Mode = "foo" },
+			expected: `invalid mode "foo"`,
+		},
+		{
+			name:     "invalid counter bits",
+			modify:   func(r *Rate) { r.CounterBits = 16 },
+			expected: "invalid counter bits 16",
+		},
+		{
+			name:     "invalid reset mode",
+			modify:   func(r *Rate) { r.ResetMode = "foo" },
+			expected: `invalid reset mode "foo"`,
+		},
+		{
+			name:     "invalid rate unit",
+			modify:   func(r *Rate) { r.RateUnit = 0 },
+			expected: "rate_unit must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newRate()
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestCases(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(r *Rate)
+		input    []telegraf.Metric
+		expected []telegraf.Metric
+	}{
+		{
+			name:     "rate",
+			input:    counters(uint64(100), uint64(200), uint64(500)),
+			expected: expected(nil, 10.0, 30.0),
+		},
+		{
+			name:     "rate per minute",
+			modify:   func(r *Rate) { r.RateUnit = config.Duration(time.Minute) },
+			input:    counters(uint64(100), uint64(200)),
+			expected: expected(nil, 600.0),
+		},
+		{
+			name:     "delta keeps type",
+			modify:   func(r *Rate) { r.Mode = "delta" },
+			input:    counters(int64(100), int64(200), int64(250)),
+			expected: expected(nil, int64(100), int64(50)),
+		},
+		{
+			name:     "delta of floats",
+			modify:   func(r *Rate) { r.Mode = "delta" },
+			input:    counters(1.5, 2.0),
+			expected: expected(nil, 0.5),
+		},
+		{
+			name:     "reset skipped",
+			modify:   func(r *Rate) { r.Mode = "delta" },
+			input:    counters(uint64(100), uint64(20), uint64(50)),
+			expected: expected(nil, nil, uint64(30)),
+		},
+		{
+			name: "reset with value",
+			modify: func(r *Rate) {
+				r.Mode = "delta"
+				r.ResetMode = "value"
+			},
+			input:    counters(uint64(100), uint64(20)),
+			expected: expected(nil, uint64(20)),
+		},
+		{
+			name: "32-bit wraparound",
+			modify: func(r *Rate) {
+				r.Mode = "delta"
+				r.CounterBits = 32
+			},
+			input:    counters(uint64(math.MaxUint32-9), uint64(10)),
+			expected: expected(nil, uint64(20)),
+		},
+		{
+			name: "32-bit reset",
+			modify: func(r *Rate) {
+				r.Mode = "delta"
+				r.CounterBits = 32
+			},
+			input:    counters(uint64(1000), uint64(10)),
+			expected: expected(nil, nil),
+		},
+		{
+			name: "64-bit wraparound",
+			modify: func(r *Rate) {
+				r.Mode = "delta"
+				r.CounterBits = 64
+			},
+			input:    counters(uint64(math.MaxUint64-4), uint64(5)),
+			expected: expected(nil, uint64(10)),
+		},
+		{
+			name:   "suffix",
+			modify: func(r *Rate) { r.Suffix = "_rate" },
+			input:  counters(uint64(100), uint64(200)),
+			expected: []telegraf.Metric{
+				counters(uint64(100))[0],
+				metric.New(
+					"net",
+					map[string]string{"interface": "eth0"},
+					map[string]interface{}{"bytes_recv": uint64(200), "bytes_recv_rate": 10.0, "state": "up"},
+					time.Unix(10, 0),
+				),
+			},
+		},
+		{
+			name:   "field selection",
+			modify: func(r *Rate) { r.Fields = []string{"packets_*"} },
+			input:  counters(uint64(100), uint64(200)),
+			expected: []telegraf.Metric{
+				counters(uint64(100))[0],
+				counters(uint64(100), uint64(200))[1],
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newRate()
+			if tt.modify != nil {
+				tt.modify(plugin)
+			}
+			require.NoError(t, plugin.Init())
+
+			var actual []telegraf.Metric
+			for _, m := range tt.input {
+				actual = append(actual, plugin.Apply(m)...)
+			}
+			testutil.RequireMetricsEqual(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestDropEmptyMetrics(t *testing.T) {
+	plugin := newRate()
+	require.NoError(t, plugin.Init())
+
+	input := metric.New("diskio", map[string]string{}, map[string]interface{}{"reads": uint64(1)}, time.Unix(0, 0))
+	require.Empty(t, plugin.Apply(input))
+}
+
+func TestSeparateSeries(t *testing.T) {
+	plugin := newRate()
+	plugin.Mode = "delta"
+	require.NoError(t, plugin.Init())
+
+	eth0 := counters(uint64(100), uint64(200))
+	eth1 := []telegraf.Metric{
+		metric.New("net", map[string]string{"interface": "eth1"}, map[string]interface{}{"bytes_recv": uint64(5)}, time.Unix(0, 0)),
+		metric.New("net", map[string]string{"interface": "eth1"}, map[string]interface{}{"bytes_recv": uint64(7)}, time.Unix(10, 0)),
+	}
+
+	actual := plugin.Apply(eth0[0], eth1[0], eth0[1], eth1[1])
+	want := []telegraf.Metric{
+		expected(nil)[0],
+		expected(nil, uint64(100))[1],
+		metric.New("net", map[string]string{"interface": "eth1"}, map[string]interface{}{"bytes_recv": uint64(2)}, time.Unix(10, 0)),
+	}
+	testutil.RequireMetricsEqual(t, want, actual)
+}
+
+func TestTTL(t *testing.T) {
+	plugin := newRate()
+	plugin.Mode = "delta"
+	plugin.TTL = config.Duration(time.Nanosecond)
+	require.NoError(t, plugin.Init())
+
+	input := counters(uint64(100), uint64(200))
+	plugin.Apply(input[0])
+	time.Sleep(time.Millisecond)
+
+	// The state expired so the second metric is treated as first observation
+	actual := plugin.Apply(input[1])
+	testutil.RequireMetricsEqual(t, expected(nil, nil)[1:], actual)
+}
+
+func TestState(t *testing.T) {
+	plugin := newRate()
+	plugin.Mode = "delta"
+	require.NoError(t, plugin.Init())
+
+	input := counters(uint64(100), uint64(250))
+	plugin.Apply(input[0])
+
+	// Serialize and restore the state the same way the persister does
+	buf, err := json.Marshal(plugin.GetState())
+	require.NoError(t, err)
+
+	restored := newRate()
+	restored.Mode = "delta"
+	require.NoError(t, restored.Init())
+	state := reflect.New(reflect.TypeOf(restored.GetState())).Interface()
+	require.NoError(t, json.Unmarshal(buf, &state))
+	require.NoError(t, restored.SetState(reflect.ValueOf(state).Elem().Interface()))
+
+	actual := restored.Apply(input[1])
+	testutil.RequireMetricsEqual(t, expected(nil, uint64(150))[1:], actual)
+}
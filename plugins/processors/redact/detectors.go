@@ -0,0 +1,61 @@
+package redact
+
+import (
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// detector finds sensitive data in a string. Matches of the expression are
+// only considered as sensitive if the optional validation succeeds.
+type detector struct {
+	re       *regexp.Regexp
+	validate func(match string) bool
+}
+
+var builtinDetectors = map[string]detector{
+	"email": {
+		re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+	},
+	"ipv4": {
+		re: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+	},
+	"ipv6": {
+		re:       regexp.MustCompile(`(?i)[0-9a-f]*:[0-9a-f:]*:[0-9a-f]*(?:(?:\.\d{1,3}){3})?(?:%[0-9a-z]+)?`),
+		validate: isIPv6,
+	},
+	"credit_card": {
+		re:       regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+		validate: isCreditCard,
+	},
+	"jwt": {
+		re: regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`),
+	},
+}
+
+func isIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is6()
+}
+
+// isCreditCard checks the length and the Luhn checksum of the card number
+// ignoring separators
+func isCreditCard(s string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	var sum int
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,256 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+const defaultMask = "[REDACTED]"
+
+type Redact struct {
+	HashKey config.Secret   `toml:"hash_key"`
+	Rules   []*rule         `toml:"rule"`
+	Log     telegraf.Logger `toml:"-"`
+
+	key   []byte
+	alias string
+}
+
+type rule struct {
+	Name     string   `toml:"name"`
+	Detector string   `toml:"detector"`
+	Pattern  string   `toml:"pattern"`
+	Action   string   `toml:"action"`
+	Mask     string   `toml:"mask"`
+	Tags     []string `toml:"tags"`
+	Fields   []string `toml:"fields"`
+
+	detector    detector
+	tagFilter   filter.Filter
+	fieldFilter filter.Filter
+	redactions  selfstat.Stat
+}
+
+func (*Redact) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Redact) SetPluginAlias(alias string) {
+	r.alias = alias
+}
+
+func (r *Redact) Init() error {
+	if len(r.Rules) == 0 {
+		return errors.New("no rules specified")
+	}
+
+	names := make(map[string]bool, len(r.Rules))
+	var needsKey bool
+	for i, rl := range r.Rules {
+		if err := rl.init(r.alias); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if names[rl.Name] {
+			return fmt.Errorf("duplicate rule name %q", rl.Name)
+		}
+		names[rl.Name] = true
+		needsKey = needsKey || rl.Action == "hash"
+	}
+
+	if needsKey {
+		if r.HashKey.Empty() {
+			return errors.New("'hash_key' is required for the hash action")
+		}
+		key, err := r.HashKey.Get()
+		if err != nil {
+			return fmt.Errorf("getting hash key failed: %w", err)
+		}
+		r.key = []byte(key.String())
+		key.Destroy()
+		if len(r.key) == 0 {
+			return errors.New("'hash_key' must not be empty")
+		}
+	}
+
+	return nil
+}
+
+func (r *Redact) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, m := range in {
+		for _, rl := range r.Rules {
+			r.applyRule(rl, m)
+		}
+
+		// Drop metrics where all fields were removed
+		if len(m.FieldList()) == 0 {
+			m.Drop()
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func (r *Redact) applyRule(rl *rule, m telegraf.Metric) {
+	if rl.tagFilter != nil {
+		// Collect the modifications first as the tag-list must not be
+		// modified while iterating
+		modified := make(map[string]string)
+		var dropped []string
+		for _, tag := range m.TagList() {
+			if !rl.tagFilter.Match(tag.Key) {
+				continue
+			}
+			value, drop, changed := r.redact(rl, tag.Value)
+			switch {
+			case drop:
+				dropped = append(dropped, tag.Key)
+			case changed:
+				modified[tag.Key] = value
+			}
+		}
+		for _, key := range dropped {
+			m.RemoveTag(key)
+		}
+		for key, value := range modified {
+			m.AddTag(key, value)
+		}
+	}
+
+	if rl.fieldFilter != nil {
+		modified := make(map[string]string)
+		var dropped []string
+		for _, field := range m.FieldList() {
+			s, ok := field.Value.(string)
+			if !ok || !rl.fieldFilter.Match(field.Key) {
+				continue
+			}
+			value, drop, changed := r.redact(rl, s)
+			switch {
+			case drop:
+				dropped = append(dropped, field.Key)
+			case changed:
+				modified[field.Key] = value
+			}
+		}
+		for _, key := range dropped {
+			m.RemoveField(key)
+		}
+		for key, value := range modified {
+			m.AddField(key, value)
+		}
+	}
+}
+
+// redact replaces the sensitive data found in the value according to the
+// rule's action and returns whether the value should be dropped or was
+// changed
+func (r *Redact) redact(rl *rule, value string) (result string, drop, changed bool) {
+	var count int64
+	result = rl.detector.re.ReplaceAllStringFunc(value, func(match string) string {
+		if rl.detector.validate != nil && !rl.detector.validate(match) {
+			return match
+		}
+		count++
+		switch rl.Action {
+		case "hash":
+			mac := hmac.New(sha256.New, r.key)
+			mac.Write([]byte(match))
+			return hex.EncodeToString(mac.Sum(nil))
+		case "mask":
+			return rl.Mask
+		}
+		return match
+	})
+	if count == 0 {
+		return value, false, false
+	}
+
+	if rl.Action == "drop" {
+		rl.redactions.Incr(1)
+		return "", true, false
+	}
+	rl.redactions.Incr(count)
+	return result, false, true
+}
+
+func (rl *rule) init(alias string) error {
+	switch rl.Detector {
+	case "regex":
+		if rl.Pattern == "" {
+			return errors.New("'pattern' is required for the regex detector")
+		}
+		re, err := regexp.Compile(rl.Pattern)
+		if err != nil {
+			return fmt.Errorf("compiling pattern failed: %w", err)
+		}
+		rl.detector = detector{re: re}
+	case "":
+		return errors.New("missing 'detector'")
+	default:
+		d, found := builtinDetectors[rl.Detector]
+		if !found {
+			return fmt.Errorf("invalid detector %q", rl.Detector)
+		}
+		rl.detector = d
+	}
+
+	switch rl.Action {
+	case "":
+		rl.Action = "mask"
+	case "mask", "drop", "hash":
+	default:
+		return fmt.Errorf("invalid action %q", rl.Action)
+	}
+	if rl.Mask == "" {
+		rl.Mask = defaultMask
+	}
+
+	if rl.Name == "" {
+		rl.Name = rl.Detector
+	}
+
+	// Check all tags and string fields if nothing is specified
+	tags, fields := rl.Tags, rl.Fields
+	if len(tags) == 0 && len(fields) == 0 {
+		tags = []string{"*"}
+		fields = []string{"*"}
+	}
+	var err error
+	if rl.tagFilter, err = filter.Compile(tags); err != nil {
+		return fmt.Errorf("creating tag filter failed: %w", err)
+	}
+	if rl.fieldFilter, err = filter.Compile(fields); err != nil {
+		return fmt.Errorf("creating field filter failed: %w", err)
+	}
+
+	statTags := map[string]string{"rule": rl.Name}
+	if alias != "" {
+		statTags["alias"] = alias
+	}
+	rl.redactions = selfstat.Register("redact", "redactions", statTags)
+
+	return nil
+}
+
+func init() {
+	processors.Add("redact", func() telegraf.Processor {
+		return &Redact{}
+	})
+}
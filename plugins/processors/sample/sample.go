@@ -0,0 +1,207 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package sample
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Sample struct {
+	Mode            string          `toml:"mode"`
+	Rate            float64         `toml:"rate"`
+	Tags            []string        `toml:"tags"`
+	Limit           float64         `toml:"limit"`
+	Burst           int             `toml:"burst"`
+	SampleRateField string          `toml:"sample_rate_field"`
+	BucketTTL       config.Duration `toml:"bucket_ttl"`
+	Log             telegraf.Logger `toml:"-"`
+
+	buckets     map[uint64]*bucket
+	lastCleanup time.Time
+	now         func() time.Time
+	random      func() float64
+
+	alias   string
+	kept    selfstat.Stat
+	dropped selfstat.Stat
+}
+
+// bucket is the token bucket of a tag set. The seen and kept counters decay
+// exponentially to estimate the current fraction of kept metrics.
+type bucket struct {
+	tokens  float64
+	seen    float64
+	kept    float64
+	updated time.Time
+}
+
+func (*Sample) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Sample) SetPluginAlias(alias string) {
+	s.alias = alias
+}
+
+func (s *Sample) Init() error {
+	switch s.Mode {
+	case "hash", "random":
+		if s.Rate <= 0 || s.Rate > 1 {
+			return errors.New("'rate' must be greater than zero and at most one")
+		}
+	case "rate_limit":
+		if s.Limit <= 0 {
+			return errors.New("'limit' must be greater than zero")
+		}
+		if s.Burst < 1 {
+			return errors.New("'burst' must be at least one")
+		}
+	default:
+		return fmt.Errorf("invalid mode %q", s.Mode)
+	}
+
+	s.buckets = make(map[uint64]*bucket)
+	if s.now == nil {
+		s.now = time.Now
+	}
+	if s.random == nil {
+		s.random = rand.Float64
+	}
+
+	tags := map[string]string{"mode": s.Mode}
+	if s.alias != "" {
+		tags["alias"] = s.alias
+	}
+	s.kept = selfstat.Register("sample", "metrics_kept", tags)
+	s.dropped = selfstat.Register("sample", "metrics_dropped", tags)
+
+	return nil
+}
+
+func (s *Sample) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := s.now()
+
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		var keep bool
+		rate := s.Rate
+		switch s.Mode {
+		case "hash":
+			// Map the hash to [0,1) to keep a consistent subset of the series
+			keep = float64(s.hash(m))/math.MaxUint64 < s.Rate
+		case "random":
+			keep = s.random() < s.Rate
+		case "rate_limit":
+			keep, rate = s.limit(m, now)
+		}
+
+		if !keep {
+			s.dropped.Incr(1)
+			m.Drop()
+			continue
+		}
+		s.kept.Incr(1)
+		if s.SampleRateField != "" {
+			m.AddField(s.SampleRateField, rate)
+		}
+		out = append(out, m)
+	}
+
+	s.cleanup(now)
+
+	return out
+}
+
+// limit applies the token bucket of the metric's tag set and returns whether
+// to keep the metric and the estimated fraction of kept metrics
+func (s *Sample) limit(m telegraf.Metric, now time.Time) (bool, float64) {
+	id := s.hash(m)
+	b, found := s.buckets[id]
+	if !found {
+		b = &bucket{tokens: float64(s.Burst), updated: now}
+		s.buckets[id] = b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(s.Burst), b.tokens+elapsed*s.Limit)
+		decay := math.Exp(-elapsed)
+		b.seen *= decay
+		b.kept *= decay
+		b.updated = now
+	}
+
+	b.seen++
+	if b.tokens < 1 {
+		return false, 0
+	}
+	b.tokens--
+	b.kept++
+
+	return true, b.kept / b.seen
+}
+
+// hash returns the hash of the metric's name and the configured tags or of
+// the whole series if no tags are configured
+func (s *Sample) hash(m telegraf.Metric) uint64 {
+	if len(s.Tags) == 0 {
+		return m.HashID()
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(m.Name()))
+	h.Write([]byte("\n"))
+	for _, key := range s.Tags {
+		v, _ := m.GetTag(key)
+		h.Write([]byte(key))
+		h.Write([]byte("\n"))
+		h.Write([]byte(v))
+		h.Write([]byte("\n"))
+	}
+	return h.Sum64()
+}
+
+// Remove the buckets not updated within the TTL
+func (s *Sample) cleanup(now time.Time) {
+	ttl := time.Duration(s.BucketTTL)
+	if ttl <= 0 || len(s.buckets) == 0 {
+		return
+	}
+	// No need to cleanup too often, use a tenth of the TTL
+	if now.Sub(s.lastCleanup) < ttl/10 {
+		return
+	}
+	s.lastCleanup = now
+
+	threshold := now.Add(-ttl)
+	for id, b := range s.buckets {
+		if b.updated.Before(threshold) {
+			delete(s.buckets, id)
+		}
+	}
+}
+
+func init() {
+	processors.Add("sample", func() telegraf.Processor {
+		return &Sample{
+			Mode:            "hash",
+			Rate:            0.1,
+			Burst:           1,
+			SampleRateField: "sample_rate",
+			BucketTTL:       config.Duration(10 * time.Minute),
+		}
+	})
+}
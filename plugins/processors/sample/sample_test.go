@@ -0,0 +1,235 @@
+package sample
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Sample
+		expected string
+	}{
+		{
+			name:     "invalid mode",
+			plugin:   &Sample{Mode: "foo"},
+			expected: `invalid mode "foo"`,
+		},
+		{
+			name:     "rate too high",
+			plugin:   &Sample{Mode: "hash", Rate: 1.5},
+			expected: "'rate' must be greater than zero and at most one",
+		},
+		{
+			name:     "zero rate",
+			plugin:   &Sample{Mode: "random"},
+			expected: "'rate' must be greater than zero and at most one",
+		},
+		{
+			name:     "missing limit",
+			plugin:   &Sample{Mode: "rate_limit", Burst: 1},
+			expected: "'limit' must be greater than zero",
+		},
+		{
+			name:     "invalid burst",
+			plugin:   &Sample{Mode: "rate_limit", Limit: 1},
+			expected: "'burst' must be at least one",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestHashConsistent(t *testing.T) {
+	plugin := &Sample{
+		Mode:            "hash",
+		Rate:            0.5,
+		Tags:            []string{"host"},
+		SampleRateField: "sample_rate",
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	// Each host must either be kept or dropped for all its metrics
+	now := time.Now()
+	kept := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		for h := 0; h < 100; h++ {
+			host := "host" + string(rune('a'+h%26)) + string(rune('a'+h/26))
+			m := metric.New(
+				"cpu",
+				map[string]string{"host": host, "cpu": "cpu" + string(rune('0'+i))},
+				map[string]interface{}{"value": 42},
+				now,
+			)
+			for _, out := range plugin.Apply(m) {
+				require.Equal(t, 0.5, out.Fields()["sample_rate"])
+				kept[host]++
+			}
+		}
+	}
+	for host, n := range kept {
+		require.Equalf(t, 10, n, "host %q kept inconsistently", host)
+	}
+	require.NotEmpty(t, kept)
+	require.Less(t, len(kept), 100)
+}
+
+func TestRandom(t *testing.T) {
+	values := []float64{0.1, 0.6, 0.2, 0.9}
+	plugin := &Sample{
+		Mode:            "random",
+		Rate:            0.5,
+		SampleRateField: "weight",
+		Log:             testutil.Logger{},
+		random: func() float64 {
+			v := values[0]
+			values = values[1:]
+			return v
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Unix(0, 0)
+	input := make([]telegraf.Metric, 0, 4)
+	for i := int64(0); i < 4; i++ {
+		input = append(input, metric.New("test", map[string]string{}, map[string]interface{}{"value": i}, now))
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": int64(0), "weight": 0.5}, now),
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": int64(2), "weight": 0.5}, now),
+	}
+	testutil.RequireMetricsEqual(t, expected, plugin.Apply(input...))
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	plugin := &Sample{
+		Mode:            "rate_limit",
+		Limit:           1,
+		Burst:           2,
+		Tags:            []string{"host"},
+		SampleRateField: "sample_rate",
+		Log:             testutil.Logger{},
+		now:             func() time.Time { return now },
+	}
+	require.NoError(t, plugin.Init())
+
+	input := make([]telegraf.Metric, 0, 5)
+	for i := int64(0); i < 4; i++ {
+		input = append(input, metric.New("test", map[string]string{"host": "a"}, map[string]interface{}{"value": i}, now))
+	}
+	input = append(input, metric.New("test", map[string]string{"host": "b"}, map[string]interface{}{"value": int64(4)}, now))
+
+	// The burst is used up for host "a" while host "b" has its own bucket
+	actual := plugin.Apply(input...)
+	require.Len(t, actual, 3)
+	require.Equal(t, int64(0), actual[0].Fields()["value"])
+	require.Equal(t, int64(1), actual[1].Fields()["value"])
+	require.Equal(t, int64(4), actual[2].Fields()["value"])
+	require.InDelta(t, 1.0, actual[2].Fields()["sample_rate"], 1e-9)
+
+	// Tokens refill over time
+	now = now.Add(time.Second)
+	actual = plugin.Apply(
+		metric.New("test", map[string]string{"host": "a"}, map[string]interface{}{"value": int64(5)}, now),
+		metric.New("test", map[string]string{"host": "a"}, map[string]interface{}{"value": int64(6)}, now),
+	)
+	require.Len(t, actual, 1)
+	rate, ok := actual[0].Fields()["sample_rate"].(float64)
+	require.True(t, ok)
+	require.Greater(t, rate, 0.0)
+	require.Less(t, rate, 1.0)
+}
+
+func TestBucketCleanup(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	plugin := &Sample{
+		Mode:      "rate_limit",
+		Limit:     1,
+		Burst:     1,
+		BucketTTL: config.Duration(time.Minute),
+		Log:       testutil.Logger{},
+		now:       func() time.Time { return now },
+	}
+	require.NoError(t, plugin.Init())
+
+	plugin.Apply(metric.New("test", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, now))
+	require.Len(t, plugin.buckets, 1)
+
+	now = now.Add(2 * time.Minute)
+	plugin.Apply(metric.New("test", map[string]string{"host": "b"}, map[string]interface{}{"value": 1}, now))
+	require.Len(t, plugin.buckets, 1)
+}
+
+func TestTracking(t *testing.T) {
+	var delivered []telegraf.DeliveryInfo
+	notify := func(di telegraf.DeliveryInfo) {
+		delivered = append(delivered, di)
+	}
+
+	now := time.Unix(1700000000, 0)
+	plugin := &Sample{
+		Mode:  "rate_limit",
+		Limit: 1,
+		Burst: 1,
+		Log:   testutil.Logger{},
+		now:   func() time.Time { return now },
+	}
+	require.NoError(t, plugin.Init())
+
+	input := make([]telegraf.Metric, 0, 3)
+	for i := int64(0); i < 3; i++ {
+		m := metric.New("test", map[string]string{}, map[string]interface{}{"value": i}, now)
+		tm, _ := metric.WithTracking(m, notify)
+		input = append(input, tm)
+	}
+
+	actual := plugin.Apply(input...)
+	require.Len(t, actual, 1)
+	for _, m := range actual {
+		m.Accept()
+	}
+	require.Len(t, delivered, 3)
+}
+
+func TestStatisticsAlias(t *testing.T) {
+	plugin := &Sample{
+		Mode: "random",
+		Rate: 0.5,
+		Log:  testutil.Logger{},
+		random: func() float64 {
+			return 0.9
+		},
+	}
+	plugin.SetPluginAlias("custom")
+	require.NoError(t, plugin.Init())
+
+	plugin.Apply(metric.New("test", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)))
+
+	var found bool
+	for _, m := range selfstat.Metrics() {
+		if m.Name() != "internal_sample" || m.Tags()["alias"] != "custom" {
+			continue
+		}
+		found = true
+		require.Equal(t, "random", m.Tags()["mode"])
+		require.Equal(t, int64(1), m.Fields()["metrics_dropped"])
+	}
+	require.True(t, found)
+}
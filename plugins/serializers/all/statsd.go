@@ -0,0 +1,7 @@
+//go:build !custom || serializers || serializers.statsd
+
+package all
+
+import (
+	_ "github.com/influxdata/telegraf/plugins/serializers/statsd" // register plugin
+)
@@ -0,0 +1,270 @@
+package arrow
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// Metadata keys used to annotate the Arrow schema so the parser can restore
+// the metric structure without additional configuration.
+const (
+	MetadataMeasurement = "telegraf.measurement"
+	MetadataRole        = "telegraf.role"
+
+	RoleTag       = "tag"
+	RoleField     = "field"
+	RoleTimestamp = "timestamp"
+)
+
+type Serializer struct {
+	TimestampColumn string          `toml:"arrow_timestamp_column"`
+	Compression     string          `toml:"arrow_compression"`
+	Log             telegraf.Logger `toml:"-"`
+
+	mem     memory.Allocator
+	options []ipc.Option
+}
+
+func (s *Serializer) Init() error {
+	if s.TimestampColumn == "" {
+		s.TimestampColumn = "time"
+	}
+
+	s.mem = memory.NewGoAllocator()
+	s.options = []ipc.Option{ipc.WithAllocator(s.mem)}
+
+	switch s.Compression {
+	case "", "none":
+	case "lz4":
+		s.options = append(s.options, ipc.WithLZ4())
+	case "zstd":
+		s.options = append(s.options, ipc.WithZstd())
+	default:
+		return fmt.Errorf("invalid compression %q", s.Compression)
+	}
+
+	return nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+// SerializeBatch converts the given metrics to Arrow IPC streams. Metrics are
+// grouped by measurement and each group is written as a separate stream with
+// its own schema. The streams are concatenated in the order of first
+// occurrence of the measurement.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+
+	order := make([]string, 0)
+	groups := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		name := m.Name()
+		if _, found := groups[name]; !found {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], m)
+	}
+
+	var buf bytes.Buffer
+	for _, name := range order {
+		if err := s.writeStream(&buf, name, groups[name]); err != nil {
+			return nil, fmt.Errorf("serializing measurement %q failed: %w", name, err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *Serializer) writeStream(buf *bytes.Buffer, name string, metrics []telegraf.Metric) error {
+	schema, err := s.inferSchema(name, metrics)
+	if err != nil {
+		return err
+	}
+
+	builder := array.NewRecordBuilder(s.mem, schema)
+	defer builder.Release()
+
+	for idx, col := range schema.Fields() {
+		role, _ := col.Metadata.GetValue(MetadataRole)
+		for _, m := range metrics {
+			switch role {
+			case RoleTimestamp:
+				b := builder.Field(idx).(*array.TimestampBuilder)
+				b.Append(arrow.Timestamp(m.Time().UnixNano()))
+			case RoleTag:
+				b := builder.Field(idx).(*array.StringBuilder)
+				if v, ok := m.GetTag(col.Name); ok {
+					b.Append(v)
+				} else {
+					b.AppendNull()
+				}
+			default:
+				v, ok := m.GetField(col.Name)
+				if !ok {
+					builder.Field(idx).AppendNull()
+					continue
+				}
+				if err := appendValue(builder.Field(idx), v); err != nil {
+					s.Log.Debugf("Cannot convert field %q of %q: %v", col.Name, name, err)
+					builder.Field(idx).AppendNull()
+				}
+			}
+		}
+	}
+
+	record := builder.NewRecord()
+	defer record.Release()
+
+	options := append([]ipc.Option{ipc.WithSchema(schema)}, s.options...)
+	writer := ipc.NewWriter(buf, options...)
+	if err := writer.Write(record); err != nil {
+		return fmt.Errorf("writing record failed: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("closing stream failed: %w", err)
+	}
+
+	return nil
+}
+
+// inferSchema determines the Arrow schema of the given metrics. Tags are
+// always string columns, fields take the type of their first occurrence.
+func (s *Serializer) inferSchema(name string, metrics []telegraf.Metric) (*arrow.Schema, error) {
+	tags := make(map[string]bool)
+	fields := make(map[string]arrow.DataType)
+	for _, m := range metrics {
+		for _, tag := range m.TagList() {
+			tags[tag.Key] = true
+		}
+		for _, field := range m.FieldList() {
+			if _, found := fields[field.Key]; found {
+				continue
+			}
+			dt, err := arrowType(field.Value)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", field.Key, err)
+			}
+			fields[field.Key] = dt
+		}
+	}
+
+	tagNames := make([]string, 0, len(tags))
+	for k := range tags {
+		if k == s.TimestampColumn {
+			return nil, fmt.Errorf("tag %q collides with timestamp column", k)
+		}
+		tagNames = append(tagNames, k)
+	}
+	sort.Strings(tagNames)
+
+	fieldNames := make([]string, 0, len(fields))
+	for k := range fields {
+		if k == s.TimestampColumn || tags[k] {
+			return nil, fmt.Errorf("field %q collides with timestamp or tag column", k)
+		}
+		fieldNames = append(fieldNames, k)
+	}
+	sort.Strings(fieldNames)
+
+	columns := make([]arrow.Field, 0, 1+len(tagNames)+len(fieldNames))
+	columns = append(columns, arrow.Field{
+		Name:     s.TimestampColumn,
+		Type:     &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"},
+		Metadata: arrow.NewMetadata([]string{MetadataRole}, []string{RoleTimestamp}),
+	})
+	for _, k := range tagNames {
+		columns = append(columns, arrow.Field{
+			Name:     k,
+			Type:     arrow.BinaryTypes.String,
+			Nullable: true,
+			Metadata: arrow.NewMetadata([]string{MetadataRole}, []string{RoleTag}),
+		})
+	}
+	for _, k := range fieldNames {
+		columns = append(columns, arrow.Field{
+			Name:     k,
+			Type:     fields[k],
+			Nullable: true,
+			Metadata: arrow.NewMetadata([]string{MetadataRole}, []string{RoleField}),
+		})
+	}
+
+	metadata := arrow.NewMetadata([]string{MetadataMeasurement}, []string{name})
+	return arrow.NewSchema(columns, &metadata), nil
+}
+
+func arrowType(value interface{}) (arrow.DataType, error) {
+	switch value.(type) {
+	case int64:
+		return arrow.PrimitiveTypes.Int64, nil
+	case uint64:
+		return arrow.PrimitiveTypes.Uint64, nil
+	case float64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case string:
+		return arrow.BinaryTypes.String, nil
+	case bool:
+		return arrow.FixedWidthTypes.Boolean, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", value)
+}
+
+func appendValue(builder array.Builder, value interface{}) error {
+	switch b := builder.(type) {
+	case *array.Int64Builder:
+		v, err := internal.ToInt64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint64Builder:
+		v, err := internal.ToUint64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float64Builder:
+		v, err := internal.ToFloat64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.StringBuilder:
+		v, err := internal.ToString(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.BooleanBuilder:
+		v, err := internal.ToBool(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	default:
+		return fmt.Errorf("unsupported builder %T", builder)
+	}
+	return nil
+}
+
+func init() {
+	serializers.Add("arrow",
+		func() serializers.Serializer {
+			return &Serializer{}
+		},
+	)
+}
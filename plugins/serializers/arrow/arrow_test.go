@@ -0,0 +1,159 @@
+package arrow
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInvalidCompression(t *testing.T) {
+	s := &Serializer{Compression: "garbage"}
+	require.EqualError(t, s.Init(), `invalid compression "garbage"`)
+}
+
+func TestSerializeBatch(t *testing.T) {
+	now := time.Unix(1700000000, 123)
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5, "count": int64(3)},
+			now,
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 23.0, "ok": true},
+			now.Add(time.Second),
+		),
+	}
+
+	s := &Serializer{Log: testutil.Logger{}}
+	require.NoError(t, s.Init())
+
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	reader, err := ipc.NewReader(bytes.NewReader(buf))
+	require.NoError(t, err)
+	defer reader.Release()
+
+	schema := reader.Schema()
+	name, found := schema.Metadata().GetValue(MetadataMeasurement)
+	require.True(t, found)
+	require.Equal(t, "cpu", name)
+
+	columns := make([]string, 0, schema.NumFields())
+	for _, f := range schema.Fields() {
+		columns = append(columns, f.Name)
+	}
+	require.Equal(t, []string{"time", "cpu", "host", "count", "ok", "usage"}, columns)
+
+	require.True(t, reader.Next())
+	record := reader.Record()
+	require.EqualValues(t, 2, record.NumRows())
+
+	ts := record.Column(0).(*array.Timestamp)
+	require.Equal(t, arrow.Timestamp(now.UnixNano()), ts.Value(0))
+
+	cpu := record.Column(1).(*array.String)
+	require.True(t, cpu.IsNull(0))
+	require.Equal(t, "cpu0", cpu.Value(1))
+
+	usage := record.Column(5).(*array.Float64)
+	require.InDelta(t, 42.5, usage.Value(0), 1e-9)
+	require.InDelta(t, 23.0, usage.Value(1), 1e-9)
+
+	require.False(t, reader.Next())
+	require.NoError(t, reader.Err())
+}
+
+func TestSerializeMultipleMeasurements(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New("mem", nil, map[string]interface{}{"used": uint64(1)}, time.Unix(0, 0)),
+		metric.New("cpu", nil, map[string]interface{}{"usage": 1.0}, time.Unix(0, 0)),
+		metric.New("mem", nil, map[string]interface{}{"used": uint64(2)}, time.Unix(1, 0)),
+	}
+
+	for _, compression := range []string{"", "lz4", "zstd"} {
+		t.Run("compression "+compression, func(t *testing.T) {
+			s := &Serializer{Compression: compression, Log: testutil.Logger{}}
+			require.NoError(t, s.Init())
+
+			buf, err := s.SerializeBatch(metrics)
+			require.NoError(t, err)
+
+			r := bytes.NewReader(buf)
+			var names []string
+			var rows []int64
+			for r.Len() > 0 {
+				reader, err := ipc.NewReader(r)
+				require.NoError(t, err)
+				name, _ := reader.Schema().Metadata().GetValue(MetadataMeasurement)
+				names = append(names, name)
+				for reader.Next() {
+					rows = append(rows, reader.Record().NumRows())
+				}
+				require.NoError(t, reader.Err())
+				reader.Release()
+			}
+			require.Equal(t, []string{"mem", "cpu"}, names)
+			require.Equal(t, []int64{2, 1}, rows)
+		})
+	}
+}
+
+func TestSerializeTypeConflict(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New("test", nil, map[string]interface{}{"value": int64(1)}, time.Unix(0, 0)),
+		metric.New("test", nil, map[string]interface{}{"value": "2"}, time.Unix(1, 0)),
+		metric.New("test", nil, map[string]interface{}{"value": "invalid"}, time.Unix(2, 0)),
+	}
+
+	s := &Serializer{Log: testutil.Logger{}}
+	require.NoError(t, s.Init())
+
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	reader, err := ipc.NewReader(bytes.NewReader(buf))
+	require.NoError(t, err)
+	defer reader.Release()
+
+	require.True(t, reader.Next())
+	values := reader.Record().Column(1).(*array.Int64)
+	require.Equal(t, int64(1), values.Value(0))
+	require.Equal(t, int64(2), values.Value(1))
+	require.True(t, values.IsNull(2))
+}
+
+func BenchmarkSerialize(b *testing.B) {
+	s := &Serializer{Log: testutil.Logger{}}
+	require.NoError(b, s.Init())
+	metrics := serializers.BenchmarkMetrics(b)
+	for n := 0; n < b.N; n++ {
+		_, err := s.Serialize(metrics[n%len(metrics)])
+		require.NoError(b, err)
+	}
+}
+
+func BenchmarkSerializeBatch(b *testing.B) {
+	s := &Serializer{Log: testutil.Logger{}}
+	require.NoError(b, s.Init())
+	m := serializers.BenchmarkMetrics(b)
+	metrics := m[:]
+	for n := 0; n < b.N; n++ {
+		_, err := s.SerializeBatch(metrics)
+		require.NoError(b, err)
+	}
+}
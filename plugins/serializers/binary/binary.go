@@ -2,16 +2,19 @@ package binary
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	common_binary "github.com/influxdata/telegraf/plugins/common/binary"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
 
 type Serializer struct {
-	Entries    []*Entry `toml:"entries"`
-	Endianness string   `toml:"endianness"`
+	Entries    []*Entry              `toml:"entries"`
+	Layout     *common_binary.Layout `toml:"layout"`
+	Endianness string                `toml:"endianness"`
 
 	converter binary.ByteOrder
 }
@@ -29,6 +32,15 @@ func (s *Serializer) Init() error {
 		return fmt.Errorf("invalid endianness %q", s.Endianness)
 	}
 
+	if s.Layout != nil {
+		if len(s.Entries) > 0 {
+			return errors.New("entries and layout cannot be used together")
+		}
+		if err := s.Layout.Init(); err != nil {
+			return fmt.Errorf("layout invalid: %w", err)
+		}
+	}
+
 	for i, entry := range s.Entries {
 		if err := entry.fillDefaults(); err != nil {
 			return fmt.Errorf("entry %d check failed: %w", i, err)
@@ -39,6 +51,10 @@ func (s *Serializer) Init() error {
 }
 
 func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	if s.Layout != nil {
+		return s.Layout.Encode(metric, s.converter)
+	}
+
 	serialized := make([]byte, 0)
 
 	for _, entry := range s.Entries {
@@ -2,11 +2,17 @@ package binary
 
 import (
 	"encoding/hex"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/influxdata/telegraf/metric"
 	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	common_binary "github.com/influxdata/telegraf/plugins/common/binary"
+	parsers_binary "github.com/influxdata/telegraf/plugins/parsers/binary"
+	"github.com/influxdata/telegraf/testutil"
 )
 
 func TestMetricSerialization(t *testing.T) {
@@ -118,3 +124,65 @@ func TestMetricSerialization(t *testing.T) {
 		})
 	}
 }
+
+func TestLayoutRoundTrip(t *testing.T) {
+	layout := func() *common_binary.Layout {
+		return &common_binary.Layout{
+			Entries: []common_binary.Entry{
+				{Name: "kind", Type: "uint8", Assignment: "tag"},
+				{Name: "alarm", Type: "bool"},
+				{Name: "level", Type: "uint8", Bits: 3},
+				{Bits: 4, Omit: true},
+				{Name: "location", Type: "string", LengthPrefix: "uint8", Assignment: "tag"},
+			},
+			Sections: []common_binary.Section{
+				{
+					Discriminator: "kind",
+					Values:        []string{"1"},
+					Entries:       []common_binary.Entry{{Name: "temperature", Type: "int16"}},
+				},
+				{
+					Discriminator: "kind",
+					Values:        []string{"2"},
+					Entries:       []common_binary.Entry{{Name: "sample", Type: "uint8", LengthPrefix: "uint8"}},
+				},
+			},
+		}
+	}
+
+	input := []telegraf.Metric{
+		metric.New(
+			"sensor",
+			map[string]string{"kind": "1", "location": "lab"},
+			map[string]interface{}{"alarm": true, "level": uint8(5), "temperature": int16(-200)},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"sensor",
+			map[string]string{"kind": "2", "location": "hall"},
+			map[string]interface{}{"alarm": false, "level": uint8(3), "sample_0": uint8(10), "sample_1": uint8(11)},
+			time.Unix(0, 0),
+		),
+	}
+	expected := []string{"01d0036c6162ff38", "0230046861 6c6c020a0b"}
+
+	serializer := &Serializer{Layout: layout(), Endianness: "big"}
+	require.NoError(t, serializer.Init())
+
+	parser := &parsers_binary.Parser{
+		Endianness: "be",
+		Configs:    []parsers_binary.Config{{MetricName: "sensor", Layout: layout()}},
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+
+	for i, m := range input {
+		buf, err := serializer.Serialize(m)
+		require.NoError(t, err)
+		require.Equal(t, strings.ReplaceAll(expected[i], " ", ""), hex.EncodeToString(buf))
+
+		actual, err := parser.Parse(buf)
+		require.NoError(t, err)
+		testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, actual, testutil.IgnoreTime())
+	}
+}
@@ -0,0 +1,202 @@
+package statsd
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// Mapping of the `metric_type` tag values set by the statsd parser and input
+// to the statsd type identifiers
+var metricTypes = map[string]string{
+	"counter":      "c",
+	"gauge":        "g",
+	"gauge_delta":  "g",
+	"set":          "s",
+	"timing":       "ms",
+	"histogram":    "h",
+	"distribution": "d",
+}
+
+type Serializer struct {
+	DataDogExtensions bool            `toml:"statsd_datadog_extensions"`
+	MetricSeparator   string          `toml:"statsd_metric_separator"`
+	DefaultType       string          `toml:"statsd_default_type"`
+	HistogramType     string          `toml:"statsd_histogram_type"`
+	Log               telegraf.Logger `toml:"-"`
+
+	tagSanitizer    *strings.Replacer
+	bucketSanitizer *strings.Replacer
+}
+
+func (s *Serializer) Init() error {
+	if s.MetricSeparator == "" {
+		s.MetricSeparator = "."
+	}
+
+	switch s.DefaultType {
+	case "":
+		s.DefaultType = "g"
+	case "c", "g", "ms", "h", "d":
+	default:
+		return fmt.Errorf("invalid default type %q", s.DefaultType)
+	}
+
+	switch s.HistogramType {
+	case "":
+		s.HistogramType = "ms"
+	case "ms", "h", "d":
+	default:
+		return fmt.Errorf("invalid histogram type %q", s.HistogramType)
+	}
+
+	if s.DataDogExtensions {
+		s.tagSanitizer = strings.NewReplacer("|", "_", ",", "_", "\n", "_")
+		s.bucketSanitizer = strings.NewReplacer("|", "_", ":", "_", "\n", "_")
+	} else {
+		s.tagSanitizer = strings.NewReplacer("|", "_", ",", "_", ":", "_", "=", "_", "\n", "_")
+		s.bucketSanitizer = strings.NewReplacer("|", "_", ",", "_", ":", "_", "\n", "_")
+	}
+
+	return nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	var buf bytes.Buffer
+	s.write(&buf, metric)
+	return buf.Bytes(), nil
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		s.write(&buf, m)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Serializer) write(buf *bytes.Buffer, m telegraf.Metric) {
+	mtype := s.metricType(m)
+	tag, _ := m.GetTag("metric_type")
+	delta := tag == "gauge_delta"
+
+	// Collect the tags excluding the metric type
+	tags := make([]string, 0, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		if tag.Key == "metric_type" {
+			continue
+		}
+		key := s.tagSanitizer.Replace(tag.Key)
+		value := s.tagSanitizer.Replace(tag.Value)
+		if s.DataDogExtensions {
+			tags = append(tags, key+":"+value)
+		} else {
+			tags = append(tags, key+"="+value)
+		}
+	}
+
+	for _, field := range m.FieldList() {
+		value, ok := s.formatValue(field.Value, mtype)
+		if !ok {
+			s.Log.Debugf("Skipping field %q of %q with unsupported value %v (%T) for type %q",
+				field.Key, m.Name(), field.Value, field.Value, mtype)
+			continue
+		}
+
+		bucket := m.Name()
+		if field.Key != "value" {
+			bucket += s.MetricSeparator + field.Key
+		}
+		bucket = s.bucketSanitizer.Replace(bucket)
+
+		// A signed gauge value modifies the current value, so send changes
+		// of the gauge parsed from statsd with a sign and reset the gauge
+		// before sending negative absolute values
+		if mtype == "g" {
+			negative := strings.HasPrefix(value, "-")
+			if delta && !negative {
+				value = "+" + value
+			} else if !delta && negative {
+				s.writeLine(buf, bucket, "0", mtype, tags)
+			}
+		}
+		s.writeLine(buf, bucket, value, mtype, tags)
+	}
+}
+
+func (s *Serializer) writeLine(buf *bytes.Buffer, bucket, value, mtype string, tags []string) {
+	buf.WriteString(bucket)
+	if !s.DataDogExtensions && len(tags) > 0 {
+		buf.WriteString(",")
+		buf.WriteString(strings.Join(tags, ","))
+	}
+	buf.WriteString(":")
+	buf.WriteString(value)
+	buf.WriteString("|")
+	buf.WriteString(mtype)
+	if s.DataDogExtensions && len(tags) > 0 {
+		buf.WriteString("|#")
+		buf.WriteString(strings.Join(tags, ","))
+	}
+	buf.WriteString("\n")
+}
+
+// metricType determines the statsd type of the metric, preferring the type
+// annotated by the statsd parser over the metric's value type.
+func (s *Serializer) metricType(m telegraf.Metric) string {
+	if v, found := m.GetTag("metric_type"); found {
+		if t, found := metricTypes[v]; found {
+			return t
+		}
+	}
+
+	switch m.Type() {
+	case telegraf.Counter:
+		return "c"
+	case telegraf.Gauge:
+		return "g"
+	case telegraf.Histogram, telegraf.Summary:
+		return s.HistogramType
+	}
+	return s.DefaultType
+}
+
+func (*Serializer) formatValue(v interface{}, mtype string) (string, bool) {
+	if mtype == "s" {
+		return fmt.Sprint(v), true
+	}
+
+	switch value := v.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10), true
+	case uint64:
+		return strconv.FormatUint(value, 10), true
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return "", false
+		}
+		if mtype == "c" {
+			return strconv.FormatInt(int64(value), 10), true
+		}
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		if value {
+			return "1", true
+		}
+		return "0", true
+	}
+	return "", false
+}
+
+func init() {
+	serializers.Add("statsd",
+		func() serializers.Serializer {
+			return &Serializer{}
+		},
+	)
+}
@@ -0,0 +1,154 @@
+package statsd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	parsers_statsd "github.com/influxdata/telegraf/plugins/parsers/statsd"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInvalidTypes(t *testing.T) {
+	s := &Serializer{DefaultType: "x"}
+	require.EqualError(t, s.Init(), `invalid default type "x"`)
+
+	s = &Serializer{HistogramType: "c"}
+	require.EqualError(t, s.Init(), `invalid histogram type "c"`)
+}
+
+func TestSerialize(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		serializer *Serializer
+		metrics    []telegraf.Metric
+		expected   string
+	}{
+		{
+			name:       "value types",
+			serializer: &Serializer{},
+			metrics: []telegraf.Metric{
+				metric.New("requests", nil, map[string]interface{}{"value": int64(5)}, now, telegraf.Counter),
+				metric.New("load", nil, map[string]interface{}{"value": 0.5}, now, telegraf.Gauge),
+				metric.New("latency", nil, map[string]interface{}{"value": 12.5}, now, telegraf.Histogram),
+				metric.New("temp", nil, map[string]interface{}{"value": 21.0}, now),
+			},
+			expected: "requests:5|c\nload:0.5|g\nlatency:12.5|ms\ntemp:21|g\n",
+		},
+		{
+			name:       "multiple fields and tags",
+			serializer: &Serializer{},
+			metrics: []telegraf.Metric{
+				metric.New(
+					"cpu",
+					map[string]string{"host": "a", "cpu": "cpu0"},
+					map[string]interface{}{"idle": 90.5, "user": uint64(3), "name": "skipped"},
+					now,
+				),
+			},
+			expected: "cpu.idle,cpu=cpu0,host=a:90.5|g\ncpu.user,cpu=cpu0,host=a:3|g\n",
+		},
+		{
+			name:       "datadog",
+			serializer: &Serializer{DataDogExtensions: true, HistogramType: "d"},
+			metrics: []telegraf.Metric{
+				metric.New(
+					"latency",
+					map[string]string{"env": "prod", "path": "/a|b"},
+					map[string]interface{}{"value": 12.0},
+					now,
+					telegraf.Histogram,
+				),
+			},
+			expected: "latency:12|d|#env:prod,path:/a_b\n",
+		},
+		{
+			name:       "metric type tag",
+			serializer: &Serializer{},
+			metrics: []telegraf.Metric{
+				metric.New(
+					"users",
+					map[string]string{"metric_type": "set"},
+					map[string]interface{}{"value": "alice"},
+					now,
+				),
+				metric.New(
+					"jobs",
+					map[string]string{"metric_type": "counter"},
+					map[string]interface{}{"value": 2.9},
+					now,
+				),
+			},
+			expected: "users:alice|s\njobs:2|c\n",
+		},
+		{
+			name:       "negative gauge",
+			serializer: &Serializer{},
+			metrics: []telegraf.Metric{
+				metric.New("temp", map[string]string{"room": "a"}, map[string]interface{}{"value": int64(-5)}, now, telegraf.Gauge),
+				metric.New("delta", nil, map[string]interface{}{"value": int64(-5)}, now, telegraf.Counter),
+			},
+			expected: "temp,room=a:0|g\ntemp,room=a:-5|g\ndelta:-5|c\n",
+		},
+		{
+			name:       "bucket sanitizing",
+			serializer: &Serializer{},
+			metrics: []telegraf.Metric{
+				metric.New("a:b|c", nil, map[string]interface{}{"x,y\nz": 1.0}, now),
+			},
+			expected: "a_b_c.x_y_z:1|g\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.serializer.Log = testutil.Logger{}
+			require.NoError(t, tt.serializer.Init())
+
+			actual, err := tt.serializer.SerializeBatch(tt.metrics)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(actual))
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	input := "requests:5|c|#env:prod\nload:0.5|g\nlevel:+2|g\nlevel:-3|g\nlatency:12.5|ms|#env:prod,region:us\nunique:bob|s\nsize:3|d\n"
+
+	parser := &parsers_statsd.Parser{DataDogExtensions: true, Log: testutil.Logger{}}
+	require.NoError(t, parser.Init())
+	metrics, err := parser.Parse([]byte(input))
+	require.NoError(t, err)
+
+	serializer := &Serializer{DataDogExtensions: true, Log: testutil.Logger{}}
+	require.NoError(t, serializer.Init())
+	actual, err := serializer.SerializeBatch(metrics)
+	require.NoError(t, err)
+	require.Equal(t, input, string(actual))
+}
+
+func BenchmarkSerialize(b *testing.B) {
+	s := &Serializer{Log: testutil.Logger{}}
+	require.NoError(b, s.Init())
+	metrics := serializers.BenchmarkMetrics(b)
+	for n := 0; n < b.N; n++ {
+		_, err := s.Serialize(metrics[n%len(metrics)])
+		require.NoError(b, err)
+	}
+}
+
+func BenchmarkSerializeBatch(b *testing.B) {
+	s := &Serializer{Log: testutil.Logger{}}
+	require.NoError(b, s.Init())
+	m := serializers.BenchmarkMetrics(b)
+	metrics := m[:]
+	for n := 0; n < b.N; n++ {
+		_, err := s.SerializeBatch(metrics)
+		require.NoError(b, err)
+	}
+}